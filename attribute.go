@@ -61,6 +61,8 @@ func parseAttribute(line string) Attribute {
 		return parseLinkageAttribute(value)
 	case "name":
 		return nameAttribute(strings.TrimSpace(value))
+	case "replace":
+		return replaceAttribute(strings.TrimSpace(value))
 	case "attr":
 		return parseLLVMAttribute(strings.TrimSpace(value))
 	case "thread_local":
@@ -138,6 +140,34 @@ func (a nameAttribute) Apply(v Value) {
 	}
 }
 
+// replaceAttribute replaces a function defined by the package
+// with the function it is applied to, which takes the replaced
+// function's name. The replaced function is removed, and its uses
+// are replaced. This allows an overlay file to replace a function
+// or method of a package whose other files are not overlaid; the
+// replacement, having a different name, does not conflict with
+// the replaced function when the package is type-checked.
+type replaceAttribute string
+
+func (a replaceAttribute) Apply(v Value) {
+	if _, isfunc := v.Type().(*types.Signature); !isfunc {
+		panic(fmt.Errorf("Cannot replace %s with a variable", string(a)))
+	}
+	fn := v.LLVMValue()
+	name := string(a)
+	curr := fn.GlobalParent().NamedFunction(name)
+	if curr.IsNil() {
+		panic(fmt.Errorf("Want to replace the function %s, which does not exist!", name))
+	}
+	if curr.Type() != fn.Type() {
+		panic(fmt.Errorf("Want to replace the function %s with a function of a different type!", name))
+	}
+	curr.SetName(name + "_llgo_replaced")
+	curr.ReplaceAllUsesWith(fn)
+	curr.EraseFromParentAsFunction()
+	fn.SetName(name)
+}
+
 func parseLLVMAttribute(value string) llvmAttribute {
 	var result llvmAttribute
 	value = strings.Replace(value, ",", " ", -1)
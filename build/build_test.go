@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"github.com/axw/llgo/build"
 	"io/ioutil"
+	"reflect"
 	"strings"
 	"testing"
 	"testing/iotest"
@@ -26,3 +27,68 @@ func TestLLVMIRReadCloser(t *testing.T) {
 		t.Errorf("%q != %q", str, expected)
 	}
 }
+
+func TestParseTriple(t *testing.T) {
+	tests := []struct {
+		triple, goos, goarch string
+	}{
+		{"x86_64-unknown-linux-gnu", "linux", "amd64"},
+		{"i686-pc-linux", "linux", "386"},
+		{"armv7-unknown-linux-gnueabihf", "linux", "arm"},
+		{"x86_64-apple-darwin13.0.0", "darwin", "amd64"},
+		{"pnacl", "nacl", "le32"},
+	}
+	for _, test := range tests {
+		goos, goarch, err := build.ParseTriple(test.triple)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", test.triple, err)
+			continue
+		}
+		if goos != test.goos || goarch != test.goarch {
+			t.Errorf("%s: got %s/%s, expected %s/%s", test.triple, goos, goarch, test.goos, test.goarch)
+		}
+	}
+	for _, triple := range []string{"x86_64", "mips-linux", "x86_64-unknown-plan9"} {
+		if _, _, err := build.ParseTriple(triple); err == nil {
+			t.Errorf("%s: expected an error", triple)
+		}
+	}
+}
+
+func TestStringVarsFlag(t *testing.T) {
+	f := make(build.StringVarsFlag)
+	for _, arg := range []string{"main.version=1.0", "a/b.c=x=y", "main.empty="} {
+		if err := f.Set(arg); err != nil {
+			t.Errorf("%s: unexpected error: %s", arg, err)
+		}
+	}
+	for _, arg := range []string{"main.version", "version=1.0", ".version=1.0", "main.=1.0"} {
+		if err := f.Set(arg); err == nil {
+			t.Errorf("%s: expected an error", arg)
+		}
+	}
+	args := strings.Join(f.Args(), " ")
+	expected := "-X a/b.c=x=y -X main.empty= -X main.version=1.0"
+	if args != expected {
+		t.Errorf("got %q, expected %q", args, expected)
+	}
+}
+
+func TestSoftFloatFlags(t *testing.T) {
+	tests := []struct {
+		triple string
+		flags  []string
+	}{
+		{"armv7-unknown-linux-gnueabihf", []string{"-mfloat-abi=soft"}},
+		{"thumbv7-unknown-linux-gnueabi", []string{"-mfloat-abi=soft"}},
+		{"i686-pc-linux", []string{"-msoft-float"}},
+		{"x86_64-unknown-linux-gnu", []string{"-msoft-float"}},
+		{"pnacl", nil},
+	}
+	for _, test := range tests {
+		flags := build.SoftFloatFlags(test.triple)
+		if !reflect.DeepEqual(flags, test.flags) {
+			t.Errorf("%s: got %q, expected %q", test.triple, flags, test.flags)
+		}
+	}
+}
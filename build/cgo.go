@@ -0,0 +1,25 @@
+// Copyright 2014 The llgo Authors.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package build
+
+import "strings"
+
+// CgoExportSymbol returns the name of the function that is
+// called from C in place of the function with the specified
+// name, exported with cgo from the package with the specified
+// import path. The function has the same signature as the Go
+// function; it prepares the calling thread to run Go code
+// before calling it.
+func CgoExportSymbol(importpath, name string) string {
+	return cgoExportPrefix + importpath + "." + name
+}
+
+const cgoExportPrefix = "__llgo.cgoexport."
+
+// IsCgoExportSymbol reports whether the symbol
+// is one named by CgoExportSymbol.
+func IsCgoExportSymbol(symbol string) bool {
+	return strings.HasPrefix(symbol, cgoExportPrefix)
+}
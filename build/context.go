@@ -7,7 +7,10 @@ package build
 import (
 	"errors"
 	"go/build"
+	"os"
+	"path/filepath"
 	"regexp"
+	"runtime"
 	"strings"
 )
 
@@ -35,6 +38,47 @@ func ContextFromTriple(triple string) (*Context, error) {
 	return ctx, nil
 }
 
+// PkgRoot returns the directory in which llgo-build installs
+// the bitcode of packages built for the triple, in the first
+// GOPATH entry: $GOPATH/pkg/llgo/<triple>[_race][_softfloat].
+// Packages built with the race detector or with software
+// floating point are kept separate, as they must not be mixed
+// with others.
+func PkgRoot(triple string, race, softfloat bool) string {
+	gopath := os.Getenv("GOPATH")
+	if gopath == "" {
+		gopath = runtime.GOROOT()
+	} else {
+		gopath = filepath.SplitList(gopath)[0]
+	}
+	root := filepath.Join(gopath, "pkg", "llgo", triple)
+	if race {
+		root += "_race"
+	}
+	if softfloat {
+		root += "_softfloat"
+	}
+	return root
+}
+
+// SoftFloatFlags returns the flags with which clang compiles C
+// files, and compiles and links programs, for the triple when
+// building with software floating point: with them, clang emits
+// no floating point instructions, and passes floating point values
+// as the target's soft-float ABI specifies, as llgo's code and the
+// C code it is linked with must agree.
+func SoftFloatFlags(triple string) []string {
+	_, goarch, err := parseTriple(triple)
+	switch {
+	case err != nil, goarch == "le32":
+		// PNaCl has no soft-float ABI.
+		return nil
+	case goarch == "arm":
+		return []string{"-mfloat-abi=soft"}
+	}
+	return []string{"-msoft-float"}
+}
+
 func parseTriple(triple string) (goos string, goarch string, err error) {
 	if strings.ToLower(triple) == "pnacl" {
 		return "nacl", "le32", nil
@@ -0,0 +1,25 @@
+// Copyright 2014 The llgo Authors.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package build
+
+// CoverSymbol returns the name of the global variable that
+// holds the coverage data for the package with the specified
+// import path. The variable has the Go type []CoverFile,
+// where CoverFile is the struct:
+//
+//	struct {
+//		Name     string
+//		Counters []uint32
+//		Pos      []uint32
+//		NumStmt  []uint16
+//	}
+//
+// Pos holds three values for each block: the start line,
+// the end line, and the start and end columns packed into
+// the low and high 16 bits respectively. This is the same
+// encoding used by "go tool cover".
+func CoverSymbol(importpath string) string {
+	return "__llgo.cover." + importpath
+}
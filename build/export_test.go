@@ -0,0 +1,4 @@
+package build
+
+// ParseTriple is exported for the external tests.
+var ParseTriple = parseTriple
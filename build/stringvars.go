@@ -0,0 +1,49 @@
+// Copyright 2014 The llgo Authors.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package build
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// StringVarsFlag is a flag.Value that accumulates the
+// importpath.name=value arguments of the -X flag, which
+// sets the values of package-level string variables in
+// a linked program.
+type StringVarsFlag map[string]string
+
+func (f StringVarsFlag) String() string {
+	return ""
+}
+
+func (f StringVarsFlag) Set(s string) error {
+	eq := strings.Index(s, "=")
+	if eq == -1 {
+		return fmt.Errorf("expected importpath.name=value, got %q", s)
+	}
+	name := s[:eq]
+	if dot := strings.LastIndex(name, "."); dot <= 0 || dot == len(name)-1 {
+		return fmt.Errorf("expected importpath.name=value, got %q", s)
+	}
+	f[name] = s[eq+1:]
+	return nil
+}
+
+// Args returns the -X arguments that set
+// the variables, sorted by variable name.
+func (f StringVarsFlag) Args() []string {
+	names := make([]string, 0, len(f))
+	for name := range f {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var args []string
+	for _, name := range names {
+		args = append(args, "-X", name+"="+f[name])
+	}
+	return args
+}
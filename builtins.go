@@ -37,6 +37,9 @@ func (c *compiler) callLen(arg *LLVMValue) *LLVMValue {
 	case *types.Slice:
 		lenvalue = c.builder.CreateExtractValue(arg.LLVMValue(), 1, "")
 	case *types.Map:
+		if c.raceEnabled() {
+			c.raceMapAccess(arg.LLVMValue(), false)
+		}
 		f := c.runtime.maplen.LLVMValue()
 		lenvalue = c.builder.CreateCall(f, []llvm.Value{arg.LLVMValue()}, "")
 	case *types.Basic:
@@ -64,6 +67,14 @@ func (c *compiler) callAppend(a, b *LLVMValue) *LLVMValue {
 		c.coerceSlice(b.LLVMValue(), i8slice),
 	}
 	result := c.builder.CreateCall(f, args, "")
+	if c.raceEnabled() {
+		elemtyp := llaType.StructElementTypes()[0].ElementType()
+		zero := llvm.ConstNull(c.types.inttype)
+		alen := c.builder.CreateExtractValue(args[1], 1, "")
+		blen := c.builder.CreateExtractValue(args[2], 1, "")
+		c.raceRangeAccess(args[2], zero, blen, elemtyp, false)
+		c.raceRangeAccess(result, alen, blen, elemtyp, true)
+	}
 	return c.NewValue(c.coerceSlice(result, llaType), a.Type())
 }
 
@@ -80,6 +91,12 @@ func (c *compiler) callCopy(dest, source *LLVMValue) *LLVMValue {
 		c.coerceSlice(source.LLVMValue(), i8slice),
 	}
 	result := c.builder.CreateCall(slicecopy, args, "")
+	if c.raceEnabled() {
+		elemtyp := dest.LLVMValue().Type().StructElementTypes()[0].ElementType()
+		zero := llvm.ConstNull(result.Type())
+		c.raceRangeAccess(args[2], zero, result, elemtyp, false)
+		c.raceRangeAccess(args[1], zero, result, elemtyp, true)
+	}
 	return c.NewValue(result, types.Typ[types.Int])
 }
 
@@ -90,6 +107,9 @@ func (c *compiler) callDelete(m_, k_ *LLVMValue) {
 	dyntyp = c.builder.CreatePtrToInt(dyntyp, c.target.IntPtrType(), "")
 	m := m_.LLVMValue()
 	k := k_.LLVMValue()
+	if c.raceEnabled() {
+		c.raceMapAccess(m, true)
+	}
 	stackptr := c.stacksave()
 	pk := c.builder.CreateAlloca(k.Type(), "")
 	c.builder.CreateStore(k, pk)
@@ -0,0 +1,89 @@
+// Copyright 2014 The llgo Authors.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package llgo
+
+import (
+	"go/ast"
+	"strings"
+
+	"code.google.com/p/go.tools/go/loader"
+	"code.google.com/p/go.tools/go/types"
+	"github.com/axw/gollvm/llvm"
+	llgobuild "github.com/axw/llgo/build"
+)
+
+// createCgoCallbacks defines the function named by
+// build.CgoExportSymbol for each function in the package
+// that is exported to C with an "//export" comment.
+func (c *compiler) createCgoCallbacks(u *unit, pkginfo *loader.PackageInfo, importpath string) {
+	for _, f := range pkginfo.Files {
+		for _, decl := range f.Decls {
+			decl, ok := decl.(*ast.FuncDecl)
+			if !ok || decl.Recv != nil || !isCgoExport(decl.Doc) {
+				continue
+			}
+			obj := pkginfo.ObjectOf(decl.Name).(*types.Func)
+			fn := u.ResolveFunc(obj).LLVMValue()
+			name := llgobuild.CgoExportSymbol(importpath, decl.Name.Name)
+			c.createCgoCallback(fn, name)
+		}
+	}
+}
+
+// createCgoCallback defines a function with the specified name
+// that calls fn, which may be called from any C thread. Before
+// calling fn, the function initialises the runtime if it has
+// not been already (in a library), attaches a G to the thread,
+// and initialises the thread's defers so that any panic that
+// escapes fn aborts the program with a message.
+func (c *compiler) createCgoCallback(fn llvm.Value, name string) {
+	ftyp := fn.Type().ElementType()
+	callback := llvm.AddFunction(c.module.Module, name, ftyp)
+	entry := llvm.AddBasicBlock(callback, "entry")
+	call := llvm.AddBasicBlock(callback, "call")
+	panicked := llvm.AddBasicBlock(callback, "panic")
+
+	c.builder.SetInsertPointAtEnd(entry)
+	if c.isLibrary() {
+		c.builder.CreateCall(c.runtime.libinit.LLVMValue(), nil, "")
+	}
+	c.builder.CreateCall(c.runtime.cgocallbackenter.LLVMValue(), nil, "")
+	defers := c.builder.CreateAlloca(c.runtime.defers.llvm, "")
+	c.builder.CreateCall(c.runtime.initdefers.LLVMValue(), []llvm.Value{defers}, "")
+	jb := c.builder.CreateStructGEP(defers, 0, "")
+	jb = c.builder.CreateBitCast(jb, llvm.PointerType(llvm.Int8Type(), 0), "")
+	result := c.builder.CreateCall(c.runtime.setjmp.LLVMValue(), []llvm.Value{jb}, "")
+	result = c.builder.CreateIsNotNull(result, "")
+	c.builder.CreateCondBr(result, panicked, call)
+
+	c.builder.SetInsertPointAtEnd(call)
+	result = c.builder.CreateCall(fn, callback.Params(), "")
+	// There are no deferred functions; rundefers
+	// just restores the thread's previous defers.
+	c.builder.CreateCall(c.runtime.rundefers.LLVMValue(), nil, "")
+	if ftyp.ReturnType().TypeKind() == llvm.VoidTypeKind {
+		c.builder.CreateRetVoid()
+	} else {
+		c.builder.CreateRet(result)
+	}
+
+	c.builder.SetInsertPointAtEnd(panicked)
+	c.builder.CreateCall(c.runtime.cgocallbackpanic.LLVMValue(), nil, "")
+	c.builder.CreateUnreachable()
+}
+
+// isCgoExport reports whether the comment group
+// contains a cgo "//export" directive.
+func isCgoExport(doc *ast.CommentGroup) bool {
+	if doc == nil {
+		return false
+	}
+	for _, comment := range doc.List {
+		if strings.HasPrefix(comment.Text, "//export ") {
+			return true
+		}
+	}
+	return false
+}
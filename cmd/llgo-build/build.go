@@ -15,6 +15,7 @@ import (
 	"path"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 
 	llgobuild "github.com/axw/llgo/build"
@@ -31,6 +32,13 @@ func (r *renamedFileInfo) Name() string {
 	return r.name
 }
 
+// byName sorts files by name, as ReadDir must.
+type byName []os.FileInfo
+
+func (f byName) Len() int           { return len(f) }
+func (f byName) Less(i, j int) bool { return f[i].Name() < f[j].Name() }
+func (f byName) Swap(i, j int)      { f[i], f[j] = f[j], f[i] }
+
 func runCmd(cmd *exec.Cmd) error {
 	if printcommands {
 		if cmd.Dir != "" {
@@ -44,7 +52,8 @@ func runCmd(cmd *exec.Cmd) error {
 
 func getPackage(pkgpath string) (pkg *build.Package, err error) {
 	// These packages are special: they're mostly written from
-	// scratch, so we don't both with the overlay.
+	// scratch, so we don't both with llgo's overlay.
+	importpath := pkgpath
 	if pkgpath == "runtime" || pkgpath == "runtime/cgo" {
 		defer func(pkgpath string) { pkg.ImportPath = pkgpath }(pkgpath)
 		pkgpath = llgoPkgPrefix + pkgpath
@@ -53,14 +62,13 @@ func getPackage(pkgpath string) (pkg *build.Package, err error) {
 	// Make a copy, as we'll be modifying ReadDir/OpenFile.
 	buildctx := *buildctx
 
-	// Attempt to find an overlay package path,
-	// which we'll use in ReadDir below.
-	overlayentries := make(map[string]bool)
-	overlaypkgpath := llgoPkgPrefix + pkgpath
-	overlaypkg, err := buildctx.Import(overlaypkgpath, "", build.FindOnly)
-	if err != nil {
-		overlaypkg = nil
-	}
+	// Find the overlay directories, which we'll use in
+	// ReadDir below. overlayentries maps the names of the
+	// files taken from an overlay to the directory holding
+	// them; replaced records those replacing another file.
+	overlaydirs := findOverlays(&buildctx, importpath, pkgpath == importpath)
+	overlayentries := make(map[string]string)
+	replaced := make(map[string]bool)
 
 	// ReadDir is overridden to return a fake ".s"
 	// file for each ".ll" file in the directory.
@@ -73,22 +81,29 @@ func getPackage(pkgpath string) (pkg *build.Package, err error) {
 		for _, info := range fi {
 			entries[info.Name()] = info
 		}
-		// Overlay all files in the overlay package dir.
-		// If we find any .ll files, replace the suffix
-		// with .s.
-		if overlaypkg != nil {
-			fi, err = ioutil.ReadDir(overlaypkg.Dir)
-		}
-		if err == nil {
-			// Check for .ll files in the overlay dir if
-			// we have one, else in the standard package dir.
+		// Overlay all files in the overlay package dirs, each
+		// replacing the files of those before it. If we find
+		// any .ll files, replace the suffix with .s. The
+		// package dir itself is checked for .ll files first.
+		dirs := append([]overlayDir{{dir: dir}}, overlaydirs...)
+		for _, overlay := range dirs {
+			fi, err := ioutil.ReadDir(overlay.dir)
+			if err != nil {
+				continue
+			}
 			for _, info := range fi {
 				name := info.Name()
+				if overlay.dir == dir && !strings.HasSuffix(name, ".ll") {
+					continue
+				}
 				if strings.HasSuffix(name, ".ll") {
 					name = name[:len(name)-3] + ".s"
 					info = &renamedFileInfo{info, name}
 				}
-				overlayentries[name] = true
+				if _, ok := entries[name]; ok && overlay.dir != dir {
+					replaced[name] = true
+				}
+				overlayentries[name] = overlay.dir
 				entries[name] = info
 			}
 		}
@@ -96,6 +111,7 @@ func getPackage(pkgpath string) (pkg *build.Package, err error) {
 		for _, info := range entries {
 			fi = append(fi, info)
 		}
+		sort.Sort(byName(fi))
 		return fi, nil
 	}
 
@@ -106,11 +122,8 @@ func getPackage(pkgpath string) (pkg *build.Package, err error) {
 	// go/build when looking for build tags.
 	buildctx.OpenFile = func(path string) (io.ReadCloser, error) {
 		base := filepath.Base(path)
-		overlay := overlayentries[base]
-		if overlay {
-			if overlaypkg != nil {
-				path = filepath.Join(overlaypkg.Dir, base)
-			}
+		if overlaydir, ok := overlayentries[base]; ok {
+			path = filepath.Join(overlaydir, base)
 			if strings.HasSuffix(path, ".s") {
 				path := path[:len(path)-2] + ".ll"
 				var r io.ReadCloser
@@ -128,64 +141,145 @@ func getPackage(pkgpath string) (pkg *build.Package, err error) {
 	pkg, err = buildctx.Import(pkgpath, "", 0)
 	if err != nil {
 		return nil, err
-	} else {
-		if overlaypkg == nil {
-			overlaypkg = pkg
+	}
+	reportOverlay(pkg, overlaydirs, overlayentries, replaced)
+
+	// pkgfile returns the path of one of the package's
+	// files, which may have been taken from an overlay.
+	pkgfile := func(filename string) string {
+		if overlaydir, ok := overlayentries[filename]; ok {
+			return path.Join(overlaydir, filename)
 		}
-		// TODO(axw) factor out the repeated code
-		for i, filename := range pkg.GoFiles {
-			pkgdir := pkg.Dir
-			if overlayentries[filename] {
-				pkgdir = overlaypkg.Dir
-			}
-			pkg.GoFiles[i] = path.Join(pkgdir, filename)
+		return path.Join(pkg.Dir, filename)
+	}
+	for _, files := range [][]string{
+		pkg.GoFiles, pkg.CgoFiles, pkg.TestGoFiles, pkg.XTestGoFiles, pkg.CFiles,
+	} {
+		for i, filename := range files {
+			files[i] = pkgfile(filename)
 		}
-		for i, filename := range pkg.CgoFiles {
-			pkgdir := pkg.Dir
-			if overlayentries[filename] {
-				pkgdir = overlaypkg.Dir
-			}
-			pkg.CgoFiles[i] = path.Join(pkgdir, filename)
+	}
+	sfiles := pkg.SFiles[:0]
+	for _, filename := range pkg.SFiles {
+		sfile := pkgfile(filename)
+		if strings.HasSuffix(filename, ".S") {
+			// .S files go straight to clang
+		} else if _, ok := overlayentries[filename]; ok {
+			sfile = sfile[:len(sfile)-2] + ".ll"
+		} else if isLoweredAsm(pkgpath, filename) {
+			continue
+		} else {
+			err := fmt.Errorf("No matching .ll file for %q", filename)
+			return nil, err
 		}
-		for i, filename := range pkg.TestGoFiles {
-			pkgdir := pkg.Dir
-			if overlayentries[filename] {
-				pkgdir = overlaypkg.Dir
-			}
-			pkg.TestGoFiles[i] = path.Join(pkgdir, filename)
+		sfiles = append(sfiles, sfile)
+	}
+	pkg.SFiles = sfiles
+	return pkg, nil
+}
+
+// loweredAsm maps import paths to the prefixes of the names of
+// the packages' gc assembly files whose functions are all lowered
+// by the compiler (see inline.go), and so need no .ll files.
+var loweredAsm = map[string][]string{
+	"math":        {"abs_", "floor_", "sqrt_"},
+	"sync/atomic": {"asm_"},
+}
+
+// isLoweredAsm reports whether the functions in the
+// package's gc assembly file are lowered by the compiler.
+func isLoweredAsm(pkgpath, filename string) bool {
+	for _, prefix := range loweredAsm[pkgpath] {
+		if strings.HasPrefix(filename, prefix) {
+			return true
 		}
-		for i, filename := range pkg.XTestGoFiles {
-			pkgdir := pkg.Dir
-			if overlayentries[filename] {
-				pkgdir = overlaypkg.Dir
+	}
+	return false
+}
+
+// overlayDir is a directory whose files overlay those of a package.
+type overlayDir struct {
+	dir string
+
+	// user is true if the directory was found in the
+	// overlay path, rather than in llgo's own overlay.
+	user bool
+}
+
+// overlayPath returns the directory trees that may overlay
+// packages, in order of precedence: those specified with the
+// -overlay flag, then those in the LLGO_OVERLAY environment
+// variable.
+func overlayPath() []string {
+	var roots []string
+	for _, list := range []string{overlay, os.Getenv("LLGO_OVERLAY")} {
+		for _, root := range filepath.SplitList(list) {
+			if root != "" {
+				roots = append(roots, root)
 			}
-			pkg.XTestGoFiles[i] = path.Join(pkgdir, filename)
 		}
-		for i, filename := range pkg.CFiles {
-			pkgdir := pkg.Dir
-			if overlayentries[filename] {
-				pkgdir = overlaypkg.Dir
-			}
-			pkg.CFiles[i] = path.Join(pkgdir, filename)
-		}
-		for i, filename := range pkg.SFiles {
-			pkgdir := pkg.Dir
-			if strings.HasSuffix(filename, ".S") {
-				// .S files go straight to clang
-			} else if overlayentries[filename] {
-				pkgdir = overlaypkg.Dir
-				filename = filename[:len(filename)-2] + ".ll"
-			} else {
-				err := fmt.Errorf("No matching .ll file for %q", filename)
-				return nil, err
-			}
-			pkg.SFiles[i] = path.Join(pkgdir, filename)
+	}
+	return roots
+}
+
+// findOverlays returns the directories whose files overlay those
+// of the package with the specified import path, in increasing
+// order of precedence: llgo's own overlay package, if llgoOverlay
+// is set, followed by the directories found in the overlay path in
+// reverse order. The files of each replace those of the package,
+// and those of the directories before it.
+func findOverlays(buildctx *build.Context, pkgpath string, llgoOverlay bool) []overlayDir {
+	var dirs []overlayDir
+	if llgoOverlay {
+		overlaypkg, err := buildctx.Import(llgoPkgPrefix+pkgpath, "", build.FindOnly)
+		if err == nil {
+			dirs = append(dirs, overlayDir{dir: overlaypkg.Dir})
 		}
 	}
-	return pkg, nil
+	roots := overlayPath()
+	for i := len(roots) - 1; i >= 0; i-- {
+		dir := filepath.Join(roots[i], filepath.FromSlash(pkgpath))
+		if fi, err := os.Stat(dir); err == nil && fi.IsDir() {
+			dirs = append(dirs, overlayDir{dir: dir, user: true})
+		}
+	}
+	return dirs
 }
 
+// reportOverlay logs the files of pkg that were replaced by, or
+// added from, the user's overlay directories, or from any overlay
+// directory if commands are being printed.
+func reportOverlay(pkg *build.Package, overlaydirs []overlayDir, overlayentries map[string]string, replaced map[string]bool) {
+	report := make(map[string]bool)
+	for _, overlay := range overlaydirs {
+		report[overlay.dir] = overlay.user || printcommands
+	}
+	var files []string
+	for _, list := range [][]string{
+		pkg.GoFiles, pkg.CgoFiles, pkg.TestGoFiles, pkg.XTestGoFiles, pkg.CFiles, pkg.SFiles,
+	} {
+		files = append(files, list...)
+	}
+	for _, file := range files {
+		overlaydir, ok := overlayentries[file]
+		if !ok || !report[overlaydir] {
+			continue
+		}
+		verb := "added"
+		if replaced[file] {
+			verb = "replaced"
+		}
+		if strings.HasSuffix(file, ".s") {
+			file = file[:len(file)-2] + ".ll"
+		}
+		log.Printf("overlay %s: %s %s\n", pkg.ImportPath, verb, filepath.Join(overlaydir, file))
+	}
+}
+
+// buildPackages builds the specified packages, or Go files,
+// along with any of their dependencies that are stale.
 func buildPackages(pkgpaths []string) error {
+	b := newBuilder()
 	if len(pkgpaths) > 0 && strings.HasSuffix(pkgpaths[0], ".go") {
 		pkg, err := goFilesPackage(pkgpaths)
 		if err != nil {
@@ -194,38 +288,76 @@ func buildPackages(pkgpaths []string) error {
 		output := output
 		if output == "" && pkg.IsCommand() {
 			first := pkg.GoFiles[0]
-			output = first[:len(first)-len(".go")]
+			output = first[:len(first)-len(".go")] + libraryExt()
 		}
 		for i, filename := range pkg.GoFiles {
 			pkg.GoFiles[i] = filepath.Join(pkg.Dir, filename)
 		}
-		log.Printf("building %s\n", pkg.Name)
-		return buildPackage(pkg, output)
+		if err := b.addRoot(pkg, output); err != nil {
+			return err
+		}
+	} else {
+		for _, pkgpath := range pkgpaths {
+			pkg, err := getPackage(pkgpath)
+			if err != nil {
+				return err
+			}
+			if err := b.addRoot(pkg, output); err != nil {
+				return err
+			}
+		}
 	}
+	if dryrun {
+		b.printGraph(os.Stdout)
+		return nil
+	}
+	return b.run()
+}
 
-	for _, pkgpath := range pkgpaths {
-		log.Printf("building %s\n", pkgpath)
-		pkg, err := getPackage(pkgpath)
+// cgoFlags returns the flags with which the package's cgo and C
+// files are preprocessed, compiled and linked: those in the
+// environment, in the package's #cgo directives, and those
+// output by pkg-config for the packages the directives name.
+func cgoFlags(pkg *build.Package, pkgworkdir string) (cppflags, cflags, ldflags []string, err error) {
+	cflags = append(envFields("CGO_CFLAGS"), pkg.CgoCFLAGS...)
+	cppflags = append(envFields("CGO_CPPFLAGS"), pkg.CgoCPPFLAGS...)
+	//cxxflags = append(envFields("CGO_CXXFLAGS"), pkg.CgoCXXFLAGS...)
+	cppflags = append(cppflags, "-I", pkgworkdir, "-I", pkg.Dir)
+	ldflags = append(envFields("CGO_LDFLAGS"), pkg.CgoLDFLAGS...)
+	if len(pkg.CgoPkgConfig) > 0 {
+		pcflags, err := pkgConfig("--cflags", pkg.CgoPkgConfig)
 		if err != nil {
-			return err
+			return nil, nil, nil, err
 		}
-		err = buildPackage(pkg, output)
+		cppflags = append(cppflags, pcflags...)
+		pcldflags, err := pkgConfig("--libs", pkg.CgoPkgConfig)
 		if err != nil {
-			return err
+			return nil, nil, nil, err
 		}
+		ldflags = append(ldflags, pcldflags...)
 	}
-	return nil
+	// Get the library dir in which to find libgcc, libstdc++, etc.
+	// We need to do this because we rely on clang to link; in Ubuntu 14.04
+	// beta 1, there is no g++-4.9, but there is gccgo-4.9. Clang sees the
+	// partial 4.9 lib directory and uses it instead of 4.8, which is what
+	// should be used.
+	if gcclib, err := findGcclib(); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to locate gcc lib dir: %v", err)
+	} else if gcclib != "." {
+		ldflags = append(ldflags, "-L", gcclib)
+	}
+	return cppflags, cflags, ldflags, nil
 }
 
 var cgoRe = regexp.MustCompile(`[/\\:]`)
 
-func runCgo(pkgpath string, cgofiles, cppflags, cflags []string) (gofiles, cfiles []string, err error) {
+func runCgo(pkgpath, objdir string, cgofiles, cppflags, cflags []string) (gofiles, cfiles []string, err error) {
 	args := []string{
 		"tool", "cgo",
 		"-gccgo",
 		"-gccgopkgpath", pkgpath,
 		"-gccgoprefix", "woobie",
-		"-objdir", workdir,
+		"-objdir", objdir,
 		"--",
 	}
 	args = append(args, cppflags...)
@@ -239,14 +371,14 @@ func runCgo(pkgpath string, cgofiles, cppflags, cflags []string) (gofiles, cfile
 		return nil, nil, err
 	}
 	// Get the names of the generated Go and C files.
-	gofiles = []string{filepath.Join(workdir, "_cgo_gotypes.go")}
-	cfiles = []string{filepath.Join(workdir, "_cgo_export.c")}
+	gofiles = []string{filepath.Join(objdir, "_cgo_gotypes.go")}
+	cfiles = []string{filepath.Join(objdir, "_cgo_export.c")}
 	for _, fn := range cgofiles {
 		f := cgoRe.ReplaceAllString(fn[:len(fn)-2], "_")
-		gofiles = append(gofiles, filepath.Join(workdir, f+"cgo1.go"))
-		cfiles = append(cfiles, filepath.Join(workdir, f+"cgo2.c"))
+		gofiles = append(gofiles, filepath.Join(objdir, f+"cgo1.go"))
+		cfiles = append(cfiles, filepath.Join(objdir, f+"cgo2.c"))
 	}
-	cfiles = append(cfiles, filepath.Join(workdir, "_cgo_defun.c"))
+	cfiles = append(cfiles, filepath.Join(objdir, "_cgo_defun.c"))
 
 	// gccgo uses "//extern" to name external symbols;
 	// translate them to "// #llgo name:".
@@ -258,12 +390,49 @@ func runCgo(pkgpath string, cgofiles, cppflags, cflags []string) (gofiles, cfile
 	return gofiles, cfiles, nil
 }
 
-func buildPackage(pkg *build.Package, output string) error {
+// llgoArgs returns the arguments common to
+// all invocations of llgo to compile a module.
+func llgoArgs() []string {
 	args := []string{"-c", "-triple", triple}
+	args = append(args, fmt.Sprintf("-g=%v", generateDebug))
+	if race {
+		args = append(args, "-race")
+	}
+	if softfloat {
+		args = append(args, "-softfloat")
+	}
+	return args
+}
+
+// clangArgs returns the arguments common to all invocations
+// of clang to compile C files, and to compile and link programs.
+func clangArgs() []string {
+	if softfloat {
+		return llgobuild.SoftFloatFlags(triple)
+	}
+	return nil
+}
+
+// buildPackage builds pkg, writing its bitcode to output. When
+// building tests, the root packages are built with their internal
+// test files, and linked into a test program; their export data is
+// written to the work directory, so that the package's export data
+// in GOPATH never includes the test files' declarations.
+func buildPackage(pkg *build.Package, output string, root bool) error {
+	testing := test && root
+
+	// Packages may be built concurrently, so each
+	// has its own directory for intermediate files.
+	pkgworkdir, err := ioutil.TempDir(workdir, "")
+	if err != nil {
+		return err
+	}
+
+	args := llgoArgs()
 	dir, file := path.Split(pkg.ImportPath)
-	if pkg.IsCommand() || test {
+	if pkg.IsCommand() || testing {
 		if output == "" {
-			output = file
+			output = file + libraryExt()
 		}
 	} else {
 		dir = filepath.Join(pkgroot, dir)
@@ -275,47 +444,52 @@ func buildPackage(pkg *build.Package, output string) error {
 			output = path.Join(dir, file+".bc")
 		}
 	}
-	if !pkg.IsCommand() || test {
+	if !pkg.IsCommand() || testing {
 		args = append(args, "-importpath", pkg.ImportPath)
 	}
 
 	var cgoCFLAGS, cgoCPPFLAGS, cgoLDFLAGS []string
 	if len(pkg.CFiles) > 0 || len(pkg.CgoFiles) > 0 {
-		// TODO(axw) process pkg-config
-		cgoCFLAGS = append(envFields("CGO_CFLAGS"), pkg.CgoCFLAGS...)
-		cgoCPPFLAGS = append(envFields("CGO_CPPFLAGS"), pkg.CgoCPPFLAGS...)
-		//cgoCXXFLAGS = append(envFields("CGO_CXXFLAGS"), pkg.CgoCXXFLAGS...)
-		cgoCPPFLAGS = append(cgoCPPFLAGS, "-I", workdir, "-I", pkg.Dir)
-		cgoLDFLAGS = append(envFields("CGO_LDFLAGS"), pkg.CgoLDFLAGS...)
-		// Get the library dir in which to find libgcc, libstdc++, etc.
-		// We need to do this because we rely on clang to link; in Ubuntu 14.04
-		// beta 1, there is no g++-4.9, but there is gccgo-4.9. Clang sees the
-		// partial 4.9 lib directory and uses it instead of 4.8, which is what
-		// should be used.
-		if gcclib, err := findGcclib(); err != nil {
-			return fmt.Errorf("failed to locate gcc lib dir: %v", err)
-		} else if gcclib != "." {
-			cgoLDFLAGS = append(cgoLDFLAGS, "-L", gcclib)
+		cgoCPPFLAGS, cgoCFLAGS, cgoLDFLAGS, err = cgoFlags(pkg, pkgworkdir)
+		if err != nil {
+			return err
 		}
 	}
 	var gofiles, cfiles []string
 	if len(pkg.CgoFiles) > 0 {
-		var err error
-		gofiles, cfiles, err = runCgo(pkg.ImportPath, pkg.CgoFiles, cgoCPPFLAGS, cgoCFLAGS)
+		gofiles, cfiles, err = runCgo(pkg.ImportPath, pkgworkdir, pkg.CgoFiles, cgoCPPFLAGS, cgoCFLAGS)
 		if err != nil {
 			return err
 		}
+		exports, err := cgoExports(pkg)
+		if err != nil {
+			return err
+		}
+		importpath := "main"
+		if !pkg.IsCommand() || testing {
+			importpath = pkg.ImportPath
+		}
+		exportfile := filepath.Join(pkgworkdir, "_cgo_export.c")
+		if err := translateCgoExports(exportfile, importpath, exports); err != nil {
+			return fmt.Errorf("failed to translate cgo exports for %q: %v", exportfile, err)
+		}
 	}
 
 	gofiles = append(gofiles, pkg.GoFiles...)
 	cfiles = append(cfiles, pkg.CFiles...)
 
 	_, file = path.Split(output)
-	tempfile := path.Join(workdir, file+".bc")
-	args = append(args, fmt.Sprintf("-g=%v", generateDebug))
+	tempfile := path.Join(pkgworkdir, file+".bc")
+	if cover && testing {
+		args = append(args, "-covermode", covermode)
+	}
+	if pkg.IsCommand() && isLibrary() {
+		args = append(args, "-buildmode", buildmode)
+	}
 	args = append(args, "-o", tempfile)
 	args = append(args, gofiles...)
-	if test {
+	if testing {
+		args = append(args, "-exportfile", testExportFile(pkgworkdir, pkg.ImportPath))
 		args = append(args, pkg.TestGoFiles...)
 	}
 	cmd := exec.Command(llgobin, args...)
@@ -338,11 +512,12 @@ func buildPackage(pkg *build.Package, output string) error {
 	// Compile and link .c files in.
 	llvmlink := filepath.Join(llvmbindir, "llvm-link")
 	for _, cfile := range cfiles {
-		bcfile := filepath.Join(workdir, filepath.Base(cfile+".bc"))
+		bcfile := filepath.Join(pkgworkdir, filepath.Base(cfile+".bc"))
 		args = []string{"-c", "-o", bcfile}
 		if triple != "pnacl" {
 			args = append(args, "-target", triple, "-emit-llvm")
 		}
+		args = append(args, clangArgs()...)
 		args = append(args, cgoCFLAGS...)
 		args = append(args, cgoCPPFLAGS...)
 		args = append(args, cfile)
@@ -387,16 +562,53 @@ func buildPackage(pkg *build.Package, output string) error {
 			cmd.Stderr = os.Stderr
 			return runCmd(cmd)
 		}
-	} else if test {
+	} else if testing {
 		if err := linktest(pkg, tempfile); err != nil {
 			return err
 		}
+		if run {
+			return runTest(tempfile)
+		}
 	} else {
 		if output != "-" && len(cgoLDFLAGS) > 0 {
 			if err := writeLdflags(pkg.ImportPath, cgoLDFLAGS); err != nil {
 				return err
 			}
 		}
+		if output != "-" && len(pkg.CgoFiles) > 0 {
+			exports, err := cgoExports(pkg)
+			if err != nil {
+				return err
+			}
+			if err := writeCgoExports(pkg.ImportPath, exports); err != nil {
+				return err
+			}
+		}
+		if output != "-" && pkg.ImportPath == "runtime" {
+			if err := installGdbScript(pkg); err != nil {
+				return err
+			}
+		}
+	}
+	if err := moveFile(tempfile, output); err != nil {
+		return err
 	}
-	return moveFile(tempfile, output)
+	if pkg.IsCommand() && isLibrary() && output != "-" {
+		if buildmode == "c-archive" && (!emitllvm || triple == "pnacl") {
+			// Install the flags with which the archive
+			// must be linked alongside it.
+			err := moveFile(linkflagsFile(tempfile), linkflagsFile(output))
+			if err != nil {
+				return err
+			}
+		}
+		// Install the header declaring the exported functions
+		// alongside the library, as "go build" does.
+		header := filepath.Join(pkgworkdir, "_cgo_export.h")
+		if _, err := os.Stat(header); err == nil {
+			ext := filepath.Ext(output)
+			return moveFile(header, output[:len(output)-len(ext)]+".h")
+		}
+	}
+	return nil
 }
@@ -0,0 +1,144 @@
+// Copyright 2014 The llgo Authors.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/build"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestGetPackageAsm checks that gc assembly files are skipped
+// only if their functions are lowered by the compiler, and that
+// any other without a matching .ll file is an error.
+func TestGetPackageAsm(t *testing.T) {
+	gopath, err := ioutil.TempDir("", "llgo-build")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(gopath)
+	dir := filepath.Join(gopath, "src", "asm")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"asm.go":     "package asm\n\nfunc Lowered()\nfunc Other()\n",
+		"lowered_.s": "TEXT ·Lowered(SB),7,$0\n\tRET\n",
+	}
+	for name, data := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx := build.Default
+	ctx.GOPATH = gopath
+	defer func(orig *build.Context) { buildctx = orig }(buildctx)
+	buildctx = &ctx
+	loweredAsm["asm"] = []string{"lowered_"}
+	defer delete(loweredAsm, "asm")
+
+	pkg, err := getPackage("asm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pkg.SFiles) != 0 {
+		t.Errorf("lowered assembly was not skipped: %v", pkg.SFiles)
+	}
+
+	other := filepath.Join(dir, "other.s")
+	if err := ioutil.WriteFile(other, []byte("TEXT ·Other(SB),7,$0\n\tRET\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err = getPackage("asm")
+	if err == nil || !strings.Contains(err.Error(), `No matching .ll file for "other.s"`) {
+		t.Errorf("got error %v, expected no matching .ll file for other.s", err)
+	}
+
+	ll := filepath.Join(dir, "other.ll")
+	if err := ioutil.WriteFile(ll, []byte("define void @asm.Other() {\n\tret void\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	pkg, err = getPackage("asm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pkg.SFiles) != 1 || pkg.SFiles[0] != ll {
+		t.Errorf("got SFiles %v, expected [%s]", pkg.SFiles, ll)
+	}
+}
+
+// TestGetPackageOverlay checks that the files of a package are
+// replaced by, or added from, the directories in the overlay path,
+// with those given by -overlay taking precedence over those in
+// LLGO_OVERLAY, and that the replacements are reported.
+func TestGetPackageOverlay(t *testing.T) {
+	root, err := ioutil.TempDir("", "llgo-build")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	files := map[string]string{
+		"gopath/src/ov/a.go":  "package ov\n\nvar A = 1\n",
+		"gopath/src/ov/b.go":  "package ov\n\nvar B = 1\n",
+		"flag/ov/b.go":        "package ov\n\nvar B = 2\n",
+		"flag/ov/c.ll":        "; an added file\n",
+		"env/ov/a.go":         "package ov\n\nvar A = 3\n",
+		"env/ov/b.go":         "package ov\n\nvar B = 3\n",
+		"env/other/README.md": "not an overlay of ov\n",
+	}
+	for name, data := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx := build.Default
+	ctx.GOPATH = filepath.Join(root, "gopath")
+	defer func(orig *build.Context) { buildctx = orig }(buildctx)
+	buildctx = &ctx
+	defer func(orig string) { overlay = orig }(overlay)
+	overlay = filepath.Join(root, "flag")
+	defer os.Setenv("LLGO_OVERLAY", os.Getenv("LLGO_OVERLAY"))
+	os.Setenv("LLGO_OVERLAY", filepath.Join(root, "env"))
+
+	var report bytes.Buffer
+	log.SetOutput(&report)
+	log.SetFlags(0)
+	defer log.SetFlags(log.LstdFlags)
+	defer log.SetOutput(os.Stderr)
+
+	pkg, err := getPackage("ov")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rootfile := func(name string) string {
+		return filepath.Join(root, filepath.FromSlash(name))
+	}
+	gofiles := []string{rootfile("env/ov/a.go"), rootfile("flag/ov/b.go")}
+	if !reflect.DeepEqual(pkg.GoFiles, gofiles) {
+		t.Errorf("got GoFiles %v, expected %v", pkg.GoFiles, gofiles)
+	}
+	sfiles := []string{rootfile("flag/ov/c.ll")}
+	if !reflect.DeepEqual(pkg.SFiles, sfiles) {
+		t.Errorf("got SFiles %v, expected %v", pkg.SFiles, sfiles)
+	}
+	expected := fmt.Sprintf("overlay ov: replaced %s\noverlay ov: replaced %s\noverlay ov: added %s\n",
+		rootfile("env/ov/a.go"), rootfile("flag/ov/b.go"), rootfile("flag/ov/c.ll"))
+	if report.String() != expected {
+		t.Errorf("got report\n%s\nexpected\n%s", report.String(), expected)
+	}
+}
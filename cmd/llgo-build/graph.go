@@ -0,0 +1,360 @@
+// Copyright 2014 The llgo Authors.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"go/build"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// errDepFailed is recorded for an action that was
+// not performed because one of its dependencies
+// failed to build.
+var errDepFailed = errors.New("dependency failed to build")
+
+// action is a node in the build graph: the building
+// of a single package, after all of its dependencies.
+type action struct {
+	pkg    *build.Package
+	output string
+	deps   []*action
+
+	// root is true for the packages named on the
+	// command line, which are always built.
+	root bool
+
+	done chan struct{}
+	err  error
+}
+
+// installed reports whether the action's output is the
+// package's bitcode file in pkgroot, and may therefore be
+// reused by later builds.
+func (a *action) installed() bool {
+	return a.output == "" && !a.pkg.IsCommand() && !(a.root && test)
+}
+
+func (a *action) bcfile() string {
+	return filepath.Join(pkgroot, a.pkg.ImportPath+".bc")
+}
+
+func (a *action) hashfile() string {
+	return filepath.Join(pkgroot, a.pkg.ImportPath+".hash")
+}
+
+// builder computes the import graph of a set of
+// packages, and builds the packages in it.
+type builder struct {
+	actions map[string]*action
+
+	// order contains the actions in dependency
+	// order: each action follows its dependencies.
+	order []*action
+
+	// visiting records the packages whose dependencies
+	// are being added, for detecting import cycles.
+	visiting map[string]bool
+}
+
+func newBuilder() *builder {
+	return &builder{
+		actions:  make(map[string]*action),
+		visiting: make(map[string]bool),
+	}
+}
+
+// addRoot adds an action for building a package named
+// on the command line, along with its dependencies.
+func (b *builder) addRoot(pkg *build.Package, output string) error {
+	a, err := b.add(pkg, true)
+	if err != nil {
+		return err
+	}
+	a.root = true
+	a.output = output
+	return nil
+}
+
+func (b *builder) add(pkg *build.Package, root bool) (*action, error) {
+	if a := b.actions[pkg.ImportPath]; a != nil {
+		return a, nil
+	}
+	if b.visiting[pkg.ImportPath] {
+		return nil, fmt.Errorf("import cycle involving %q", pkg.ImportPath)
+	}
+	b.visiting[pkg.ImportPath] = true
+	defer delete(b.visiting, pkg.ImportPath)
+
+	a := &action{pkg: pkg, done: make(chan struct{})}
+	for _, path := range imports(pkg, root) {
+		deppkg, err := getPackage(path)
+		if err != nil {
+			return nil, err
+		}
+		dep, err := b.add(deppkg, false)
+		if err != nil {
+			return nil, err
+		}
+		a.deps = append(a.deps, dep)
+	}
+	b.actions[pkg.ImportPath] = a
+	b.order = append(b.order, a)
+	return a, nil
+}
+
+// imports returns the sorted import paths of the packages
+// that must be built before pkg, including those that are
+// implicitly imported. The imports of the test files are
+// included for packages named on the command line when
+// building tests.
+func imports(pkg *build.Package, root bool) []string {
+	paths := make(map[string]bool)
+	add := func(imports []string) {
+		for _, path := range imports {
+			switch path {
+			case "unsafe":
+			case "C":
+				paths["runtime/cgo"] = true
+				paths["syscall"] = true
+			default:
+				paths[path] = true
+			}
+		}
+	}
+	add(pkg.Imports)
+	if len(pkg.CgoFiles) > 0 {
+		add([]string{"C"})
+	}
+	if root && test {
+		add(pkg.TestImports)
+		add(pkg.XTestImports)
+		// The coverage counters of packages
+		// instrumented in atomic mode are
+		// incremented with sync/atomic.
+		if covermode == "atomic" {
+			add([]string{"sync/atomic"})
+		}
+	}
+	paths["runtime"] = true
+
+	// The runtime packages are written specially for llgo,
+	// and must not depend upon the packages they support.
+	delete(paths, pkg.ImportPath)
+	if pkg.ImportPath == "runtime" || pkg.ImportPath == "runtime/cgo" {
+		for path := range paths {
+			if path == "runtime" || path == "runtime/cgo" || path == "syscall" {
+				delete(paths, path)
+			}
+		}
+	}
+
+	sorted := make([]string, 0, len(paths))
+	for path := range paths {
+		sorted = append(sorted, path)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
+
+// stale reports whether the action must be performed. Packages
+// named on the command line, and those that are not installed
+// in pkgroot, are always stale; dependencies are never stale if
+// -build-deps=false. Otherwise, the package is stale if any of
+// its dependencies are, or if the hash recorded when it was last
+// built differs from its current hash.
+func (b *builder) stale(a *action, stale map[*action]bool) bool {
+	if a.root || !a.installed() {
+		return true
+	}
+	if !buildDeps {
+		return false
+	}
+	for _, dep := range a.deps {
+		if stale[dep] {
+			return true
+		}
+	}
+	if _, err := os.Stat(a.bcfile()); err != nil {
+		return true
+	}
+	recorded, err := ioutil.ReadFile(a.hashfile())
+	if err != nil {
+		return true
+	}
+	current, err := b.hash(a)
+	return err != nil || !bytes.Equal(recorded, current)
+}
+
+// hash computes a hash of everything that affects the result of
+// building a package: its source and header files, the flags it is
+// built with, including those given by pkg-config, the compiler
+// version, and the export data of its dependencies.
+func (b *builder) hash(a *action) ([]byte, error) {
+	h := sha1.New()
+	version, err := llgoVersion()
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintln(h, version)
+	fmt.Fprintln(h, strings.Join(llgoArgs(), " "))
+	fmt.Fprintln(h, clang)
+	for _, env := range []string{"CGO_CFLAGS", "CGO_CPPFLAGS", "CGO_LDFLAGS", "PKG_CONFIG"} {
+		fmt.Fprintln(h, env, os.Getenv(env))
+	}
+
+	pkg := a.pkg
+	if len(pkg.CgoPkgConfig) > 0 {
+		for _, flag := range []string{"--cflags", "--libs"} {
+			flags, err := pkgConfig(flag, pkg.CgoPkgConfig)
+			if err != nil {
+				return nil, err
+			}
+			fmt.Fprintln(h, flag, strings.Join(flags, " "))
+		}
+	}
+
+	var files []string
+	files = append(files, pkg.GoFiles...)
+	files = append(files, pkg.CgoFiles...)
+	files = append(files, pkg.CFiles...)
+	files = append(files, pkg.HFiles...)
+	files = append(files, pkg.SFiles...)
+	for _, file := range files {
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(pkg.Dir, file)
+		}
+		if err := hashFile(h, file); err != nil {
+			return nil, err
+		}
+	}
+	for _, dep := range a.deps {
+		exports := filepath.Join(exportroot, dep.pkg.ImportPath+".lgx")
+		if err := hashFile(h, exports); err != nil {
+			if !os.IsNotExist(err) {
+				return nil, err
+			}
+			fmt.Fprintln(h, dep.pkg.ImportPath, "no export data")
+		}
+	}
+	return []byte(fmt.Sprintf("%x\n", h.Sum(nil))), nil
+}
+
+func hashFile(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fmt.Fprintln(w, path)
+	_, err = io.Copy(w, f)
+	return err
+}
+
+var llgoVersionOnce struct {
+	sync.Once
+	version string
+	err     error
+}
+
+// llgoVersion returns the output of "llgo -version".
+func llgoVersion() (string, error) {
+	v := &llgoVersionOnce
+	v.Do(func() {
+		out, err := exec.Command(llgobin, "-version").Output()
+		v.version, v.err = string(out), err
+	})
+	return v.version, v.err
+}
+
+// printGraph prints the actions in the order they would be
+// performed, along with their dependencies, without building
+// anything.
+func (b *builder) printGraph(w io.Writer) {
+	stale := make(map[*action]bool)
+	for _, a := range b.order {
+		stale[a] = b.stale(a, stale)
+		verb := "build"
+		if !stale[a] {
+			verb = "skip"
+		}
+		deps := make([]string, len(a.deps))
+		for i, dep := range a.deps {
+			deps[i] = dep.pkg.ImportPath
+		}
+		fmt.Fprintf(w, "%s %s", verb, a.pkg.ImportPath)
+		if len(deps) > 0 {
+			fmt.Fprintf(w, " <- %s", strings.Join(deps, " "))
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+// run performs the actions, with up to "parallelism"
+// packages being built concurrently. Each package is
+// built once all of its dependencies have been built.
+func (b *builder) run() error {
+	var mu sync.Mutex
+	stale := make(map[*action]bool)
+	sem := make(chan struct{}, parallelism)
+	for _, a := range b.order {
+		go func(a *action) {
+			defer close(a.done)
+			for _, dep := range a.deps {
+				<-dep.done
+				if dep.err != nil {
+					a.err = errDepFailed
+					return
+				}
+			}
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			mu.Lock()
+			isStale := b.stale(a, stale)
+			stale[a] = isStale
+			mu.Unlock()
+			if isStale {
+				a.err = b.build(a)
+			}
+		}(a)
+	}
+
+	var err error
+	for _, a := range b.order {
+		<-a.done
+		if a.err != nil && a.err != errDepFailed && err == nil {
+			err = a.err
+		}
+	}
+	return err
+}
+
+// build builds the action's package, and records its
+// hash if it is installed in pkgroot.
+func (b *builder) build(a *action) error {
+	log.Printf("building %s\n", a.pkg.ImportPath)
+	if err := buildPackage(a.pkg, a.output, a.root); err != nil {
+		return err
+	}
+	if !a.installed() {
+		return nil
+	}
+	hash, err := b.hash(a)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(a.hashfile(), hash, 0644)
+}
@@ -0,0 +1,171 @@
+// Copyright 2014 The llgo Authors.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"go/build"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// graphTest is a builder for a package "b" that imports a
+// package "a", whose sources are in dir, and whose bitcode,
+// hash and export files are in pkgroot.
+type graphTest struct {
+	*testing.T
+	dir  string
+	b    *builder
+	a, c *action
+
+	pkgroot, exportroot string
+}
+
+func newGraphTest(t *testing.T) *graphTest {
+	dir, err := ioutil.TempDir("", "llgo-build")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := &graphTest{T: t, dir: dir, b: newBuilder(), pkgroot: pkgroot, exportroot: exportroot}
+	pkgroot = filepath.Join(dir, "pkg")
+	exportroot = pkgroot
+	if err := os.MkdirAll(pkgroot, 0755); err != nil {
+		t.Fatal(err)
+	}
+	g.write("a/a.go", "package a\n")
+	g.write("b/b.go", "package b\n\nimport \"a\"\n")
+	g.write("b/b.h", "int b(void);\n")
+	g.a = &action{pkg: &build.Package{
+		ImportPath: "a",
+		Dir:        filepath.Join(dir, "a"),
+		GoFiles:    []string{"a.go"},
+	}}
+	g.c = &action{pkg: &build.Package{
+		ImportPath: "b",
+		Dir:        filepath.Join(dir, "b"),
+		GoFiles:    []string{"b.go"},
+		HFiles:     []string{"b.h"},
+	}, deps: []*action{g.a}}
+	g.b.order = []*action{g.a, g.c}
+	return g
+}
+
+// cleanup removes the test's files, and restores pkgroot.
+func (g *graphTest) cleanup() {
+	os.RemoveAll(g.dir)
+	pkgroot, exportroot = g.pkgroot, g.exportroot
+}
+
+func (g *graphTest) write(name, data string) {
+	path := filepath.Join(g.dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		g.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+		g.Fatal(err)
+	}
+}
+
+// install records the packages as built, as builder.build does.
+func (g *graphTest) install() {
+	for _, a := range g.b.order {
+		if err := ioutil.WriteFile(a.bcfile(), nil, 0644); err != nil {
+			g.Fatal(err)
+		}
+		hash, err := g.b.hash(a)
+		if err != nil {
+			g.Fatal(err)
+		}
+		if err := ioutil.WriteFile(a.hashfile(), hash, 0644); err != nil {
+			g.Fatal(err)
+		}
+	}
+}
+
+// checkGraph checks the output of printGraph,
+// as printed by llgo-build -n.
+func (g *graphTest) checkGraph(what, expected string) {
+	var buf bytes.Buffer
+	g.b.printGraph(&buf)
+	if buf.String() != expected {
+		g.Errorf("%s: got graph\n%s\nexpected\n%s", what, buf.String(), expected)
+	}
+}
+
+func TestGraphStale(t *testing.T) {
+	g := newGraphTest(t)
+	defer g.cleanup()
+	llgoVersionOnce.Do(func() { llgoVersionOnce.version = "llgo-build test" })
+	defer func(orig bool) { generateDebug = orig }(generateDebug)
+
+	const stale = "build a\nbuild b <- a\n"
+	const upToDate = "skip a\nskip b <- a\n"
+	g.checkGraph("not installed", stale)
+	g.install()
+	g.checkGraph("installed", upToDate)
+
+	// Only the contents of the files are hashed.
+	future := time.Now().Add(time.Hour)
+	if err := os.Chtimes(filepath.Join(g.dir, "a", "a.go"), future, future); err != nil {
+		t.Fatal(err)
+	}
+	g.checkGraph("touched a.go", upToDate)
+	g.write("a/a.go", "package a\n\nvar A int\n")
+	g.checkGraph("changed a.go", stale)
+	g.install()
+
+	g.write("b/b.h", "int b(int);\n")
+	g.checkGraph("changed b.h", "skip a\nbuild b <- a\n")
+	g.install()
+
+	generateDebug = !generateDebug
+	g.checkGraph("changed -g", stale)
+	g.install()
+
+	g.write("pkg/a.lgx", "export data\n")
+	g.checkGraph("changed a.lgx", "skip a\nbuild b <- a\n")
+	g.install()
+	g.checkGraph("reinstalled", upToDate)
+
+	if err := os.Remove(g.a.bcfile()); err != nil {
+		t.Fatal(err)
+	}
+	g.checkGraph("removed a.bc", stale)
+
+	buildDeps = false
+	defer func() { buildDeps = true }()
+	g.checkGraph("-build-deps=false", upToDate)
+}
+
+func TestGraphRoot(t *testing.T) {
+	g := newGraphTest(t)
+	defer g.cleanup()
+	llgoVersionOnce.Do(func() { llgoVersionOnce.version = "llgo-build test" })
+	g.install()
+	g.c.root = true
+	g.checkGraph("root", "skip a\nbuild b <- a\n")
+}
+
+func TestGraphPkgConfig(t *testing.T) {
+	g := newGraphTest(t)
+	defer g.cleanup()
+	llgoVersionOnce.Do(func() { llgoVersionOnce.version = "llgo-build test" })
+	g.write("pkg-config", "#!/bin/sh\ncat \"$(dirname \"$0\")/pkg-config.out\"\n")
+	if err := os.Chmod(filepath.Join(g.dir, "pkg-config"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Setenv("PKG_CONFIG", os.Getenv("PKG_CONFIG"))
+	os.Setenv("PKG_CONFIG", filepath.Join(g.dir, "pkg-config"))
+	g.write("pkg-config.out", "-lfoo\n")
+	g.c.pkg.CgoPkgConfig = []string{"foo"}
+
+	g.install()
+	g.checkGraph("installed", "skip a\nskip b <- a\n")
+	g.write("pkg-config.out", "-lfoo -lbar\n")
+	g.checkGraph("changed pkg-config output", "skip a\nbuild b <- a\n")
+}
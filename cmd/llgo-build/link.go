@@ -5,12 +5,16 @@
 package main
 
 import (
+	"bytes"
 	"go/build"
 	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
+
+	llgobuild "github.com/axw/llgo/build"
 )
 
 // linkdeps links dependencies into the specified output file.
@@ -69,6 +73,10 @@ func linkdeps(pkg *build.Package, output string) error {
 	llvmlink := filepath.Join(llvmbindir, "llvm-link")
 	args := []string{"-o", output, output}
 	var ldflags []string
+	exports, err := cgoExports(pkg)
+	if err != nil {
+		return err
+	}
 	for _, path := range depslist {
 		if path == pkg.ImportPath {
 			continue
@@ -87,6 +95,11 @@ func linkdeps(pkg *build.Package, output string) error {
 		} else {
 			ldflags = append(ldflags, pkgldflags...)
 		}
+		if pkgexports, err := readCgoExports(path); err != nil {
+			return err
+		} else {
+			exports = append(exports, pkgexports...)
+		}
 	}
 	cmd := exec.Command(llvmlink, args...)
 	cmd.Stdout = os.Stdout
@@ -95,6 +108,18 @@ func linkdeps(pkg *build.Package, output string) error {
 		return err
 	}
 
+	if len(stringVars) > 0 {
+		if err = setStringVars(output); err != nil {
+			return err
+		}
+	}
+
+	if lto {
+		if err = optimizeProgram(output, exports); err != nil {
+			return err
+		}
+	}
+
 	if !emitllvm || triple == "pnacl" {
 		input := output
 		if strings.Contains(triple, "darwin") || strings.Contains(triple, "mac") {
@@ -103,6 +128,7 @@ func linkdeps(pkg *build.Package, output string) error {
 			// See discussion in issue #49 for more details.
 			input += ".o"
 			args := []string{"-g", "-c", "-o", input, output}
+			args = append(args, clangArgs()...)
 			cmd := exec.Command(clang, args...)
 			cmd.Stdout = os.Stdout
 			cmd.Stderr = os.Stderr
@@ -111,11 +137,53 @@ func linkdeps(pkg *build.Package, output string) error {
 			}
 		}
 
-		args := []string{"-pthread", "-v", "-g", "-o", output, input}
+		// The flags with which the program is linked, which the
+		// user of a C archive must also link it with.
+		linkflags := []string{"-pthread"}
 		if triple == "pnacl" {
-			args = append(args, "-l", "ppapi")
+			linkflags = append(linkflags, "-l", "ppapi")
+		}
+		if race {
+			linkflags = append(linkflags, "-fsanitize=thread")
 		}
-		args = append(args, ldflags...)
+		linkflags = append(linkflags, clangArgs()...)
+		linkflags = append(linkflags, ldflags...)
+
+		if buildmode == "c-archive" {
+			// Compile the program to a single object, unless
+			// that was done above, and archive it. The archive
+			// includes the runtime; the libraries it needs are
+			// named in a file alongside it (see buildPackage).
+			object := input
+			if object == output {
+				object = output + ".o"
+				args := []string{"-g", "-c", "-o", object, input}
+				args = append(args, clangArgs()...)
+				cmd := exec.Command(clang, args...)
+				cmd.Stdout = os.Stdout
+				cmd.Stderr = os.Stderr
+				if err = runCmd(cmd); err != nil {
+					return err
+				}
+			}
+			defer os.Remove(object)
+			if err = os.Remove(output); err != nil {
+				return err
+			}
+			cmd := exec.Command("ar", "rcs", output, object)
+			cmd.Stdout = os.Stdout
+			cmd.Stderr = os.Stderr
+			if err = runCmd(cmd); err != nil {
+				return err
+			}
+			return writeLinkflags(output, linkflags)
+		}
+
+		args := []string{"-v", "-g", "-o", output, input}
+		if buildmode == "c-shared" {
+			args = append(args, "-shared", "-fPIC")
+		}
+		args = append(args, linkflags...)
 		cmd := exec.Command(clang+"++", args...)
 		cmd.Stdout = os.Stdout
 		cmd.Stderr = os.Stderr
@@ -127,12 +195,172 @@ func linkdeps(pkg *build.Package, output string) error {
 	return nil
 }
 
+// setStringVars sets the string variables named
+// with -X in the linked program in the bitcode file.
+func setStringVars(bcfile string) error {
+	args := append(stringVars.Args(), "-o", bcfile, bcfile)
+	cmd := exec.Command(llgobin, args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return runCmd(cmd)
+}
+
+// ltoEntryPoints are the symbols that must remain visible
+// outside a program optimised with -lto: the entry point
+// called by the C runtime, and those called by libppapi in
+// a PNaCl program.
+var ltoEntryPoints = []string{
+	"main",
+	"PPP_InitializeModule",
+	"PPP_GetInterface",
+	"PPP_ShutdownModule",
+}
+
+// optimizeProgram performs link-time optimisation on the fully
+// linked program in the bitcode file. Every symbol other than
+// those that must remain visible outside the program, listed
+// by ltoPublicSymbols, is internalised; unused functions and
+// globals are then removed, and functions are inlined and
+// constants propagated across package boundaries.
+func optimizeProgram(bcfile string, exports []string) error {
+	symbols, err := definedSymbols(bcfile)
+	if err != nil {
+		return err
+	}
+	public := ltoPublicSymbols(symbols, exports)
+	args := []string{
+		"-internalize",
+		"-internalize-public-api-list=" + strings.Join(public, ","),
+		"-globaldce",
+		"-ipsccp",
+		"-inline",
+		"-constprop",
+		"-globalopt",
+		"-globaldce",
+		"-o", bcfile, bcfile,
+	}
+	cmd := exec.Command(filepath.Join(llvmbindir, "opt"), args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return runCmd(cmd)
+}
+
+// definedSymbols returns the external symbols
+// defined in the bitcode file, as listed by llvm-nm.
+func definedSymbols(bcfile string) ([]string, error) {
+	var buf bytes.Buffer
+	cmd := exec.Command(filepath.Join(llvmbindir, "llvm-nm"), "--defined-only", "--extern-only", bcfile)
+	cmd.Stdout = &buf
+	cmd.Stderr = os.Stderr
+	if err := runCmd(cmd); err != nil {
+		return nil, err
+	}
+	var symbols []string
+	for _, line := range strings.Split(buf.String(), "\n") {
+		// Each line is "[address] type name".
+		if fields := strings.Fields(line); len(fields) >= 2 {
+			symbols = append(symbols, fields[len(fields)-1])
+		}
+	}
+	return symbols, nil
+}
+
+// ltoPublicSymbols returns those of the symbols defined in
+// a program that must remain visible outside it: the entry
+// points, the C functions that cgo generates for "//export"
+// comments, which are named by the exports, and the functions
+// named by build.CgoExportSymbol that they call.
+func ltoPublicSymbols(symbols, exports []string) []string {
+	public := make(map[string]bool)
+	for _, name := range ltoEntryPoints {
+		public[name] = true
+	}
+	for _, name := range exports {
+		public[name] = true
+	}
+	var result []string
+	for _, symbol := range symbols {
+		if public[symbol] || llgobuild.IsCgoExportSymbol(symbol) {
+			result = append(result, symbol)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// cgoExports returns the names of the functions exported
+// with "//export" comments in the package's cgo files.
+func cgoExports(pkg *build.Package) ([]string, error) {
+	var exports []string
+	for _, file := range pkg.CgoFiles {
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(pkg.Dir, file)
+		}
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			if strings.HasPrefix(line, "//export ") {
+				if fields := strings.Fields(line[len("//export "):]); len(fields) > 0 {
+					exports = append(exports, fields[0])
+				}
+			}
+		}
+	}
+	return exports, nil
+}
+
+// writeCgoExports writes the names of the functions exported
+// with cgo to a file, one name per line, so they can be kept
+// visible when linking a program with -lto.
+func writeCgoExports(pkgpath string, exports []string) error {
+	file := filepath.Join(pkgroot, pkgpath+".cgoexports")
+	return ioutil.WriteFile(file, []byte(strings.Join(exports, "\n")), 0644)
+}
+
+// readCgoExports reads the names written by writeCgoExports.
+func readCgoExports(pkgpath string) ([]string, error) {
+	data, err := ioutil.ReadFile(filepath.Join(pkgroot, pkgpath+".cgoexports"))
+	if err == nil {
+		return strings.Fields(string(data)), nil
+	} else if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return nil, err
+}
+
 // writeLdflags writes CGO_LDFLAGS flags to a file, one argument per line.
 func writeLdflags(pkgpath string, flags []string) error {
 	file := filepath.Join(pkgroot, pkgpath+".ldflags")
 	return ioutil.WriteFile(file, []byte(strings.Join(flags, "\n")), 0644)
 }
 
+// linkflagsFile returns the file alongside a C archive
+// naming the flags with which it must be linked.
+func linkflagsFile(archive string) string {
+	ext := filepath.Ext(archive)
+	return archive[:len(archive)-len(ext)] + ".ldflags"
+}
+
+// writeLinkflags writes the flags with which a C archive must be
+// linked to its linkflagsFile, on a single line, so that they
+// may be passed to the linker with "$(cat lib.ldflags)".
+func writeLinkflags(archive string, flags []string) error {
+	data := strings.Join(flags, " ") + "\n"
+	return ioutil.WriteFile(linkflagsFile(archive), []byte(data), 0644)
+}
+
+// installGdbScript copies the runtime's gdb helper script
+// to the package root, where gdb users may source it.
+func installGdbScript(pkg *build.Package) error {
+	data, err := ioutil.ReadFile(filepath.Join(pkg.Dir, "runtime-gdb.py"))
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(pkgroot, "runtime-gdb.py"), data, 0644)
+}
+
 // readLdflags reads CGO_LDFLAGS written to a file by writeLdflags.
 func readLdflags(pkgpath string) ([]string, error) {
 	data, err := ioutil.ReadFile(filepath.Join(pkgroot, pkgpath+".ldflags"))
@@ -0,0 +1,170 @@
+// Copyright 2014 The llgo Authors.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"debug/elf"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLTOPublicSymbols(t *testing.T) {
+	symbols := []string{
+		"Add",
+		"__llgo.cgoexport.main.Add",
+		"main",
+		"main.Add",
+		"main.main",
+		"runtime.malloc",
+	}
+	public := ltoPublicSymbols(symbols, []string{"Add", "Missing"})
+	expected := []string{"Add", "__llgo.cgoexport.main.Add", "main"}
+	if !reflect.DeepEqual(public, expected) {
+		t.Errorf("got %v, expected %v", public, expected)
+	}
+}
+
+const ltoCSharedGo = `package main
+
+import "C"
+
+//export Add
+func Add(a, b C.int) C.int {
+	return a + b
+}
+
+func main() {}
+`
+
+const ltoCSharedC = `#include <stdio.h>
+#include "libadd.h"
+
+int main() {
+	printf("%d\n", Add(2, 3));
+	return 0;
+}
+`
+
+// TestLTOCShared builds a shared library with -lto, and checks
+// that the function it exports can be called from C.
+func TestLTOCShared(t *testing.T) {
+	for _, tool := range []string{"llgo", "clang"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			t.Skipf("%s not found", tool)
+		}
+	}
+	dir, err := ioutil.TempDir("", "llgo-build")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	run := func(name string, args ...string) string {
+		cmd := exec.Command(name, args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "LD_LIBRARY_PATH="+dir)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("%s failed: %v\n%s", name, err, out)
+		}
+		return string(out)
+	}
+	write := func(name, data string) {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("add.go", ltoCSharedGo)
+	write("main.c", ltoCSharedC)
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	llgobuild := filepath.Join(dir, "llgo-build")
+	run("go", "build", "-o", llgobuild, wd)
+	run(llgobuild, "-buildmode=c-shared", "-lto", "-o", "libadd.so", "add.go")
+
+	f, err := elf.Open(filepath.Join(dir, "libadd.so"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	symbols, err := f.DynamicSymbols()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found bool
+	for _, symbol := range symbols {
+		if symbol.Name == "Add" && symbol.Section != elf.SHN_UNDEF {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatal("Add is not exported by libadd.so")
+	}
+
+	run("clang", "-o", "main", "main.c", "-L", dir, "-ladd")
+	if out := strings.TrimSpace(run(filepath.Join(dir, "main"))); out != "5" {
+		t.Errorf("got %q, expected %q", out, "5")
+	}
+}
+
+// TestCArchive builds a C archive, and checks that it can be
+// linked into a C program with the flags written alongside it.
+func TestCArchive(t *testing.T) {
+	for _, tool := range []string{"llgo", "clang"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			t.Skipf("%s not found", tool)
+		}
+	}
+	dir, err := ioutil.TempDir("", "llgo-build")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	run := func(name string, args ...string) string {
+		cmd := exec.Command(name, args...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("%s failed: %v\n%s", name, err, out)
+		}
+		return string(out)
+	}
+	write := func(name, data string) {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("add.go", ltoCSharedGo)
+	write("main.c", ltoCSharedC)
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	llgobuild := filepath.Join(dir, "llgo-build")
+	run("go", "build", "-o", llgobuild, wd)
+	run(llgobuild, "-buildmode=c-archive", "-o", "libadd.a", "add.go")
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, "libadd.ldflags"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ldflags := strings.Fields(string(data))
+	if len(ldflags) == 0 || ldflags[0] != "-pthread" {
+		t.Errorf("got ldflags %q, expected them to begin with -pthread", ldflags)
+	}
+	args := append([]string{"-o", "main", "main.c", "libadd.a"}, ldflags...)
+	run("clang", args...)
+	if out := strings.TrimSpace(run(filepath.Join(dir, "main"))); out != "5" {
+		t.Errorf("got %q, expected %q", out, "5")
+	}
+}
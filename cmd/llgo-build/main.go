@@ -12,8 +12,8 @@ import (
 	"io/ioutil"
 	"log"
 	"os"
-	"path/filepath"
 	"runtime"
+	"time"
 )
 
 var (
@@ -34,6 +34,20 @@ var (
 	buildDeps     bool = true
 	work          bool
 	run           bool
+	race          bool
+	softfloat     bool
+	cover         bool
+	covermode     string
+	bench         string
+	benchtime     time.Duration
+	benchmem      bool
+	parallelism   int = runtime.NumCPU()
+	dryrun        bool
+	exportroot    string
+	overlay       string
+	lto           bool
+	stringVars    = make(llgobuild.StringVarsFlag)
+	buildmode     string
 )
 
 func init() {
@@ -47,6 +61,37 @@ func init() {
 	flag.BoolVar(&buildDeps, "build-deps", buildDeps, "Whether to also build dependency packages or not")
 	flag.BoolVar(&work, "work", work, "Print the name of the temporary work directory and do not delete it when exiting")
 	flag.BoolVar(&run, "run", run, "Run the command and dispose of the binary")
+	flag.StringVar(&overlay, "overlay", "", "A list of directory trees whose packages overlay those with the same import path")
+	flag.Var(stringVars, "X", "Set the value of a string variable: importpath.name=value (may be repeated)")
+	flag.StringVar(&buildmode, "buildmode", "exe", "The build mode for commands: exe, c-archive (static library) or c-shared (shared library)")
+	flag.BoolVar(&lto, "lto", lto, "Perform link-time optimisation across packages when linking a program")
+	flag.IntVar(&parallelism, "p", parallelism, "The number of packages that can be built in parallel")
+	flag.BoolVar(&dryrun, "n", dryrun, "Print the packages that would be built, and their dependencies, but do not build them")
+	flag.BoolVar(&race, "race", race, "Enable data race detection")
+	flag.BoolVar(&softfloat, "softfloat", softfloat, "Implement floating point operations in software")
+	flag.BoolVar(&cover, "cover", cover, "Enable coverage analysis when building a test binary")
+	flag.StringVar(&covermode, "covermode", "", "The coverage mode: set, count or atomic (implies -cover)")
+	flag.StringVar(&bench, "bench", "", "Run benchmarks matching the regular expression, with -test and -run")
+	flag.DurationVar(&benchtime, "benchtime", time.Second, "Approximate run time for each benchmark, with -test and -run")
+	flag.BoolVar(&benchmem, "benchmem", false, "Print memory allocation statistics for benchmarks, with -test and -run")
+}
+
+// isLibrary reports whether commands are
+// being built as C archives or shared libraries.
+func isLibrary() bool {
+	return buildmode == "c-archive" || buildmode == "c-shared"
+}
+
+// libraryExt returns the file extension for
+// commands built with the current build mode.
+func libraryExt() string {
+	switch buildmode {
+	case "c-archive":
+		return ".a"
+	case "c-shared":
+		return ".so"
+	}
+	return ""
 }
 
 func main() {
@@ -60,20 +105,42 @@ func main() {
 		clang = "clang"
 	}
 
+	if covermode != "" {
+		cover = true
+	} else if cover {
+		// Unsynchronised counter updates would
+		// be reported by the race detector.
+		covermode = "set"
+		if race {
+			covermode = "atomic"
+		}
+	}
+	if cover && !test {
+		log.Fatal("-cover may only be used with -test")
+	}
+	switch buildmode {
+	case "exe":
+	case "c-archive", "c-shared":
+		if test || run {
+			log.Fatalf("-buildmode=%s may not be used with -test or -run", buildmode)
+		}
+	default:
+		log.Fatalf("invalid -buildmode: %q", buildmode)
+	}
+
 	llgobuildctx, err := llgobuild.ContextFromTriple(triple)
 	if err != nil {
 		log.Fatal(err)
 	}
 	buildctx = &llgobuildctx.Context
-
-	// pkgroot = $GOPATH/pkg/llgo/<triple>
-	gopath := os.Getenv("GOPATH")
-	if gopath == "" {
-		gopath = runtime.GOROOT()
-	} else {
-		gopath = filepath.SplitList(gopath)[0]
+	if race {
+		buildctx.BuildTags = append(buildctx.BuildTags, "race")
 	}
-	pkgroot = filepath.Join(gopath, "pkg", "llgo", triple)
+
+	pkgroot = llgobuild.PkgRoot(triple, race, softfloat)
+	// Export data is written by llgo, which
+	// knows nothing of the suffixes of pkgroot.
+	exportroot = llgobuild.PkgRoot(triple, false, false)
 
 	// Create a temporary work dir.
 	workdir, err = ioutil.TempDir("", "llgo")
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"go/ast"
 	"go/build"
+	"go/doc"
 	"go/format"
 	"go/parser"
 	"go/token"
@@ -13,6 +14,10 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	llgobuild "github.com/axw/llgo/build"
 )
 
 func buildPackageTests(pkgpath string) error {
@@ -23,110 +28,282 @@ func buildPackageTests(pkgpath string) error {
 }
 
 func writeTestMain(pkg *build.Package, linkfile string) (err error) {
+	data, err := testMainSource(pkg)
+	if err != nil {
+		return err
+	}
+	gofile := filepath.Join(filepath.Dir(linkfile), "main.go")
+	if err = ioutil.WriteFile(gofile, data, 0644); err != nil {
+		return err
+	}
+
+	// The test main imports the packages under test from
+	// the work directory; see testExportFile.
+	pkgworkdir := filepath.Dir(linkfile)
+	importArgs := []string{"-importfile", pkg.ImportPath + "=" + testExportFile(pkgworkdir, pkg.ImportPath)}
+	test_bc := ""
+	if len(pkg.XTestGoFiles) > 0 {
+		file := filepath.Base(linkfile)
+		test_bc = filepath.Join(pkgworkdir, file[:len(file)-3]+"_test.bc")
+		if err := buildXTestPackage(pkg, pkgworkdir, test_bc); err != nil {
+			return err
+		}
+		xtestpath := pkg.ImportPath + "_test"
+		importArgs = append(importArgs, "-importfile", xtestpath+"="+testExportFile(pkgworkdir, xtestpath))
+	}
+
+	mainbc := gofile[:len(gofile)-3] + ".bc"
+	args := append(llgoArgs(), importArgs...)
+	args = append(args, "-o", mainbc, gofile)
+	cmd := exec.Command(llgobin, args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err = runCmd(cmd)
+	if err != nil {
+		return err
+	}
+	llvmlink := filepath.Join(llvmbindir, "llvm-link")
+	args = []string{"-o", linkfile, linkfile, mainbc}
+	if test_bc != "" {
+		args = append(args, test_bc)
+	}
+
+	cmd = exec.Command(llvmlink, args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err = runCmd(cmd); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// testMainSource returns the source of the main package of the test
+// program for pkg, which runs its tests, benchmarks and examples.
+func testMainSource(pkg *build.Package) ([]byte, error) {
 	w := bytes.NewBuffer(nil)
 	tests := bytes.NewBuffer(nil)
+	benchmarks := bytes.NewBuffer(nil)
+	examples := bytes.NewBuffer(nil)
 	fset := token.NewFileSet()
 
 	w.WriteString(`package main
 
 import (
+	"regexp"
 	"testing"
 `)
 
 	tests.WriteString("var tests = []testing.InternalTest{\n")
+	benchmarks.WriteString("var benchmarks = []testing.InternalBenchmark{\n")
+	examples.WriteString("var examples = []testing.InternalExample{\n")
 
 	imports := make(map[string]bool)
+	addimport := func(pkgname, pkgpath string) {
+		if !imports[pkgpath] {
+			w.WriteString(fmt.Sprintf("\t%s \"%s\"\n", pkgname, pkgpath))
+			imports[pkgpath] = true
+		}
+	}
 
+	// The packages under test are imported with names that
+	// cannot conflict with those imported by the test main.
 	addtests := func(pkgname, pkgpath string, gofiles []string) error {
+		var files []*ast.File
 		for _, tf := range gofiles {
-			f, err := parser.ParseFile(fset, tf, nil, 0)
+			f, err := parser.ParseFile(fset, tf, nil, parser.ParseComments)
 			if err != nil {
 				return err
 			}
-			for k, o := range f.Scope.Objects {
-				if strings.HasPrefix(k, "Test") && o.Kind == ast.Fun {
-					if !imports[pkgpath] {
-						w.WriteString(fmt.Sprintf("\t\"%s\"\n", pkgpath))
-						imports[pkgpath] = true
-					}
-					tests.WriteString(fmt.Sprintf("\t{\"%s\", %s.%s},\n", k, pkgname, k))
+			files = append(files, f)
+			for _, decl := range f.Decls {
+				fn, ok := decl.(*ast.FuncDecl)
+				if !ok || fn.Recv != nil {
+					continue
 				}
+				name := fn.Name.Name
+				switch {
+				case isTest(name, "Test"):
+					addimport(pkgname, pkgpath)
+					tests.WriteString(fmt.Sprintf("\t{\"%s\", %s.%s},\n", name, pkgname, name))
+				case isTest(name, "Benchmark"):
+					addimport(pkgname, pkgpath)
+					benchmarks.WriteString(fmt.Sprintf("\t{\"%s\", %s.%s},\n", name, pkgname, name))
+				}
+			}
+		}
+		for _, e := range doc.Examples(files...) {
+			if e.Output == "" && !e.EmptyOutput {
+				// Examples without an output comment
+				// are compiled but not run.
+				continue
 			}
+			addimport(pkgname, pkgpath)
+			if e.Unordered {
+				// Set by name, as the testing packages of
+				// older releases have no Unordered field.
+				examples.WriteString(fmt.Sprintf("\t{Name: \"Example%s\", F: %s.Example%s, Output: %q, Unordered: true},\n", e.Name, pkgname, e.Name, e.Output))
+				continue
+			}
+			examples.WriteString(fmt.Sprintf("\t{\"Example%s\", %s.Example%s, %q},\n", e.Name, pkgname, e.Name, e.Output))
 		}
 		return nil
 	}
-	if err = addtests(pkg.Name, pkg.ImportPath, pkg.TestGoFiles); err != nil {
-		return err
+	if err := addtests("_test", pkg.ImportPath, pkg.TestGoFiles); err != nil {
+		return nil, err
 	}
-	if err = addtests(pkg.Name+"_test", pkg.ImportPath+"_test", pkg.XTestGoFiles); err != nil {
-		return err
+	if err := addtests("_xtest", pkg.ImportPath+"_test", pkg.XTestGoFiles); err != nil {
+		return nil, err
 	}
 	w.WriteString(")\n")
 	tests.WriteString("}\n")
+	benchmarks.WriteString("}\n")
+	examples.WriteString("}\n")
 	w.Write(tests.Bytes())
+	w.Write(benchmarks.Bytes())
+	w.Write(examples.Bytes())
 	w.WriteString(`
 
-var benchmarks = []testing.InternalBenchmark{}
-var examples = []testing.InternalExample{}
+var matchPat string
+var matchRe *regexp.Regexp
 
-func matchString(pat, str string) (bool, error) {
-	return true, nil
+func matchString(pat, str string) (result bool, err error) {
+	if matchRe == nil || matchPat != pat {
+		matchPat = pat
+		matchRe, err = regexp.Compile(matchPat)
+		if err != nil {
+			return
+		}
+	}
+	return matchRe.MatchString(str), nil
 }
-
+`)
+	if cover {
+		writeCoverMain(w, pkg)
+	} else {
+		w.WriteString(`
 func main() {
 	testing.Main(matchString, tests, benchmarks, examples)
 }
-
 `)
-	data, err := format.Source(w.Bytes())
-	if err != nil {
-		return err
-	}
-	gofile := filepath.Join(filepath.Dir(linkfile), "main.go")
-	if err = ioutil.WriteFile(gofile, data, 0644); err != nil {
-		return err
 	}
+	return format.Source(w.Bytes())
+}
 
-	test_bc := ""
-	if len(pkg.XTestGoFiles) > 0 {
-		args2 := []string{"-c", "-triple", triple, "-importpath", pkg.ImportPath + "_test"}
-		file := filepath.Base(linkfile)
-		file = file[:len(file)-3]
-		test_bc = filepath.Join(workdir, file+"_test.bc")
-		args2 = append(args2, "-o", test_bc)
-		args2 = append(args2, pkg.XTestGoFiles...)
-		cmd := exec.Command("llgo", args2...)
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-		err := runCmd(cmd)
-		if err != nil {
-			return err
-		}
-	}
+// testExportFile returns the file in pkgworkdir to which the export
+// data of a package built for testing is written: that of a package
+// under test, compiled with its internal test files, or that of its
+// external test package. They are kept apart from the export data
+// in GOPATH, which must describe the packages without their tests.
+func testExportFile(pkgworkdir, importpath string) string {
+	return filepath.Join(pkgworkdir, "_test", importpath+".lgx")
+}
 
-	mainbc := gofile[:len(gofile)-3] + ".bc"
-	args := []string{"-c", "-triple", triple, "-o", mainbc, gofile}
-	cmd := exec.Command("llgo", args...)
+// buildXTestPackage compiles the external test package
+// (package foo_test) for pkg into a separate module. The
+// package under test must already have been compiled with
+// its internal test files into pkgworkdir; the external
+// tests import it from there, so that they may refer to
+// anything those files export.
+func buildXTestPackage(pkg *build.Package, pkgworkdir, output string) error {
+	xtestpath := pkg.ImportPath + "_test"
+	args := append(llgoArgs(), "-importpath", xtestpath, "-o", output)
+	args = append(args, "-importfile", pkg.ImportPath+"="+testExportFile(pkgworkdir, pkg.ImportPath))
+	args = append(args, "-exportfile", testExportFile(pkgworkdir, xtestpath))
+	args = append(args, pkg.XTestGoFiles...)
+	cmd := exec.Command(llgobin, args...)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
-	err = runCmd(cmd)
-	if err != nil {
-		return err
+	return runCmd(cmd)
+}
+
+// writeCoverMain writes the main function for a test program
+// built with -cover, along with the declarations needed to
+// register the coverage counters of the package under test.
+//
+// The compiler records the counters in a variable named by
+// build.CoverSymbol; coverFiles is a weak definition of the
+// same variable, which the linker replaces with the real one.
+func writeCoverMain(w *bytes.Buffer, pkg *build.Package) {
+	fmt.Fprintf(w, `
+type coverFile struct {
+	Name     string
+	Counters []uint32
+	Pos      []uint32
+	NumStmt  []uint16
+}
+
+// #llgo name: %s
+// #llgo linkage: weak
+var coverFiles []coverFile
+
+var (
+	coverCounters = make(map[string][]uint32)
+	coverBlocks   = make(map[string][]testing.CoverBlock)
+)
+
+func coverRegisterFile(f coverFile) {
+	if 3*len(f.Counters) != len(f.Pos) || len(f.Counters) != len(f.NumStmt) {
+		panic("coverage: mismatched sizes")
 	}
-	llvmlink := filepath.Join(llvmbindir, "llvm-link")
-	args = []string{"-o", linkfile, linkfile, mainbc}
-	if test_bc != "" {
-		args = append(args, test_bc)
+	block := make([]testing.CoverBlock, len(f.Counters))
+	for i := range block {
+		block[i] = testing.CoverBlock{
+			Line0: f.Pos[3*i+0],
+			Col0:  uint16(f.Pos[3*i+2]),
+			Line1: f.Pos[3*i+1],
+			Col1:  uint16(f.Pos[3*i+2] >> 16),
+			Stmts: f.NumStmt[i],
+		}
 	}
+	coverCounters[f.Name] = f.Counters
+	coverBlocks[f.Name] = block
+}
 
-	cmd = exec.Command(llvmlink, args...)
+func main() {
+	for _, f := range coverFiles {
+		coverRegisterFile(f)
+	}
+	testing.RegisterCover(testing.Cover{
+		Mode:            %q,
+		Counters:        coverCounters,
+		Blocks:          coverBlocks,
+		CoveredPackages: %q,
+	})
+	testing.Main(matchString, tests, benchmarks, examples)
+}
+`, llgobuild.CoverSymbol(pkg.ImportPath), covermode, " in "+pkg.ImportPath)
+}
+
+// runTest runs a test binary, passing on the
+// flags for selecting and running benchmarks.
+func runTest(testfile string) error {
+	var args []string
+	if bench != "" {
+		args = append(args,
+			"-test.bench", bench,
+			"-test.benchtime", benchtime.String(),
+			fmt.Sprintf("-test.benchmem=%v", benchmem),
+		)
+	}
+	cmd := exec.Command(testfile, args...)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
-	if err = runCmd(cmd); err != nil {
-		return err
-	}
+	return runCmd(cmd)
+}
 
-	return nil
+// isTest reports whether name looks like a test, benchmark
+// or example function: the prefix followed by a character
+// that is not a lower-case letter.
+func isTest(name, prefix string) bool {
+	if !strings.HasPrefix(name, prefix) {
+		return false
+	}
+	if len(name) == len(prefix) {
+		return true
+	}
+	r, _ := utf8.DecodeRuneInString(name[len(prefix):])
+	return !unicode.IsLower(r)
 }
 
 func linktest(pkg *build.Package, linkfile string) error {
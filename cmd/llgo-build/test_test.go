@@ -0,0 +1,140 @@
+// Copyright 2014 The llgo Authors.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const testMainInternal = `package a
+
+import "testing"
+
+func TestA(t *testing.T) {}
+
+func BenchmarkB(b *testing.B) {}
+
+func helper() {}
+
+func ExampleC() {
+	println("c")
+	// Output: c
+}
+
+func ExampleD() {
+	println("d1")
+	println("d2")
+	// Unordered output:
+	// d2
+	// d1
+}
+
+// ExampleE has no output, so is compiled but not run.
+func ExampleE() {}
+`
+
+const testMainExternal = `package a_test
+
+import "testing"
+
+func TestX(t *testing.T) {}
+
+func BenchmarkY(b *testing.B) {}
+
+func Example() {
+	// Output:
+}
+`
+
+// testMainTables returns the elements of the test main's
+// tables of tests, benchmarks and examples, by table.
+func testMainTables(t *testing.T, src []byte) map[string][]string {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "main.go", src, 0)
+	if err != nil {
+		t.Fatalf("%v\n%s", err, src)
+	}
+	tables := make(map[string][]string)
+	for _, decl := range f.Decls {
+		decl, ok := decl.(*ast.GenDecl)
+		if !ok || decl.Tok != token.VAR {
+			continue
+		}
+		for _, spec := range decl.Specs {
+			spec := spec.(*ast.ValueSpec)
+			if len(spec.Values) != 1 {
+				continue
+			}
+			lit, ok := spec.Values[0].(*ast.CompositeLit)
+			if !ok {
+				continue
+			}
+			name := spec.Names[0].Name
+			tables[name] = []string{}
+			for _, elt := range lit.Elts {
+				var buf bytes.Buffer
+				printer.Fprint(&buf, fset, elt)
+				tables[name] = append(tables[name], buf.String())
+			}
+		}
+	}
+	return tables
+}
+
+func TestTestMainSource(t *testing.T) {
+	dir, err := ioutil.TempDir("", "llgo-build")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	internal := filepath.Join(dir, "a_test.go")
+	external := filepath.Join(dir, "x_test.go")
+	if err := ioutil.WriteFile(internal, []byte(testMainInternal), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(external, []byte(testMainExternal), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	pkg := &build.Package{
+		Dir:          dir,
+		ImportPath:   "a",
+		TestGoFiles:  []string{internal},
+		XTestGoFiles: []string{external},
+	}
+	src, err := testMainSource(pkg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tables := testMainTables(t, src)
+	expected := map[string][]string{
+		"tests": {
+			`{"TestA", _test.TestA}`,
+			`{"TestX", _xtest.TestX}`,
+		},
+		"benchmarks": {
+			`{"BenchmarkB", _test.BenchmarkB}`,
+			`{"BenchmarkY", _xtest.BenchmarkY}`,
+		},
+		"examples": {
+			`{"ExampleC", _test.ExampleC, "c\n"}`,
+			`{Name: "ExampleD", F: _test.ExampleD, Output: "d2\nd1\n", Unordered: true}`,
+			`{"Example", _xtest.Example, ""}`,
+		},
+	}
+	if !reflect.DeepEqual(tables, expected) {
+		t.Errorf("got tables %q, expected %q\n%s", tables, expected, src)
+	}
+}
@@ -20,6 +20,8 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
+
+	llgobuild "github.com/axw/llgo/build"
 )
 
 // goFilesPackage creates a package for building a collection of Go files.
@@ -131,6 +133,34 @@ func translateGccgoExterns(filename string) error {
 	return ioutil.WriteFile(filename, data, 0644)
 }
 
+// cgoExportAsmRegexp matches the assembler names given to the
+// Go functions called by the C functions that cgo generates for
+// "//export" comments. The Go function may be called either by
+// its own name or through a wrapper prefixed with "Cgoexp_".
+var cgoExportAsmRegexp = regexp.MustCompile(`__asm__\("[^"]*\.(?:Cgoexp_)?(\w+)"\)`)
+
+// translateCgoExports rewrites the cgo-generated C file so that
+// the functions exported with cgo call the functions that llgo
+// defines for calling them from C, named by CgoExportSymbol.
+func translateCgoExports(filename, importpath string, exports []string) error {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	exported := make(map[string]bool)
+	for _, name := range exports {
+		exported[name] = true
+	}
+	data = cgoExportAsmRegexp.ReplaceAllFunc(data, func(asm []byte) []byte {
+		name := string(cgoExportAsmRegexp.FindSubmatch(asm)[1])
+		if !exported[name] {
+			return asm
+		}
+		return []byte(fmt.Sprintf("__asm__(%q)", llgobuild.CgoExportSymbol(importpath, name)))
+	})
+	return ioutil.WriteFile(filename, data, 0644)
+}
+
 // find the library directory for the version
 // of gcc found in $PATH.
 func findGcclib() (string, error) {
@@ -154,3 +184,27 @@ func findGcclib() (string, error) {
 func envFields(key string) []string {
 	return strings.Fields(os.Getenv(key))
 }
+
+// pkgConfig runs pkg-config with the specified flag (--cflags
+// or --libs) for the specified packages, and returns the flags
+// it outputs. The PKG_CONFIG environment variable may be used
+// to specify an alternative pkg-config command.
+func pkgConfig(flag string, pkgs []string) ([]string, error) {
+	pkgconfig := os.Getenv("PKG_CONFIG")
+	if pkgconfig == "" {
+		pkgconfig = "pkg-config"
+	}
+	args := append([]string{flag}, pkgs...)
+	var stderr bytes.Buffer
+	cmd := exec.Command(pkgconfig, args...)
+	cmd.Stderr = &stderr
+	if printcommands {
+		s := fmt.Sprint(cmd.Args)
+		log.Println(s[1 : len(s)-1])
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s %s: %v\n%s", pkgconfig, strings.Join(args, " "), err, stderr.Bytes())
+	}
+	return strings.Fields(string(out)), nil
+}
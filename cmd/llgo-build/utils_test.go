@@ -0,0 +1,122 @@
+// Copyright 2014 The llgo Authors.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"go/build"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// pkgConfigStub outputs a -I flag for each package with --cflags,
+// and a -l flag for each with --libs, failing for package "missing".
+const pkgConfigStub = `#!/bin/sh
+flag=$1
+shift
+for pkg; do
+	if [ "$pkg" = missing ]; then
+		echo "Package $pkg was not found" >&2
+		exit 1
+	fi
+	case $flag in
+	--cflags) printf -- "-I/usr/include/%s " "$pkg";;
+	--libs) printf -- "-l%s " "$pkg";;
+	esac
+done
+echo
+`
+
+// withPkgConfigStub runs f with PKG_CONFIG set to pkgConfigStub,
+// no CGO_*FLAGS, and pkgroot set to a temporary directory.
+func withPkgConfigStub(t *testing.T, f func(dir string)) {
+	dir, err := ioutil.TempDir("", "llgo-build")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	stub := filepath.Join(dir, "pkg-config")
+	if err := ioutil.WriteFile(stub, []byte(pkgConfigStub), 0755); err != nil {
+		t.Fatal(err)
+	}
+	env := map[string]string{
+		"PKG_CONFIG":   stub,
+		"CGO_CFLAGS":   "",
+		"CGO_CPPFLAGS": "",
+		"CGO_LDFLAGS":  "",
+	}
+	for key, value := range env {
+		defer os.Setenv(key, os.Getenv(key))
+		os.Setenv(key, value)
+	}
+	defer func(orig string) { pkgroot = orig }(pkgroot)
+	pkgroot = dir
+	f(dir)
+}
+
+// TestPkgConfig checks that the flags output by pkg-config for the
+// packages named in #cgo pkg-config directives are passed to the
+// C compiler, and written to the package's .ldflags file.
+func TestPkgConfig(t *testing.T) {
+	withPkgConfigStub(t, func(dir string) {
+		pkg := &build.Package{
+			ImportPath:   "pc",
+			Dir:          dir,
+			CgoFiles:     []string{"pc.go"},
+			CgoCFLAGS:    []string{"-DPC"},
+			CgoLDFLAGS:   []string{"-lpc"},
+			CgoPkgConfig: []string{"foo", "bar"},
+		}
+		cppflags, cflags, ldflags, err := cgoFlags(pkg, "work")
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := []string{"-I", "work", "-I", dir, "-I/usr/include/foo", "-I/usr/include/bar"}
+		if !reflect.DeepEqual(cppflags, expected) {
+			t.Errorf("got CPPFLAGS %q, expected %q", cppflags, expected)
+		}
+		if !reflect.DeepEqual(cflags, []string{"-DPC"}) {
+			t.Errorf("got CFLAGS %q, expected %q", cflags, []string{"-DPC"})
+		}
+		expected = []string{"-lpc", "-lfoo", "-lbar"}
+		if len(ldflags) < len(expected) || !reflect.DeepEqual(ldflags[:len(expected)], expected) {
+			t.Errorf("got LDFLAGS %q, expected them to begin with %q", ldflags, expected)
+		}
+
+		if err := writeLdflags(pkg.ImportPath, ldflags); err != nil {
+			t.Fatal(err)
+		}
+		written, err := readLdflags(pkg.ImportPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(written, ldflags) {
+			t.Errorf("got written LDFLAGS %q, expected %q", written, ldflags)
+		}
+	})
+}
+
+// TestPkgConfigError checks that a failure of pkg-config is
+// reported, along with its output.
+func TestPkgConfigError(t *testing.T) {
+	withPkgConfigStub(t, func(dir string) {
+		pkg := &build.Package{
+			ImportPath:   "pc",
+			Dir:          dir,
+			CgoFiles:     []string{"pc.go"},
+			CgoPkgConfig: []string{"foo", "missing"},
+		}
+		_, _, _, err := cgoFlags(pkg, "work")
+		if err == nil || !strings.Contains(err.Error(), "Package missing was not found") {
+			t.Errorf("got error %v, expected pkg-config's", err)
+		}
+		if _, err := pkgConfig("--libs", []string{"missing"}); err == nil {
+			t.Error("expected an error")
+		}
+	})
+}
@@ -15,6 +15,7 @@ import (
 
 const gollvmpkgpath = "github.com/axw/gollvm/llvm"
 const llgopkgpath = "github.com/axw/llgo/llgo"
+const llgoreplpkgpath = "github.com/axw/llgo/cmd/llgo-repl"
 
 var (
 	// llgobin is the path to the llgo command.
@@ -58,7 +59,9 @@ func buildLlgo() error {
 		llvmtag = "llvmsvn"
 	}
 	args = append(args, []string{"-tags", llvmtag}...)
-	args = append(args, llgopkgpath)
+	// llgo-repl links with the compiler,
+	// so it is built in the same way.
+	args = append(args, llgopkgpath, llgoreplpkgpath)
 
 	cmd = command("go", args...)
 	cmd.Env = os.Environ()
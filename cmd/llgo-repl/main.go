@@ -0,0 +1,119 @@
+// Copyright 2014 The llgo Authors.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+// llgo-repl is an interactive Go interpreter, built on the llgo
+// compiler. Each input is compiled into a module, along with the
+// declarations entered before it, and run with LLVM's MCJIT.
+//
+// Inputs may be declarations, including imports, or statements.
+// Variables declared with statements persist in the session, and
+// the values of expression statements are printed.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"go/scanner"
+	"go/token"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/axw/gollvm/llvm"
+	"github.com/axw/llgo"
+	llgobuild "github.com/axw/llgo/build"
+)
+
+var triple = flag.String("triple", defaultTriple(), "The target triple")
+
+// defaultTriple returns the triple for the host,
+// in the form used by llgo.
+func defaultTriple() string {
+	arch := runtime.GOARCH
+	switch arch {
+	case "386":
+		arch = "i386"
+	case "amd64":
+		arch = "x86_64"
+	}
+	return fmt.Sprintf("%s-unknown-%s", arch, runtime.GOOS)
+}
+
+// depth returns the nesting depth of brackets, braces and
+// parentheses at the end of the source, so that input may
+// continue over several lines.
+func depth(src string) int {
+	var s scanner.Scanner
+	fset := token.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(src))
+	s.Init(file, []byte(src), nil, 0)
+	n := 0
+	for {
+		_, tok, _ := s.Scan()
+		switch tok {
+		case token.EOF:
+			return n
+		case token.LPAREN, token.LBRACE, token.LBRACK:
+			n++
+		case token.RPAREN, token.RBRACE, token.RBRACK:
+			n--
+		}
+	}
+}
+
+func main() {
+	llvm.InitializeAllTargets()
+	llvm.InitializeAllTargetMCs()
+	llvm.InitializeAllTargetInfos()
+	flag.Parse()
+
+	ctx, err := llgobuild.ContextFromTriple(*triple)
+	if err != nil {
+		log.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "llgo-repl")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	opts := llgo.CompilerOptions{TargetTriple: *triple}
+	s, err := newSession(ctx, opts, dir)
+	if err != nil {
+		os.RemoveAll(dir)
+		log.Fatal(err)
+	}
+	defer s.dispose()
+
+	r := bufio.NewReader(os.Stdin)
+	var input string
+	for {
+		if input == "" {
+			fmt.Print("> ")
+		} else {
+			fmt.Print(". ")
+		}
+		line, err := r.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			fmt.Println()
+			return
+		}
+		input += line
+		if strings.TrimSpace(input) == "" {
+			input = ""
+			continue
+		}
+		if depth(input) > 0 {
+			continue
+		}
+		if err := s.eval(input); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		input = ""
+	}
+}
@@ -0,0 +1,427 @@
+// Copyright 2014 The llgo Authors.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"code.google.com/p/go.tools/go/types"
+	"github.com/axw/llgo"
+	llgobuild "github.com/axw/llgo/build"
+	llgoimporter "github.com/axw/llgo/importer"
+	"github.com/axw/llgo/jit"
+)
+
+// fmtName is the name by which each snippet imports
+// "fmt", for printing the values of expressions.
+const fmtName = "_repl_fmt"
+
+// prelude is included in every snippet. The "main" function
+// of the first snippet initialises the runtime and packages;
+// __replRecover is deferred by each snippet's function, so
+// that a panic does not terminate the session.
+const prelude = `
+func main() {}
+
+func __replRecover() {
+	if r := recover(); r != nil {
+		` + fmtName + `.Println("panic:", r)
+	}
+}
+`
+
+// session holds the state of a REPL session: the declarations
+// and imports entered so far, and the JIT to which the snippets
+// compiled from each input are added.
+type session struct {
+	ctx  *llgobuild.Context
+	opts llgo.CompilerOptions
+	jit  *jit.JIT
+	dir  string
+
+	// imports maps the names of the imported
+	// packages to their import paths.
+	imports map[string]string
+
+	// decls holds the source of the declarations
+	// entered so far, in the order entered.
+	decls []string
+
+	// vars records the names of the package-level
+	// variables declared so far.
+	vars map[string]bool
+
+	// n is the number of snippets compiled so far.
+	n int
+}
+
+func newSession(ctx *llgobuild.Context, opts llgo.CompilerOptions, dir string) (*session, error) {
+	s := &session{
+		ctx:     ctx,
+		opts:    opts,
+		jit:     jit.New(ctx, llgobuild.PkgRoot(ctx.Triple, false, false)),
+		dir:     dir,
+		imports: make(map[string]string),
+		vars:    make(map[string]bool),
+	}
+	if err := s.jit.Load([]string{"runtime", "fmt"}); err != nil {
+		return nil, err
+	}
+	m, err := s.compile(s.source(nil, nil, nil, ""))
+	if err != nil {
+		return nil, err
+	}
+	if err := s.jit.Add(m.Module); err != nil {
+		return nil, err
+	}
+	// The "main" function of the first snippet
+	// initialises the runtime and the packages.
+	rc, err := s.jit.Main([]string{os.Args[0]})
+	if err == nil && rc != 0 {
+		err = fmt.Errorf("initialisation failed with status %d", rc)
+	}
+	return s, err
+}
+
+func (s *session) dispose() {
+	s.jit.Dispose()
+}
+
+// snippet is the result of parsing an input: the imports and
+// declarations that persist, and the statements to execute.
+type snippet struct {
+	imports map[string]string
+	decls   []string
+	vars    []string
+	stmts   []string
+
+	// calls holds the indices of the statements that
+	// are calls, which may or may not have results.
+	calls map[int]bool
+}
+
+// eval parses, type-checks, compiles and runs the input.
+func (s *session) eval(input string) error {
+	snip, err := s.parse(input)
+	if err != nil {
+		return err
+	}
+
+	// Print the results of calls that have any.
+	name := fmt.Sprintf("__repl%d", s.n+1)
+	if len(snip.calls) > 0 {
+		info := &types.Info{Types: make(map[ast.Expr]types.TypeAndValue)}
+		src := s.source(snip.imports, snip.decls, snip.stmts, name)
+		fset, f, err := s.check(src, info)
+		if err != nil {
+			return err
+		}
+		body := findFunc(f, name).Body.List[1:]
+		for i := range snip.calls {
+			call := body[i].(*ast.ExprStmt).X
+			typ := info.Types[call].Type
+			if tuple, ok := typ.(*types.Tuple); typ != nil && (!ok || tuple.Len() > 0) {
+				snip.stmts[i] = fmtName + ".Println(" + nodeString(fset, call) + ")"
+			}
+		}
+	}
+
+	src := s.source(snip.imports, snip.decls, snip.stmts, name)
+	if _, _, err := s.check(src, nil); err != nil {
+		return err
+	}
+	var paths []string
+	for _, path := range snip.imports {
+		paths = append(paths, path)
+	}
+	if err := s.jit.Load(paths); err != nil {
+		return err
+	}
+	m, err := s.compile(src)
+	if err != nil {
+		return err
+	}
+	if err := s.jit.Add(m.Module); err != nil {
+		return err
+	}
+
+	// The snippet has been added to the session, so
+	// its declarations persist even if it panics.
+	s.n++
+	for pkgname, path := range snip.imports {
+		s.imports[pkgname] = path
+	}
+	s.decls = append(s.decls, snip.decls...)
+	for _, name := range snip.vars {
+		s.vars[name] = true
+	}
+	return s.jit.Run("main." + name)
+}
+
+// parse parses the input as either a sequence of declarations,
+// or a sequence of statements. Variables declared by the top-level
+// statements are made package-level variables so that they persist,
+// and are assigned their initial values by the statements.
+func (s *session) parse(input string) (*snippet, error) {
+	snip := &snippet{
+		imports: make(map[string]string),
+		calls:   make(map[int]bool),
+	}
+	fset := token.NewFileSet()
+	f, declErr := parser.ParseFile(fset, "", "package main\n"+input, 0)
+	if declErr == nil {
+		for _, decl := range f.Decls {
+			if err := s.parseDecl(fset, decl, snip); err != nil {
+				return nil, err
+			}
+		}
+		return snip, nil
+	}
+
+	f, err := parser.ParseFile(fset, "", "package main\nfunc _() {\n"+input+"\n}", 0)
+	if err != nil {
+		if startsWithDecl(input) {
+			return nil, declErr
+		}
+		return nil, err
+	}
+	for _, stmt := range f.Decls[0].(*ast.FuncDecl).Body.List {
+		switch stmt := stmt.(type) {
+		case *ast.AssignStmt:
+			if stmt.Tok == token.DEFINE {
+				s.hoist(fset, stmt.Lhs, stmt.Rhs, snip)
+				continue
+			}
+		case *ast.DeclStmt:
+			if err := s.parseDecl(fset, stmt.Decl, snip); err != nil {
+				return nil, err
+			}
+			continue
+		case *ast.ExprStmt:
+			if _, ok := stmt.X.(*ast.CallExpr); ok {
+				snip.calls[len(snip.stmts)] = true
+			} else {
+				snip.stmts = append(snip.stmts, fmtName+".Println("+nodeString(fset, stmt.X)+")")
+				continue
+			}
+		}
+		snip.stmts = append(snip.stmts, nodeString(fset, stmt))
+	}
+	return snip, nil
+}
+
+func (s *session) parseDecl(fset *token.FileSet, decl ast.Decl, snip *snippet) error {
+	gen, ok := decl.(*ast.GenDecl)
+	if !ok {
+		snip.decls = append(snip.decls, nodeString(fset, decl))
+		return nil
+	}
+	switch gen.Tok {
+	case token.IMPORT:
+		for _, spec := range gen.Specs {
+			spec := spec.(*ast.ImportSpec)
+			path, err := strconv.Unquote(spec.Path.Value)
+			if err != nil {
+				return err
+			}
+			var name string
+			if spec.Name != nil {
+				name = spec.Name.Name
+			} else {
+				pkg, err := s.ctx.Import(path, "", 0)
+				if err != nil {
+					return err
+				}
+				name = pkg.Name
+			}
+			snip.imports[name] = path
+		}
+	case token.VAR:
+		for _, spec := range gen.Specs {
+			spec := spec.(*ast.ValueSpec)
+			var lhs []ast.Expr
+			for _, name := range spec.Names {
+				snip.vars = append(snip.vars, name.Name)
+				lhs = append(lhs, name)
+			}
+			// Package-level initializers are never run;
+			// the values are assigned by the snippet.
+			snip.decls = append(snip.decls, "var "+nodeString(fset, spec))
+			if len(spec.Values) > 0 {
+				snip.stmts = append(snip.stmts, exprList(fset, lhs)+" = "+exprList(fset, spec.Values))
+			}
+		}
+	default:
+		snip.decls = append(snip.decls, nodeString(fset, decl))
+	}
+	return nil
+}
+
+// hoist declares the variables on the left hand side of a short
+// variable declaration as package-level variables, and assigns
+// them their values in the snippet. Variables already declared
+// are assigned, as with a short variable declaration.
+func (s *session) hoist(fset *token.FileSet, lhs, rhs []ast.Expr, snip *snippet) {
+	names := make([]string, len(lhs))
+	declared := false
+	for i, expr := range lhs {
+		name := expr.(*ast.Ident).Name
+		names[i] = "_"
+		if name != "_" && !s.vars[name] {
+			names[i] = name
+			snip.vars = append(snip.vars, name)
+			declared = true
+		}
+	}
+	if declared {
+		decl := "var " + strings.Join(names, ", ") + " = " + exprList(fset, rhs)
+		snip.decls = append(snip.decls, decl)
+	}
+	snip.stmts = append(snip.stmts, exprList(fset, lhs)+" = "+exprList(fset, rhs))
+}
+
+// source returns the source of a snippet with the specified
+// imports, declarations and statements, as well as those of
+// the session. The statements form the body of a function
+// with the specified name.
+func (s *session) source(imports map[string]string, decls, stmts []string, name string) string {
+	var body bytes.Buffer
+	for _, decl := range s.decls {
+		fmt.Fprintf(&body, "%s\n\n", decl)
+	}
+	for _, decl := range decls {
+		fmt.Fprintf(&body, "%s\n\n", decl)
+	}
+	body.WriteString(prelude)
+	if name != "" {
+		fmt.Fprintf(&body, "\nfunc %s() {\n\tdefer __replRecover()\n", name)
+		for _, stmt := range stmts {
+			fmt.Fprintf(&body, "\t%s\n", stmt)
+		}
+		body.WriteString("}\n")
+	}
+
+	// Import only the packages that are used,
+	// as unused imports are an error.
+	all := make(map[string]string)
+	for name, path := range s.imports {
+		all[name] = path
+	}
+	for name, path := range imports {
+		all[name] = path
+	}
+	used := usedNames(body.String())
+	var specs []string
+	for name, path := range all {
+		if used[name] {
+			specs = append(specs, fmt.Sprintf("\t%s %q\n", name, path))
+		}
+	}
+	sort.Strings(specs)
+
+	var src bytes.Buffer
+	src.WriteString("package main\n\nimport (\n")
+	fmt.Fprintf(&src, "\t%s %q\n", fmtName, "fmt")
+	for _, spec := range specs {
+		src.WriteString(spec)
+	}
+	src.WriteString(")\n\n")
+	src.Write(body.Bytes())
+	return src.String()
+}
+
+// check type-checks the snippet source.
+func (s *session) check(src string, info *types.Info) (*token.FileSet, *ast.File, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	conf := types.Config{Import: llgoimporter.NewImporter(s.ctx).Import}
+	if _, err := conf.Check("main", fset, []*ast.File{f}, info); err != nil {
+		return nil, nil, err
+	}
+	return fset, f, nil
+}
+
+// compile compiles the snippet source into a new module.
+func (s *session) compile(src string) (*llgo.Module, error) {
+	filename := filepath.Join(s.dir, fmt.Sprintf("repl%d.go", s.n))
+	if err := ioutil.WriteFile(filename, []byte(src), 0644); err != nil {
+		return nil, err
+	}
+	compiler, err := llgo.NewCompiler(s.opts)
+	if err != nil {
+		return nil, err
+	}
+	return compiler.Compile([]string{filename}, "main")
+}
+
+// usedNames returns the identifiers used as the
+// operand of a selector expression in the source.
+func usedNames(body string) map[string]bool {
+	used := make(map[string]bool)
+	f, err := parser.ParseFile(token.NewFileSet(), "", "package main\n"+body, 0)
+	if err != nil {
+		return used
+	}
+	ast.Inspect(f, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if ident, ok := sel.X.(*ast.Ident); ok {
+				used[ident.Name] = true
+			}
+		}
+		return true
+	})
+	return used
+}
+
+func findFunc(f *ast.File, name string) *ast.FuncDecl {
+	for _, decl := range f.Decls {
+		if decl, ok := decl.(*ast.FuncDecl); ok && decl.Name.Name == name {
+			return decl
+		}
+	}
+	return nil
+}
+
+// startsWithDecl reports whether the input begins
+// with a keyword that introduces a declaration.
+func startsWithDecl(input string) bool {
+	fields := strings.Fields(input)
+	if len(fields) == 0 {
+		return false
+	}
+	switch fields[0] {
+	case "import", "func", "type", "const", "var":
+		return true
+	}
+	return false
+}
+
+func nodeString(fset *token.FileSet, node interface{}) string {
+	var buf bytes.Buffer
+	printer.Fprint(&buf, fset, node)
+	return buf.String()
+}
+
+func exprList(fset *token.FileSet, exprs []ast.Expr) string {
+	strs := make([]string, len(exprs))
+	for i, expr := range exprs {
+		strs[i] = nodeString(fset, expr)
+	}
+	return strings.Join(strs, ", ")
+}
@@ -0,0 +1,159 @@
+// Copyright 2014 The llgo Authors.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/axw/gollvm/llvm"
+	"github.com/axw/llgo"
+	llgobuild "github.com/axw/llgo/build"
+)
+
+func init() {
+	llvm.InitializeAllTargets()
+	llvm.InitializeAllTargetMCs()
+	llvm.InitializeAllTargetInfos()
+}
+
+func TestDepth(t *testing.T) {
+	tests := []struct {
+		src   string
+		depth int
+	}{
+		{"x := 1", 0},
+		{"func f() {", 1},
+		{"f(a[", 2},
+		{"f(a[1])", 0},
+		{`s := "{"`, 0},
+	}
+	for _, test := range tests {
+		if d := depth(test.src); d != test.depth {
+			t.Errorf("%q: got depth %d, expected %d", test.src, d, test.depth)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	s := &session{vars: map[string]bool{"x": true}}
+	snip, err := s.parse("x, y := 1, 2\nvar z = 3\nf()\nx + y")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"var _, y = 1, 2", "var z = 3"}; !reflect.DeepEqual(snip.decls, expected) {
+		t.Errorf("got decls %q, expected %q", snip.decls, expected)
+	}
+	if expected := []string{"y", "z"}; !reflect.DeepEqual(snip.vars, expected) {
+		t.Errorf("got vars %q, expected %q", snip.vars, expected)
+	}
+	expected := []string{"x, y = 1, 2", "z = 3", "f()", fmtName + ".Println(x + y)"}
+	if !reflect.DeepEqual(snip.stmts, expected) {
+		t.Errorf("got stmts %q, expected %q", snip.stmts, expected)
+	}
+	if !reflect.DeepEqual(snip.calls, map[int]bool{2: true}) {
+		t.Errorf("got calls %v", snip.calls)
+	}
+
+	snip, err = s.parse(`import str "strings"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(snip.imports, map[string]string{"str": "strings"}) {
+		t.Errorf("got imports %v", snip.imports)
+	}
+}
+
+func TestSourceImportsUsedPackages(t *testing.T) {
+	s := &session{imports: map[string]string{"strings": "strings", "os": "os"}}
+	src := s.source(nil, nil, []string{`strings.ToUpper("x")`}, "__repl1")
+	if !strings.Contains(src, `strings "strings"`) {
+		t.Errorf("strings is not imported:\n%s", src)
+	}
+	if strings.Contains(src, `"os"`) {
+		t.Errorf("os is imported, but not used:\n%s", src)
+	}
+}
+
+// captureStdout returns what is written to the standard
+// output file descriptor, including by the JIT-compiled
+// runtime, while f runs.
+func captureStdout(t *testing.T, f func()) string {
+	tmp, err := ioutil.TempFile("", "llgo-repl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	saved, err := syscall.Dup(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer syscall.Close(saved)
+	if err := syscall.Dup2(int(tmp.Fd()), 1); err != nil {
+		t.Fatal(err)
+	}
+	f()
+	syscall.Dup2(saved, 1)
+	data, err := ioutil.ReadFile(tmp.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// TestEval evaluates a sequence of inputs in a session, and
+// checks what they print. It requires the runtime and the
+// packages used to have been installed with llgo-build.
+func TestEval(t *testing.T) {
+	root := llgobuild.PkgRoot(defaultTriple(), false, false)
+	for _, pkg := range []string{"runtime", "fmt", "strings"} {
+		if _, err := os.Stat(filepath.Join(root, pkg+".bc")); err != nil {
+			t.Skipf("%s has not been installed with llgo-build", pkg)
+		}
+	}
+	ctx, err := llgobuild.ContextFromTriple(defaultTriple())
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "llgo-repl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s, err := newSession(ctx, llgo.CompilerOptions{TargetTriple: defaultTriple()}, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.dispose()
+
+	inputs := []string{
+		"x := 40",
+		"x += 2",
+		"x",
+		`import "strings"`,
+		`strings.ToUpper("go")`,
+		"func double(n int) int { return n * 2 }",
+		"double(x)",
+		`panic("boom")`,
+		"x",
+	}
+	output := captureStdout(t, func() {
+		for _, input := range inputs {
+			if err := s.eval(input); err != nil {
+				t.Errorf("%q: %v", input, err)
+			}
+		}
+	})
+	expected := "42\nGO\n84\npanic: boom\n42\n"
+	if output != expected {
+		t.Errorf("got output %q, expected %q", output, expected)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"go/token"
 	"log"
 	"runtime"
+	"sort"
 	"strings"
 
 	"github.com/axw/gollvm/llvm"
@@ -58,9 +59,42 @@ type CompilerOptions struct {
 	// Logger is a logger used for tracing compilation.
 	Logger *log.Logger
 
-	// OrderedCompilation attempts to do some sorting to compile
-	// functions in a deterministic order
+	// OrderedCompilation is deprecated, and has no effect:
+	// functions are always compiled in a deterministic order.
 	OrderedCompilation bool
+
+	// RaceDetector decides whether memory accesses and
+	// synchronisation are instrumented for ThreadSanitizer.
+	RaceDetector bool
+
+	// CoverMode, if non-empty, causes the blocks of the package's
+	// non-test files to be instrumented with coverage counters, as
+	// by "go tool cover". The mode must be "set", "count" or "atomic".
+	CoverMode string
+
+	// BuildMode specifies how a "main" package is to be linked:
+	// "exe" (or the empty string) for an executable, or either
+	// "c-archive" or "c-shared" for a library that may be called
+	// from C. Libraries have no "main" function; instead, the
+	// function that C calls in place of each function exported
+	// with cgo (see build.CgoExportSymbol) initialises the
+	// runtime on the first call, before calling the Go function.
+	BuildMode string
+
+	// ExportFile, if non-empty, is the file to which the package's
+	// export data is written, in place of its file in GOPATH.
+	ExportFile string
+
+	// ImportFiles maps import paths to the files from which the
+	// export data of those packages is read, in place of their
+	// files in GOPATH.
+	ImportFiles map[string]string
+
+	// SoftFloat causes floating point arithmetic, comparisons
+	// and conversions to be lowered to calls to the runtime's
+	// software floating point functions, for targets without
+	// a floating point unit.
+	SoftFloat bool
 }
 
 type Compiler struct {
@@ -71,6 +105,14 @@ type Compiler struct {
 
 func NewCompiler(opts CompilerOptions) (*Compiler, error) {
 	compiler := &Compiler{opts: opts}
+	if !validCoverMode(opts.CoverMode) {
+		return nil, fmt.Errorf("invalid cover mode %q", opts.CoverMode)
+	}
+	switch opts.BuildMode {
+	case "", "exe", "c-archive", "c-shared":
+	default:
+		return nil, fmt.Errorf("invalid build mode %q", opts.BuildMode)
+	}
 	if strings.ToLower(compiler.opts.TargetTriple) == "pnacl" {
 		compiler.opts.TargetTriple = PNaClTriple
 		compiler.pnacl = true
@@ -119,6 +161,10 @@ type compiler struct {
 	pnacl bool
 
 	debug debugInfo
+
+	// cover records coverage instrumentation,
+	// if CoverMode is non-empty.
+	cover *coverage
 }
 
 func (c *compiler) logf(format string, v ...interface{}) {
@@ -132,10 +178,15 @@ func (compiler *compiler) compile(filenames []string, importpath string) (m *Mod
 	if err != nil {
 		return nil, err
 	}
+	if compiler.RaceDetector {
+		buildctx.BuildTags = append(buildctx.BuildTags, "race")
+	}
+	importer := llgoimporter.NewImporter(buildctx)
+	importer.Files = compiler.ImportFiles
 	impcfg := &loader.Config{
 		Fset: token.NewFileSet(),
 		TypeChecker: types.Config{
-			Import: llgoimporter.NewImporter(buildctx).Import,
+			Import: importer.Import,
 			Sizes:  compiler.llvmtypes,
 		},
 		Build: &buildctx.Context,
@@ -152,6 +203,20 @@ func (compiler *compiler) compile(filenames []string, importpath string) (m *Mod
 	if pkgname := astFiles[0].Name.String(); importpath == "" || pkgname == "main" {
 		importpath = pkgname
 	}
+	if compiler.CoverMode != "" && importpath != "runtime" {
+		compiler.cover = newCoverage(compiler.CoverMode, impcfg.Fset, importpath)
+		// As with "go test -cover", test files
+		// are not instrumented.
+		for _, file := range astFiles {
+			filename := impcfg.Fset.Position(file.Pos()).Filename
+			if strings.HasSuffix(filename, "_test.go") {
+				continue
+			}
+			if err := compiler.cover.annotate(file); err != nil {
+				return nil, err
+			}
+		}
+	}
 	impcfg.CreateFromFiles(importpath, astFiles...)
 	// Create a "runtime" package too, so we can reference
 	// its types and functions in the compiler and generated
@@ -167,7 +232,14 @@ func (compiler *compiler) compile(filenames []string, importpath string) (m *Mod
 	if err != nil {
 		return nil, err
 	}
-	program := ssa.Create(iprog, 0)
+	compiler.fileset = impcfg.Fset
+	// In debug mode, the SSA builder records the variables
+	// to which values correspond, with DebugRef instructions.
+	var mode ssa.BuilderMode
+	if compiler.GenerateDebug {
+		mode |= ssa.GlobalDebug
+	}
+	program := ssa.Create(iprog, mode)
 	var mainPkginfo, runtimePkginfo *loader.PackageInfo
 	if pkgs := iprog.InitialPackages(); len(pkgs) == 1 {
 		mainPkginfo, runtimePkginfo = pkgs[0], pkgs[0]
@@ -214,16 +286,24 @@ func (compiler *compiler) compile(filenames []string, importpath string) (m *Mod
 	compiler.debug.module = compiler.module.Module
 	compiler.debug.Fset = impcfg.Fset
 	compiler.debug.Sizes = compiler.llvmtypes
+	compiler.debug.setScopes(mainPkginfo.Scopes)
 
 	mainPkg.Build()
+	stringInits, stringVars := prepareStringInits(mainPkg)
 	unit.translatePackage(mainPkg)
+	unit.applyStringInits(stringInits)
+	unit.exportStringVars(stringVars)
+	if compiler.cover != nil {
+		unit.exportCoverage(importpath)
+	}
 	compiler.processAnnotations(unit, mainPkginfo)
 	if runtimePkginfo != mainPkginfo {
 		compiler.processAnnotations(unit, runtimePkginfo)
 	}
+	compiler.defineInlineFuncs()
 
 	// Finalise debugging.
-	for _, cu := range compiler.debug.cu {
+	for _, cu := range compiler.debug.compileUnits() {
 		compiler.module.AddNamedMetadataOperand(
 			"llvm.dbg.cu",
 			compiler.debug.MDNode(cu),
@@ -232,27 +312,46 @@ func (compiler *compiler) compile(filenames []string, importpath string) (m *Mod
 
 	// Export runtime type information.
 	var exportedTypes []types.Type
-	for _, m := range mainPkg.Members {
+	var exportedNames []string
+	for name, m := range mainPkg.Members {
 		if t, ok := m.(*ssa.Type); ok && ast.IsExported(t.Name()) {
-			exportedTypes = append(exportedTypes, t.Type())
+			exportedNames = append(exportedNames, name)
 		}
 	}
+	sort.Strings(exportedNames)
+	for _, name := range exportedNames {
+		exportedTypes = append(exportedTypes, mainPkg.Members[name].Type())
+	}
 	compiler.exportRuntimeTypes(exportedTypes, importpath == "runtime")
+	compiler.createTypelinks()
 
-	if importpath == "main" {
+	compiler.createCgoCallbacks(unit, mainPkginfo, importpath)
+	if importpath == "main" && !compiler.isLibrary() {
 		// Wrap "main.main" in a call to runtime.main.
 		if err = compiler.createMainFunction(); err != nil {
 			return nil, fmt.Errorf("failed to create main.main: %v", err)
 		}
 	} else {
-		if err := llgoimporter.Export(buildctx, mainPkg.Object); err != nil {
+		if compiler.ExportFile != "" {
+			err = llgoimporter.ExportFile(compiler.ExportFile, mainPkg.Object)
+		} else {
+			err = llgoimporter.Export(buildctx, mainPkg.Object)
+		}
+		if err != nil {
 			return nil, fmt.Errorf("failed to export package data: %v", err)
 		}
 	}
 
+	compiler.alignVectorAccesses()
 	return compiler.module, nil
 }
 
+// isLibrary reports whether a "main" package is
+// being compiled for a C archive or shared library.
+func (c *compiler) isLibrary() bool {
+	return c.BuildMode == "c-archive" || c.BuildMode == "c-shared"
+}
+
 func (c *compiler) createMainFunction() error {
 	// In a PNaCl program (plugin), there should not be a "main.main";
 	// instead, we expect a "main.CreateModule" function.
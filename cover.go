@@ -0,0 +1,627 @@
+// Copyright 2014 The llgo Authors.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package llgo
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/scanner"
+	"go/token"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"strconv"
+
+	"code.google.com/p/go.tools/go/exact"
+	"code.google.com/p/go.tools/go/ssa"
+	"code.google.com/p/go.tools/go/types"
+	"github.com/axw/gollvm/llvm"
+	llgobuild "github.com/axw/llgo/build"
+)
+
+// validCoverMode reports whether mode is a valid CoverMode.
+func validCoverMode(mode string) bool {
+	switch mode {
+	case "", "set", "count", "atomic":
+		return true
+	}
+	return false
+}
+
+// coverAtomicPackage is the name by which the files of a package
+// instrumented in atomic mode import sync/atomic.
+const coverAtomicPackage = "_llgo_cover_atomic"
+
+// coverage records the blocks of source instrumented with
+// coverage counters, in the order of the files of the package.
+//
+// Files are instrumented as "go tool cover" rewrites them: the
+// statements of each block are split into the same basic blocks,
+// and a statement incrementing the block's counter is inserted
+// at the start of each. Rather than rewriting the source text,
+// the counters are inserted into the syntax tree before type
+// checking. The counters of each file are held in an array
+// variable declared in the file.
+//
+// The counters are not inserted into the SSA form: its basic
+// blocks are split by short-circuit operators and calls that may
+// panic, and have no end positions, so they could not be mapped
+// onto the blocks that "go tool cover -html" and "-func" expect,
+// and profiles would not merge with gc's.
+type coverage struct {
+	mode  string
+	fset  *token.FileSet
+	files []*coverFile
+
+	// atomicPkg is the qualifier for the sync/atomic
+	// functions, which is empty when sync/atomic is
+	// itself instrumented.
+	atomicPkg string
+}
+
+type coverFile struct {
+	name    string
+	varname string
+	blocks  []coverBlock
+}
+
+type coverBlock struct {
+	start, end token.Position
+	stmts      int
+}
+
+func newCoverage(mode string, fset *token.FileSet, importpath string) *coverage {
+	c := &coverage{mode: mode, fset: fset, atomicPkg: coverAtomicPackage}
+	if importpath == "sync/atomic" {
+		c.atomicPkg = ""
+	}
+	return c
+}
+
+// annotate inserts coverage counters into the file, and
+// declares the variable holding them.
+func (c *coverage) annotate(file *ast.File) error {
+	tokfile := c.fset.File(file.Pos())
+	content, err := ioutil.ReadFile(tokfile.Name())
+	if err != nil {
+		return err
+	}
+	f := &coverFile{
+		name:    tokfile.Name(),
+		varname: fmt.Sprintf("_llgo_cover_%d", len(c.files)),
+	}
+	c.files = append(c.files, f)
+	ast.Walk(&coverVisitor{c, f, tokfile, content}, file)
+
+	// var _llgo_cover_N [len(blocks)]uint32
+	decl := &ast.GenDecl{
+		Tok: token.VAR,
+		Specs: []ast.Spec{&ast.ValueSpec{
+			Names: []*ast.Ident{ast.NewIdent(f.varname)},
+			Type: &ast.ArrayType{
+				Len: &ast.BasicLit{Kind: token.INT, Value: strconv.Itoa(len(f.blocks))},
+				Elt: ast.NewIdent("uint32"),
+			},
+		}},
+	}
+	file.Decls = append(file.Decls, decl)
+	if c.mode == "atomic" && c.atomicPkg != "" {
+		// import _llgo_cover_atomic "sync/atomic"
+		// var _ = _llgo_cover_atomic.AddUint32
+		spec := &ast.ImportSpec{
+			Name: ast.NewIdent(c.atomicPkg),
+			Path: &ast.BasicLit{Kind: token.STRING, Value: strconv.Quote("sync/atomic")},
+		}
+		imports := &ast.GenDecl{Tok: token.IMPORT, Specs: []ast.Spec{spec}}
+		file.Decls = append([]ast.Decl{imports}, file.Decls...)
+		file.Imports = append(file.Imports, spec)
+		use := &ast.GenDecl{
+			Tok: token.VAR,
+			Specs: []ast.Spec{&ast.ValueSpec{
+				Names: []*ast.Ident{ast.NewIdent("_")},
+				Values: []ast.Expr{&ast.SelectorExpr{
+					X:   ast.NewIdent(c.atomicPkg),
+					Sel: ast.NewIdent("AddUint32"),
+				}},
+			}},
+		}
+		file.Decls = append(file.Decls, use)
+	}
+	return nil
+}
+
+// coverVisitor inserts counters into the blocks
+// of a file; it is cmd/cover's File.
+type coverVisitor struct {
+	*coverage
+	file    *coverFile
+	tokfile *token.File
+	content []byte
+}
+
+func (v *coverVisitor) Visit(node ast.Node) ast.Visitor {
+	switch n := node.(type) {
+	case *ast.BlockStmt:
+		// The bodies of switch and select statements are lists of
+		// clauses; each clause is counted, not the block itself.
+		if len(n.List) > 0 {
+			switch n.List[0].(type) {
+			case *ast.CaseClause:
+				for _, s := range n.List {
+					clause := s.(*ast.CaseClause)
+					clause.Body = v.addCounters(clause.Colon+1, clause.Colon+1, clause.End(), clause.Body, false)
+				}
+				return v
+			case *ast.CommClause:
+				for _, s := range n.List {
+					clause := s.(*ast.CommClause)
+					clause.Body = v.addCounters(clause.Colon+1, clause.Colon+1, clause.End(), clause.Body, false)
+				}
+				return v
+			}
+		}
+		// +1 to step past the closing brace.
+		n.List = v.addCounters(n.Lbrace, n.Lbrace+1, n.Rbrace+1, n.List, true)
+	case *ast.IfStmt:
+		if n.Init != nil {
+			ast.Walk(v, n.Init)
+		}
+		ast.Walk(v, n.Cond)
+		ast.Walk(v, n.Body)
+		if n.Else == nil {
+			return nil
+		}
+		// An "else if" is moved into a hidden block, so that
+		// there is somewhere to count the "if". Either way,
+		// the else block starts after the "else".
+		offset := v.findText(n.Body.End(), "else")
+		if offset < 0 {
+			panic("lost else")
+		}
+		pos := v.tokfile.Pos(offset + len("else"))
+		switch s := n.Else.(type) {
+		case *ast.IfStmt:
+			n.Else = &ast.BlockStmt{
+				Lbrace: pos,
+				List:   []ast.Stmt{s},
+				Rbrace: s.End(),
+			}
+		case *ast.BlockStmt:
+			s.Lbrace = pos
+		}
+		ast.Walk(v, n.Else)
+		return nil
+	case *ast.SelectStmt:
+		if n.Body == nil || len(n.Body.List) == 0 {
+			return nil
+		}
+	case *ast.SwitchStmt:
+		if n.Body == nil || len(n.Body.List) == 0 {
+			if n.Init != nil {
+				ast.Walk(v, n.Init)
+			}
+			if n.Tag != nil {
+				ast.Walk(v, n.Tag)
+			}
+			return nil
+		}
+	case *ast.TypeSwitchStmt:
+		if n.Body == nil || len(n.Body.List) == 0 {
+			if n.Init != nil {
+				ast.Walk(v, n.Init)
+			}
+			ast.Walk(v, n.Assign)
+			return nil
+		}
+	case *ast.FuncDecl:
+		// Functions with blank names can't be called, and
+		// those without bodies have nothing to count. When
+		// sync/atomic is instrumented, the functions that
+		// increment the counters are not.
+		if n.Name.Name == "_" || n.Body == nil {
+			return nil
+		}
+		if v.mode == "atomic" && v.atomicPkg == "" {
+			if name := n.Name.Name; name == "AddUint32" || name == "StoreUint32" {
+				return nil
+			}
+		}
+		ast.Walk(v, n.Body)
+		return nil
+	}
+	return v
+}
+
+// addCounters splits the statements of a block, spanning pos
+// to blockEnd, into basic blocks, and returns the statements
+// with a counter inserted at the start of each; the first is
+// inserted at insertPos. If extendToClosingBrace is set, the
+// last basic block extends to the end of the block.
+func (v *coverVisitor) addCounters(pos, insertPos, blockEnd token.Pos, list []ast.Stmt, extendToClosingBrace bool) []ast.Stmt {
+	// An empty block is counted too; this can't be done
+	// below, which would count an empty statement list
+	// following a return statement, for example.
+	if len(list) == 0 {
+		r := v.codeRanges(insertPos, blockEnd)[0]
+		return []ast.Stmt{v.newCounter(r.pos, r.end, 0)}
+	}
+	list = append([]ast.Stmt(nil), list...)
+	var newList []ast.Stmt
+	for {
+		// The first statement that affects the flow of
+		// control ends the basic block.
+		var last int
+		end := blockEnd
+		for last = 0; last < len(list); last++ {
+			stmt := list[last]
+			end = statementBoundary(stmt)
+			if endsBasicSourceBlock(stmt) {
+				// A labeled statement may be the target of a goto,
+				// and so start a basic block. The label is split
+				// from its statement, unless that is a control
+				// statement, ending the previous block before it:
+				//	foo: ; COUNTER; stmt
+				if label, ok := stmt.(*ast.LabeledStmt); ok && !isControl(label.Stmt) {
+					newLabel := *label
+					newLabel.Stmt = &ast.EmptyStmt{Semicolon: label.Stmt.Pos()}
+					end = label.Pos()
+					list[last] = &newLabel
+					list = append(list, nil)
+					copy(list[last+1:], list[last:])
+					list[last+1] = label.Stmt
+				}
+				last++
+				extendToClosingBrace = false
+				break
+			}
+		}
+		if extendToClosingBrace {
+			end = blockEnd
+		}
+		stmts := list[:last]
+		// There is no source to cover if blocks abut. Only
+		// the ranges of the block that contain code are
+		// counted, each with its own counter, inserted
+		// before the statement at which the range starts.
+		if pos != end {
+			ranges := mergeRangesWithinStatements(v.codeRanges(pos, end), stmts)
+			for i, r := range ranges {
+				counter := v.newCounter(r.pos, r.end, last)
+				if i > 0 {
+					for len(stmts) > 0 && stmts[0].Pos() < r.pos {
+						newList = append(newList, stmts[0])
+						stmts = stmts[1:]
+					}
+				}
+				newList = append(newList, counter)
+			}
+		}
+		newList = append(newList, stmts...)
+		list = list[last:]
+		if len(list) == 0 {
+			break
+		}
+		pos = list[0].Pos()
+		insertPos = pos
+	}
+	return newList
+}
+
+// newCounter records a block of source, and returns
+// the statement that increments the block's counter.
+func (v *coverVisitor) newCounter(start, end token.Pos, numStmt int) ast.Stmt {
+	index := len(v.file.blocks)
+	v.file.blocks = append(v.file.blocks, coverBlock{
+		start: v.fset.Position(start),
+		end:   v.fset.Position(end),
+		stmts: numStmt,
+	})
+	counter := &ast.IndexExpr{
+		X:     ast.NewIdent(v.file.varname),
+		Index: &ast.BasicLit{Kind: token.INT, Value: strconv.Itoa(index)},
+	}
+	switch v.mode {
+	case "set":
+		return &ast.AssignStmt{
+			Lhs: []ast.Expr{counter},
+			Tok: token.ASSIGN,
+			Rhs: []ast.Expr{&ast.BasicLit{Kind: token.INT, Value: "1"}},
+		}
+	case "count":
+		return &ast.IncDecStmt{X: counter, Tok: token.INC}
+	}
+	var fn ast.Expr = ast.NewIdent("AddUint32")
+	if v.atomicPkg != "" {
+		fn = &ast.SelectorExpr{X: ast.NewIdent(v.atomicPkg), Sel: fn.(*ast.Ident)}
+	}
+	return &ast.ExprStmt{X: &ast.CallExpr{
+		Fun: fn,
+		Args: []ast.Expr{
+			&ast.UnaryExpr{Op: token.AND, X: counter},
+			&ast.BasicLit{Kind: token.INT, Value: "1"},
+		},
+	}}
+}
+
+// coverRange is a range of source containing code.
+type coverRange struct {
+	pos, end token.Pos
+}
+
+// codeRanges returns the ranges of the source from start to
+// end that contain code, excluding blank lines, lines holding
+// only comments, and lines holding only braces. If there is no
+// code, a single empty range at start is returned.
+func (v *coverVisitor) codeRanges(start, end token.Pos) []coverRange {
+	startOffset := v.tokfile.Offset(start)
+	src := v.content[startOffset:v.tokfile.Offset(end)]
+
+	// lineStart returns the offset in src of the given line.
+	lineStart := func(line int) int {
+		offset := 0
+		for ; line > 1; line-- {
+			offset += bytes.IndexByte(src[offset:], '\n') + 1
+		}
+		return offset
+	}
+
+	// The source is scanned as a file of its own,
+	// so lines are numbered from the start of src.
+	scanfile := token.NewFileSet().AddFile("", -1, len(src))
+	var s scanner.Scanner
+	s.Init(scanfile, src, nil, 0)
+
+	// A range is ended by a gap of lines with no code;
+	// the next range starts at the next token.
+	var ranges []coverRange
+	var codeStart token.Pos
+	prevEndLine := 0
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		if tok == token.LBRACE || tok == token.RBRACE {
+			continue
+		}
+		if tok == token.SEMICOLON && lit == "\n" {
+			continue
+		}
+		startLine := scanfile.Position(pos).Line
+		endLine := startLine
+		if tok == token.STRING {
+			// Only raw strings span multiple lines.
+			endLine = scanfile.Position(pos + token.Pos(len(lit))).Line
+		}
+		if prevEndLine == 0 {
+			codeStart = v.tokfile.Pos(startOffset + scanfile.Offset(pos))
+		} else if startLine > prevEndLine+1 {
+			codeEnd := v.tokfile.Pos(startOffset + lineStart(prevEndLine+1))
+			ranges = append(ranges, coverRange{codeStart, codeEnd})
+			codeStart = v.tokfile.Pos(startOffset + scanfile.Offset(pos))
+		}
+		if endLine > prevEndLine {
+			prevEndLine = endLine
+		}
+	}
+	if prevEndLine > 0 {
+		// Lines without code following the last
+		// (such as a lone "}") are excluded.
+		if prevEndLine < bytes.Count(src, []byte("\n"))+1 {
+			codeEnd := v.tokfile.Pos(startOffset + lineStart(prevEndLine+1))
+			ranges = append(ranges, coverRange{codeStart, codeEnd})
+		} else {
+			ranges = append(ranges, coverRange{codeStart, end})
+		}
+	}
+	if len(ranges) == 0 {
+		return []coverRange{{start, start}}
+	}
+	return ranges
+}
+
+// mergeRangesWithinStatements merges each range that starts
+// within one of the statements into the range before it, so
+// that counters are never inserted within a statement.
+func mergeRangesWithinStatements(ranges []coverRange, stmts []ast.Stmt) []coverRange {
+	if len(ranges) <= 1 {
+		return ranges
+	}
+	merged := []coverRange{ranges[0]}
+	for _, r := range ranges[1:] {
+		inside := false
+		for _, s := range stmts {
+			if s.Pos() < r.pos && r.pos < s.End() {
+				inside = true
+				break
+			}
+		}
+		if inside {
+			merged[len(merged)-1].end = r.end
+		} else {
+			merged = append(merged, r)
+		}
+	}
+	return merged
+}
+
+// findText returns the offset of the first occurrence of text
+// in the file's source after pos, skipping comments, or -1.
+func (v *coverVisitor) findText(pos token.Pos, text string) int {
+	b := []byte(text)
+	s := v.content
+	for i := v.tokfile.Offset(pos); i < len(s); {
+		switch {
+		case bytes.HasPrefix(s[i:], b):
+			return i
+		case bytes.HasPrefix(s[i:], []byte("//")):
+			for i < len(s) && s[i] != '\n' {
+				i++
+			}
+		case bytes.HasPrefix(s[i:], []byte("/*")):
+			end := bytes.Index(s[i+2:], []byte("*/"))
+			if end < 0 {
+				return -1
+			}
+			i += 2 + end + 2
+		default:
+			i++
+		}
+	}
+	return -1
+}
+
+// statementBoundary returns the position at which the basic
+// block ending with the statement s ends. Function literals
+// are excluded from blocks: a block containing one ends at
+// the start of the first's body.
+func statementBoundary(s ast.Stmt) token.Pos {
+	var heads []ast.Node
+	var body *ast.BlockStmt
+	switch s := s.(type) {
+	case *ast.BlockStmt:
+		// Blocks are treated as basic blocks,
+		// to avoid overlapping counters.
+		return s.Lbrace
+	case *ast.LabeledStmt:
+		return statementBoundary(s.Stmt)
+	case *ast.IfStmt:
+		heads, body = []ast.Node{s.Init, s.Cond}, s.Body
+	case *ast.ForStmt:
+		heads, body = []ast.Node{s.Init, s.Cond, s.Post}, s.Body
+	case *ast.RangeStmt:
+		heads, body = []ast.Node{s.X}, s.Body
+	case *ast.SwitchStmt:
+		heads, body = []ast.Node{s.Init, s.Tag}, s.Body
+	case *ast.SelectStmt:
+		body = s.Body
+	case *ast.TypeSwitchStmt:
+		heads, body = []ast.Node{s.Init}, s.Body
+	default:
+		if found, pos := hasFuncLiteral(s); found {
+			return pos
+		}
+		return s.End()
+	}
+	for _, n := range heads {
+		if found, pos := hasFuncLiteral(n); found {
+			return pos
+		}
+	}
+	return body.Lbrace
+}
+
+// endsBasicSourceBlock reports whether s changes the flow of
+// control, and so ends a basic block.
+func endsBasicSourceBlock(s ast.Stmt) bool {
+	switch s := s.(type) {
+	case *ast.BlockStmt, *ast.BranchStmt, *ast.ForStmt, *ast.IfStmt, *ast.LabeledStmt,
+		*ast.RangeStmt, *ast.SwitchStmt, *ast.SelectStmt, *ast.TypeSwitchStmt:
+		return true
+	case *ast.ExprStmt:
+		// Calls to panic change the flow. As in cmd/cover,
+		// panic is assumed to be the predeclared function.
+		if call, ok := s.X.(*ast.CallExpr); ok {
+			if ident, ok := call.Fun.(*ast.Ident); ok && ident.Name == "panic" && len(call.Args) == 1 {
+				return true
+			}
+		}
+	}
+	found, _ := hasFuncLiteral(s)
+	return found
+}
+
+// isControl reports whether s is a control statement,
+// which can't be separated from its label.
+func isControl(s ast.Stmt) bool {
+	switch s.(type) {
+	case *ast.ForStmt, *ast.RangeStmt, *ast.SwitchStmt, *ast.SelectStmt, *ast.TypeSwitchStmt:
+		return true
+	}
+	return false
+}
+
+// hasFuncLiteral reports whether n contains a function
+// literal, and if so, the position of the first's body.
+func hasFuncLiteral(n ast.Node) (bool, token.Pos) {
+	if n == nil {
+		return false, 0
+	}
+	var literal funcLitFinder
+	ast.Walk(&literal, n)
+	return literal != 0, token.Pos(literal)
+}
+
+// funcLitFinder records the position of the
+// body of the first function literal visited.
+type funcLitFinder token.Pos
+
+func (f *funcLitFinder) Visit(node ast.Node) ast.Visitor {
+	if *f != 0 {
+		return nil
+	}
+	if n, ok := node.(*ast.FuncLit); ok {
+		*f = funcLitFinder(n.Body.Lbrace)
+		return nil
+	}
+	return f
+}
+
+// exportCoverage defines the global variable named by
+// build.CoverSymbol, which describes the blocks and
+// counters of each instrumented file.
+func (u *unit) exportCoverage(importpath string) {
+	stringType := types.Typ[types.String]
+	uint32sType := types.NewSlice(types.Typ[types.Uint32])
+	uint16sType := types.NewSlice(types.Typ[types.Uint16])
+	fileType := types.NewStruct([]*types.Var{
+		types.NewField(0, nil, "Name", stringType, false),
+		types.NewField(0, nil, "Counters", uint32sType, false),
+		types.NewField(0, nil, "Pos", uint32sType, false),
+		types.NewField(0, nil, "NumStmt", uint16sType, false),
+	}, nil)
+	llfileType := u.types.ToLLVM(fileType)
+	lluint32sType := u.types.ToLLVM(uint32sType)
+	lluint16sType := u.types.ToLLVM(uint16sType)
+
+	files := make([]llvm.Value, len(u.cover.files))
+	for i, file := range u.cover.files {
+		nblocks := len(file.blocks)
+		pos := make([]llvm.Value, 0, nblocks*3)
+		numStmt := make([]llvm.Value, nblocks)
+		for j, block := range file.blocks {
+			cols := uint64(block.start.Column&0xFFFF) | uint64(block.end.Column&0xFFFF)<<16
+			pos = append(pos,
+				llvm.ConstInt(llvm.Int32Type(), uint64(block.start.Line), false),
+				llvm.ConstInt(llvm.Int32Type(), uint64(block.end.Line), false),
+				llvm.ConstInt(llvm.Int32Type(), cols, false),
+			)
+			numStmt[j] = llvm.ConstInt(llvm.Int16Type(), uint64(block.stmts), false)
+		}
+
+		counters := u.globals[u.pkg.Members[file.varname].(*ssa.Global)].LLVMValue()
+		countersSlice := llvm.ConstNull(lluint32sType)
+		countersPtr := llvm.ConstBitCast(counters, lluint32sType.StructElementTypes()[0])
+		countersLen := llvm.ConstInt(u.types.inttype, uint64(nblocks), false)
+		countersSlice = llvm.ConstInsertValue(countersSlice, countersPtr, []uint32{0})
+		countersSlice = llvm.ConstInsertValue(countersSlice, countersLen, []uint32{1})
+		countersSlice = llvm.ConstInsertValue(countersSlice, countersLen, []uint32{2})
+
+		// The profile refers to files by import path,
+		// as "go tool cover" does.
+		name := path.Join(importpath, filepath.Base(file.name))
+		f := llvm.ConstNull(llfileType)
+		f = llvm.ConstInsertValue(f, u.NewConstValue(exact.MakeString(name), stringType).LLVMValue(), []uint32{0})
+		f = llvm.ConstInsertValue(f, countersSlice, []uint32{1})
+		f = llvm.ConstInsertValue(f, u.types.makeSlice(pos, lluint32sType), []uint32{2})
+		f = llvm.ConstInsertValue(f, u.types.makeSlice(numStmt, lluint16sType), []uint32{3})
+		files[i] = f
+	}
+
+	slice := u.types.makeSlice(files, u.types.ToLLVM(types.NewSlice(fileType)))
+	global := llvm.AddGlobal(u.module.Module, slice.Type(), llgobuild.CoverSymbol(importpath))
+	global.SetInitializer(slice)
+}
@@ -6,7 +6,9 @@ package llgo
 
 import (
 	"debug/dwarf"
+	"go/ast"
 	"go/token"
+	"sort"
 
 	"code.google.com/p/go.tools/go/ssa"
 	"code.google.com/p/go.tools/go/types"
@@ -21,6 +23,10 @@ const (
 	tagArgVariable  dwarf.Tag = 0x101
 )
 
+// syntheticFile is the name of the compile unit to which
+// synthetic functions, such as wrappers, are attributed.
+const syntheticFile = "<autogenerated>"
+
 type debugInfo struct {
 	debug.DebugInfo
 	debug.TypeMap
@@ -28,6 +34,45 @@ type debugInfo struct {
 	blockUid     uint32
 	cu           map[*token.File]*debug.CompileUnitDescriptor
 	debugContext []debug.DebugDescriptor
+
+	// scopes maps the lexical scopes of the package to
+	// the syntax that introduces them, and nodeScopes is
+	// its inverse.
+	scopes     map[*types.Scope]ast.Node
+	nodeScopes map[ast.Node]*types.Scope
+
+	// fn holds the debug information of the
+	// function being defined, if any.
+	fn *functionDebugInfo
+}
+
+// functionDebugInfo holds the debug information
+// of the function being defined.
+type functionDebugInfo struct {
+	subprog   *debug.SubprogramDescriptor
+	synthetic bool
+
+	// scope is the scope of the function's signature
+	// and body, or nil if it has no syntax.
+	scope *types.Scope
+
+	// blocks holds the lexical blocks created so far
+	// for the scopes nested within scope.
+	blocks map[*types.Scope]*debug.BlockDescriptor
+
+	// vars holds the descriptors of the variables
+	// described by llvm.dbg.value calls.
+	vars map[types.Object]*debug.LocalVariableDescriptor
+}
+
+// setScopes records the lexical scopes of the package,
+// as recorded by the type checker.
+func (d *debugInfo) setScopes(scopes map[ast.Node]*types.Scope) {
+	d.nodeScopes = scopes
+	d.scopes = make(map[*types.Scope]ast.Node)
+	for node, scope := range scopes {
+		d.scopes[scope] = node
+	}
 }
 
 func (d *debugInfo) pushContext(dd debug.DebugDescriptor) {
@@ -47,29 +92,13 @@ func (d *debugInfo) context() debug.DebugDescriptor {
 	return d.debugContext[len(d.debugContext)-1]
 }
 
-func (d *debugInfo) pushBlockContext(pos token.Pos) {
-	uid := d.blockUid
-	d.blockUid++
-	file := d.Fset.File(pos)
-	d.pushContext(&debug.BlockDescriptor{
-		File:    &d.getCompileUnit(file).Path,
-		Line:    uint32(file.Line(pos)),
-		Context: d.context(),
-		Id:      uid,
-	})
-}
-
-func (d *debugInfo) popBlockContext() {
-	d.popContext()
-}
-
 func (d *debugInfo) getCompileUnit(file *token.File) *debug.CompileUnitDescriptor {
 	if d.cu == nil {
 		d.cu = make(map[*token.File]*debug.CompileUnitDescriptor)
 	}
 	cu := d.cu[file]
 	if cu == nil {
-		var path string
+		path := syntheticFile
 		if file != nil {
 			path = d.Fset.File(file.Pos(0)).Name()
 		}
@@ -84,29 +113,150 @@ func (d *debugInfo) getCompileUnit(file *token.File) *debug.CompileUnitDescripto
 	return cu
 }
 
-func (d *debugInfo) pushFunctionContext(fnptr llvm.Value, sig *types.Signature, pos token.Pos) {
+// compileUnits returns the compile units created so
+// far, sorted by path for a deterministic order.
+func (d *debugInfo) compileUnits() []*debug.CompileUnitDescriptor {
+	paths := make([]string, 0, len(d.cu))
+	cus := make(map[string]*debug.CompileUnitDescriptor)
+	for _, cu := range d.cu {
+		path := string(cu.Path)
+		paths = append(paths, path)
+		cus[path] = cu
+	}
+	sort.Strings(paths)
+	sorted := make([]*debug.CompileUnitDescriptor, len(paths))
+	for i, path := range paths {
+		sorted[i] = cus[path]
+	}
+	return sorted
+}
+
+// pushFunctionContext creates a subprogram for the function, and
+// pushes it onto the debug context. Synthetic functions, which
+// have no source, are attributed to a generated compile unit.
+func (d *debugInfo) pushFunctionContext(fnptr llvm.Value, f *ssa.Function) {
 	subprog := &debug.SubprogramDescriptor{
 		Name:        fnptr.Name(),
 		DisplayName: fnptr.Name(),
 		Function:    fnptr,
 	}
-	file := d.Fset.File(pos)
+	fn := &functionDebugInfo{
+		subprog:   subprog,
+		synthetic: f.Synthetic != "",
+		blocks:    make(map[*types.Scope]*debug.BlockDescriptor),
+		vars:      make(map[types.Object]*debug.LocalVariableDescriptor),
+	}
+	var file *token.File
+	if !fn.synthetic {
+		file = d.Fset.File(f.Pos())
+	}
 	cu := d.getCompileUnit(file)
-	subprog.File = file.Name()
+	subprog.File = string(cu.Path)
 	subprog.Context = &cu.Path
 	if file != nil {
-		subprog.Line = uint32(file.Line(pos))
-		subprog.ScopeLine = uint32(file.Line(pos)) // TODO(axw)
+		subprog.Line = uint32(file.Line(f.Pos()))
+		subprog.ScopeLine = subprog.Line
+		var ftyp *ast.FuncType
+		var body *ast.BlockStmt
+		switch syntax := f.Syntax().(type) {
+		case *ast.FuncDecl:
+			ftyp, body = syntax.Type, syntax.Body
+		case *ast.FuncLit:
+			ftyp, body = syntax.Type, syntax.Body
+		}
+		if body != nil {
+			subprog.ScopeLine = uint32(file.Line(body.Lbrace))
+		}
+		if ftyp != nil {
+			fn.scope = d.nodeScopes[ftyp]
+		}
+	} else {
+		subprog.Line = 1
+		subprog.ScopeLine = 1
 	}
-	sigType := d.TypeDebugDescriptor(sig).(*debug.CompositeTypeDescriptor)
+	sigType := d.TypeDebugDescriptor(f.Signature).(*debug.CompositeTypeDescriptor)
 	subroutineType := sigType.Members[0]
 	subprog.Type = subroutineType
 	cu.Subprograms = append(cu.Subprograms, subprog)
+	d.fn = fn
 	d.pushContext(subprog)
 }
 
 func (d *debugInfo) popFunctionContext() {
 	d.popContext()
+	d.fn = nil
+}
+
+// scopeAt returns the innermost lexical block of the function
+// being defined that contains pos, or the function's subprogram
+// if there is none.
+func (d *debugInfo) scopeAt(pos token.Pos) debug.DebugDescriptor {
+	fn := d.fn
+	if fn == nil || fn.scope == nil || !pos.IsValid() {
+		return d.context()
+	}
+	scope := fn.scope
+	for found := true; found; {
+		found = false
+		for i := 0; i < scope.NumChildren(); i++ {
+			child := scope.Child(i)
+			node := d.scopes[child]
+			if node == nil || node.Pos() > pos || pos >= node.End() {
+				continue
+			}
+			// Function literals are described
+			// by subprograms of their own.
+			if _, ok := node.(*ast.FuncType); ok {
+				continue
+			}
+			scope, found = child, true
+			break
+		}
+	}
+	return d.lexicalBlock(scope)
+}
+
+// lexicalBlock returns the lexical block for the specified scope,
+// which must be the function's scope or nested within it. Blocks
+// are created as they are first needed.
+func (d *debugInfo) lexicalBlock(scope *types.Scope) debug.DebugDescriptor {
+	fn := d.fn
+	if scope == fn.scope {
+		return fn.subprog
+	}
+	if block, ok := fn.blocks[scope]; ok {
+		return block
+	}
+	parent := d.lexicalBlock(scope.Parent())
+	pos := d.scopes[scope].Pos()
+	file := d.Fset.File(pos)
+	position := d.Fset.Position(pos)
+	block := &debug.BlockDescriptor{
+		File:    &d.getCompileUnit(file).Path,
+		Line:    uint32(position.Line),
+		Column:  uint32(position.Column),
+		Context: parent,
+		Id:      d.blockUid,
+	}
+	d.blockUid++
+	fn.blocks[scope] = block
+	return block
+}
+
+// varScope returns the lexical block in which the variable is
+// declared, or the function's subprogram if the variable is not
+// declared within a scope nested within the function's scope.
+func (d *debugInfo) varScope(obj types.Object) debug.DebugDescriptor {
+	fn := d.fn
+	if fn.scope == nil {
+		return fn.subprog
+	}
+	for s := obj.Parent(); s != nil; s = s.Parent() {
+		if s == fn.scope {
+			return d.lexicalBlock(obj.Parent())
+		}
+	}
+	return fn.subprog
 }
 
 // declare creates an llvm.dbg.declare call for the specified function
@@ -124,21 +274,59 @@ func (d *debugInfo) declare(b llvm.Builder, v ssa.Value, llv llvm.Value, paramIn
 		ld.File = &d.getCompileUnit(file).Path
 	}
 	ld.Type = d.TypeDebugDescriptor(deref(v.Type()))
-	ld.Context = d.context()
+	ld.Context = d.scopeAt(v.Pos())
 	b.InsertDeclare(d.module, llvm.MDNode([]llvm.Value{llv}), d.MDNode(ld))
 }
 
-// value creates an llvm.dbg.value call for the specified register value.
-func (d *debugInfo) value(b llvm.Builder, v ssa.Value, llv llvm.Value, paramIndex int) {
-	// TODO(axw)
+// value creates an llvm.dbg.value call for the specified variable,
+// recording that the register value llv holds its value from this
+// point. The variable is described once per function.
+func (d *debugInfo) value(b llvm.Builder, obj types.Object, llv llvm.Value, paramIndex int) {
+	ld := d.fn.vars[obj]
+	if ld == nil {
+		tag := tagAutoVariable
+		if paramIndex >= 0 {
+			tag = tagArgVariable
+		}
+		ld = debug.NewLocalVariableDescriptor(tag)
+		ld.Argument = uint32(paramIndex + 1)
+		ld.Name = obj.Name()
+		if file := d.Fset.File(obj.Pos()); file != nil {
+			ld.Line = uint32(file.Position(obj.Pos()).Line)
+			ld.File = &d.getCompileUnit(file).Path
+		}
+		ld.Type = d.TypeDebugDescriptor(obj.Type())
+		ld.Context = d.varScope(obj)
+		d.fn.vars[obj] = ld
+	}
+	mdvalue := llvm.MDNode([]llvm.Value{llv})
+	mdvar := d.MDNode(ld)
+	dbgvalue := d.module.NamedFunction("llvm.dbg.value")
+	if dbgvalue.IsNil() {
+		paramTypes := []llvm.Type{mdvalue.Type(), llvm.Int64Type(), mdvar.Type()}
+		fntype := llvm.FunctionType(llvm.VoidType(), paramTypes, false)
+		dbgvalue = llvm.AddFunction(d.module, "llvm.dbg.value", fntype)
+	}
+	offset := llvm.ConstInt(llvm.Int64Type(), 0, false)
+	b.CreateCall(dbgvalue, []llvm.Value{mdvalue, offset, mdvar}, "")
 }
 
+// setLocation sets the builder's debug location to the specified
+// position, in the innermost lexical block that contains it. All
+// locations in synthetic functions are at the first line of the
+// generated compile unit.
 func (d *debugInfo) setLocation(b llvm.Builder, pos token.Pos) {
-	position := d.Fset.Position(pos)
+	var line, column int
+	if d.fn != nil && d.fn.synthetic {
+		line = 1
+	} else {
+		position := d.Fset.Position(pos)
+		line, column = position.Line, position.Column
+	}
 	b.SetCurrentDebugLocation(llvm.MDNode([]llvm.Value{
-		llvm.ConstInt(llvm.Int32Type(), uint64(position.Line), true),
-		llvm.ConstInt(llvm.Int32Type(), uint64(position.Column), true),
-		d.MDNode(d.context()),
+		llvm.ConstInt(llvm.Int32Type(), uint64(line), true),
+		llvm.ConstInt(llvm.Int32Type(), uint64(column), true),
+		d.MDNode(d.scopeAt(pos)),
 		llvm.Value{},
 	}))
 }
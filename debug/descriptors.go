@@ -287,6 +287,10 @@ func NewParamDerivedType(base DebugDescriptor) *DerivedTypeDescriptor {
 	return &DerivedTypeDescriptor{tag: dwarf.TagFormalParameter, Base: base}
 }
 
+func NewTypedefDerivedType(base DebugDescriptor) *DerivedTypeDescriptor {
+	return &DerivedTypeDescriptor{tag: dwarf.TagTypedef, Base: base}
+}
+
 ///////////////////////////////////////////////////////////////////////////////
 // Subprograms.
 
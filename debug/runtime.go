@@ -0,0 +1,123 @@
+// Copyright 2014 The llgo Authors.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package debug
+
+import (
+	"code.google.com/p/go.tools/go/types"
+)
+
+// The types below mirror the layouts of the runtime's data
+// structures (see pkg/runtime), so that debuggers can look
+// inside maps, channels and interfaces. They are named with
+// a "runtime." prefix, which the gdb helper script relies on.
+
+var (
+	unsafePointer = types.Typ[types.UnsafePointer]
+
+	// runtimeRtype mirrors runtime.rtype, up to and including
+	// the string representation of the type.
+	runtimeRtype = runtimeStruct("rtype",
+		types.NewVar(0, nil, "size", types.Typ[types.Uintptr]),
+		types.NewVar(0, nil, "hash", types.Typ[types.Uint32]),
+		types.NewVar(0, nil, "_", types.Typ[types.Uint8]),
+		types.NewVar(0, nil, "align", types.Typ[types.Uint8]),
+		types.NewVar(0, nil, "fieldAlign", types.Typ[types.Uint8]),
+		types.NewVar(0, nil, "kind", types.Typ[types.Uint8]),
+		types.NewVar(0, nil, "alg", unsafePointer),
+		types.NewVar(0, nil, "gc", unsafePointer),
+		types.NewVar(0, nil, "string", types.NewPointer(types.Typ[types.String])),
+		types.NewVar(0, nil, "uncommonType", unsafePointer),
+		types.NewVar(0, nil, "ptrToThis", unsafePointer),
+	)
+
+	// runtimeEface mirrors the representation of interface{}.
+	runtimeEface = runtimeStruct("eface",
+		types.NewVar(0, nil, "_type", types.NewPointer(runtimeRtype)),
+		types.NewVar(0, nil, "data", unsafePointer),
+	)
+
+	// runtimeItab mirrors runtime.itab. The method
+	// table follows the struct in memory.
+	runtimeItab = runtimeStruct("itab",
+		types.NewVar(0, nil, "inter", types.NewPointer(runtimeRtype)),
+		types.NewVar(0, nil, "_type", types.NewPointer(runtimeRtype)),
+		types.NewVar(0, nil, "link", unsafePointer),
+		types.NewVar(0, nil, "bad", types.Typ[types.Int32]),
+		types.NewVar(0, nil, "unused", types.Typ[types.Int32]),
+		types.NewVar(0, nil, "fun", unsafePointer),
+	)
+
+	// runtimeIface mirrors the representation
+	// of interfaces with methods.
+	runtimeIface = runtimeStruct("iface",
+		types.NewVar(0, nil, "tab", types.NewPointer(runtimeItab)),
+		types.NewVar(0, nil, "data", unsafePointer),
+	)
+
+	// runtimeSudoG mirrors runtime.SudoG, an entry
+	// in the queue of goroutines waiting on a channel.
+	runtimeSudoG = runtimeStruct("SudoG")
+
+	// runtimeWaitQ mirrors runtime.WaitQ.
+	runtimeWaitQ = runtimeStruct("WaitQ",
+		types.NewVar(0, nil, "first", types.NewPointer(runtimeSudoG)),
+		types.NewVar(0, nil, "last", types.NewPointer(runtimeSudoG)),
+	)
+)
+
+func init() {
+	runtimeSudoG.SetUnderlying(types.NewStruct([]*types.Var{
+		types.NewVar(0, nil, "g", unsafePointer),
+		types.NewVar(0, nil, "selgen", types.Typ[types.Uint32]),
+		types.NewVar(0, nil, "link", types.NewPointer(runtimeSudoG)),
+		types.NewVar(0, nil, "releasetime", types.Typ[types.Int64]),
+		types.NewVar(0, nil, "elem", unsafePointer),
+	}, nil))
+}
+
+// runtimeStruct returns a named struct type with the specified
+// fields, named as the runtime type with the specified name.
+func runtimeStruct(name string, fields ...*types.Var) *types.Named {
+	obj := types.NewTypeName(0, nil, "runtime."+name, nil)
+	return types.NewNamed(obj, types.NewStruct(fields, nil), nil)
+}
+
+// runtimeHchan returns a type mirroring runtime.Hchan, for
+// channels with the specified element type. The buffer of
+// elements immediately follows the Hchan structure.
+func runtimeHchan(elem types.Type) *types.Named {
+	name := "hchan<" + types.TypeString(nil, elem) + ">"
+	return runtimeStruct(name,
+		types.NewVar(0, nil, "qcount", types.Typ[types.Uint]),
+		types.NewVar(0, nil, "dataqsiz", types.Typ[types.Uint]),
+		types.NewVar(0, nil, "elemsize", types.Typ[types.Uint16]),
+		types.NewVar(0, nil, "closed", types.Typ[types.Bool]),
+		types.NewVar(0, nil, "elemalign", types.Typ[types.Uint8]),
+		types.NewVar(0, nil, "sendx", types.Typ[types.Uint]),
+		types.NewVar(0, nil, "recvx", types.Typ[types.Uint]),
+		types.NewVar(0, nil, "recvq", runtimeWaitQ),
+		types.NewVar(0, nil, "sendq", runtimeWaitQ),
+		types.NewVar(0, nil, "lock", types.Typ[types.Uintptr]),
+		types.NewVar(0, nil, "buf", types.NewArray(elem, 0)),
+	)
+}
+
+// runtimeHmap returns a type mirroring the runtime's map
+// structure, for maps with the specified key and element
+// types. A map is a slice of pointers to entries, each of
+// which holds a key and its value after a reserved word.
+func runtimeHmap(key, elem types.Type) *types.Named {
+	kv := types.TypeString(nil, key) + "," + types.TypeString(nil, elem)
+	entry := runtimeStruct("mapentry<"+kv+">",
+		types.NewVar(0, nil, "_", types.Typ[types.Uintptr]),
+		types.NewVar(0, nil, "key", key),
+		types.NewVar(0, nil, "value", elem),
+	)
+	return runtimeStruct("hmap<"+kv+">",
+		types.NewVar(0, nil, "entries", types.NewPointer(types.NewPointer(entry))),
+		types.NewVar(0, nil, "count", types.Typ[types.Int]),
+		types.NewVar(0, nil, "cap", types.Typ[types.Int]),
+	)
+}
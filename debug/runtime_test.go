@@ -0,0 +1,177 @@
+// Copyright 2014 The llgo Authors.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package debug
+
+import (
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"testing"
+
+	"code.google.com/p/go.tools/go/types"
+)
+
+// runtimeStructs parses the runtime package for the host,
+// and returns its struct type declarations by name.
+func runtimeStructs(t *testing.T) map[string]*ast.StructType {
+	pkg, err := build.Default.ImportDir(filepath.Join("..", "pkg", "runtime"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	structs := make(map[string]*ast.StructType)
+	typeSpecs := make(map[string]ast.Expr)
+	fset := token.NewFileSet()
+	for _, name := range pkg.GoFiles {
+		f, err := parser.ParseFile(fset, filepath.Join(pkg.Dir, name), nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, decl := range f.Decls {
+			if decl, ok := decl.(*ast.GenDecl); ok && decl.Tok == token.TYPE {
+				for _, spec := range decl.Specs {
+					spec := spec.(*ast.TypeSpec)
+					typeSpecs[spec.Name.Name] = spec.Type
+					if st, ok := spec.Type.(*ast.StructType); ok {
+						structs[spec.Name.Name] = st
+					}
+				}
+			}
+		}
+	}
+	// The runtime's lock type is defined per OS; give
+	// its non-struct definition an equivalent struct.
+	if typ, ok := typeSpecs["lock"].(*ast.Ident); ok {
+		structs["lock"] = &ast.StructType{Fields: &ast.FieldList{List: []*ast.Field{
+			{Names: []*ast.Ident{ast.NewIdent("key")}, Type: typ},
+		}}}
+	}
+	return structs
+}
+
+// runtimeFields returns the fields of a runtime struct as
+// variables of types with the same sizes and alignments.
+func runtimeFields(t *testing.T, structs map[string]*ast.StructType, name string) []*types.Var {
+	st, ok := structs[name]
+	if !ok {
+		t.Fatalf("runtime.%s is not a struct", name)
+	}
+	var fields []*types.Var
+	for _, field := range st.Fields.List {
+		typ := runtimeFieldType(t, structs, field.Type)
+		if len(field.Names) == 0 {
+			name := field.Type
+			if star, ok := name.(*ast.StarExpr); ok {
+				name = star.X
+			}
+			fields = append(fields, types.NewVar(0, nil, name.(*ast.Ident).Name, typ))
+		}
+		for _, name := range field.Names {
+			fields = append(fields, types.NewVar(0, nil, name.Name, typ))
+		}
+	}
+	return fields
+}
+
+func runtimeFieldType(t *testing.T, structs map[string]*ast.StructType, expr ast.Expr) types.Type {
+	switch expr := expr.(type) {
+	case *ast.StarExpr:
+		return unsafePointer
+	case *ast.SelectorExpr:
+		if x, ok := expr.X.(*ast.Ident); ok && x.Name == "unsafe" && expr.Sel.Name == "Pointer" {
+			return unsafePointer
+		}
+	case *ast.Ident:
+		if obj, ok := types.Universe.Lookup(expr.Name).(*types.TypeName); ok {
+			return obj.Type()
+		}
+		if _, ok := structs[expr.Name]; ok {
+			return types.NewStruct(runtimeFields(t, structs, expr.Name), nil)
+		}
+	}
+	t.Fatalf("unhandled runtime field type %T", expr)
+	return nil
+}
+
+// renamedFields maps the names of runtime fields to
+// the names the mirrors give them, for runtime-gdb.py.
+var renamedFields = map[string]string{
+	"rtyp": "_type",
+	"typ":  "_type",
+}
+
+func checkRuntimeLayout(t *testing.T, structs map[string]*ast.StructType, name string, mirror types.Type, prefix bool) {
+	sizes := &types.StdSizes{WordSize: 8, MaxAlign: 8}
+	fields := runtimeFields(t, structs, name)
+	st := mirror.Underlying().(*types.Struct)
+	mirrorFields := make([]*types.Var, st.NumFields())
+	for i := range mirrorFields {
+		mirrorFields[i] = st.Field(i)
+	}
+	if len(mirrorFields) > len(fields) || !prefix && len(mirrorFields) != len(fields) {
+		t.Errorf("%s: %d fields, runtime.%s has %d", mirror, len(mirrorFields), name, len(fields))
+		return
+	}
+	offsets := sizes.Offsetsof(fields)
+	mirrorOffsets := sizes.Offsetsof(mirrorFields)
+	for i, f := range mirrorFields {
+		name := fields[i].Name()
+		if renamed, ok := renamedFields[name]; ok {
+			name = renamed
+		}
+		if f.Name() != name {
+			t.Errorf("%s: field %d is %s, runtime's is %s", mirror, i, f.Name(), fields[i].Name())
+		}
+		if mirrorOffsets[i] != offsets[i] {
+			t.Errorf("%s.%s: offset %d, runtime's is %d", mirror, f.Name(), mirrorOffsets[i], offsets[i])
+		}
+		if size, rsize := sizes.Sizeof(f.Type()), sizes.Sizeof(fields[i].Type()); size != rsize {
+			t.Errorf("%s.%s: size %d, runtime's is %d", mirror, f.Name(), size, rsize)
+		}
+	}
+}
+
+func TestRuntimeLayouts(t *testing.T) {
+	structs := runtimeStructs(t)
+	checkRuntimeLayout(t, structs, "rtype", runtimeRtype, true)
+	checkRuntimeLayout(t, structs, "eface", runtimeEface, false)
+	checkRuntimeLayout(t, structs, "iface", runtimeIface, false)
+	checkRuntimeLayout(t, structs, "itab", runtimeItab, false)
+	checkRuntimeLayout(t, structs, "SudoG", runtimeSudoG, false)
+	checkRuntimeLayout(t, structs, "WaitQ", runtimeWaitQ, false)
+
+	// The channel's buffer immediately follows Hchan.
+	hchan := runtimeHchan(types.Typ[types.Int64])
+	st := hchan.Underlying().(*types.Struct)
+	fields := make([]*types.Var, st.NumFields())
+	for i := range fields {
+		fields[i] = st.Field(i)
+	}
+	n := len(fields) - 1
+	checkRuntimeLayout(t, structs, "Hchan", types.NewStruct(fields[:n], nil), false)
+	sizes := &types.StdSizes{WordSize: 8, MaxAlign: 8}
+	bufOffset := sizes.Offsetsof(fields)[n]
+	hchanSize := sizes.Sizeof(types.NewStruct(runtimeFields(t, structs, "Hchan"), nil))
+	if bufOffset != hchanSize {
+		t.Errorf("%s.buf: offset %d, runtime.Hchan's size is %d", hchan, bufOffset, hchanSize)
+	}
+}
+
+func TestRuntimeTypeNames(t *testing.T) {
+	for _, test := range []struct {
+		typ  types.Type
+		name string
+	}{
+		{runtimeEface, "runtime.eface"},
+		{runtimeIface, "runtime.iface"},
+		{runtimeHchan(types.Typ[types.Int]), "runtime.hchan<int>"},
+		{runtimeHmap(types.Typ[types.String], types.Typ[types.Int]), "runtime.hmap<string,int>"},
+	} {
+		if name := test.typ.(*types.Named).Obj().Name(); name != test.name {
+			t.Errorf("%s is named %q, runtime-gdb.py expects %q", test.typ, name, test.name)
+		}
+	}
+}
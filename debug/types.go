@@ -76,10 +76,11 @@ func (m *TypeMap) descriptor(t types.Type, name string) TypeDebugDescriptor {
 func (m *TypeMap) descriptorBasic(t *types.Basic, name string) TypeDebugDescriptor {
 	switch t.Kind() {
 	case types.String:
-		return m.descriptorStruct(types.NewStruct([]*types.Var{
-			types.NewVar(0, nil, "ptr", types.NewPointer(types.Typ[types.Uint8])),
+		dt := m.descriptorStruct(types.NewStruct([]*types.Var{
+			types.NewVar(0, nil, "str", types.NewPointer(types.Typ[types.Uint8])),
 			types.NewVar(0, nil, "len", types.Typ[types.Int]),
-		}, nil), name)
+		}, nil), "string")
+		return m.typedef(dt, name)
 	case types.UnsafePointer:
 		return &BasicTypeDescriptor{
 			TypeDescriptorCommon: TypeDescriptorCommon{
@@ -118,22 +119,56 @@ func (m *TypeMap) descriptorBasic(t *types.Basic, name string) TypeDebugDescript
 }
 
 func (m *TypeMap) descriptorPointer(t *types.Pointer) TypeDebugDescriptor {
-	return NewPointerDerivedType(m.TypeDebugDescriptor(t.Elem()))
+	dt := NewPointerDerivedType(m.TypeDebugDescriptor(t.Elem()))
+	m.setSize(dt, t)
+	return dt
 }
 
 func (m *TypeMap) descriptorStruct(t *types.Struct, name string) TypeDebugDescriptor {
-	members := make([]DebugDescriptor, t.NumFields())
-	for i := range members {
-		f := t.Field(i)
+	fields := make([]*types.Var, t.NumFields())
+	for i := range fields {
+		fields[i] = t.Field(i)
+	}
+	offsets := m.Sizes.Offsetsof(fields)
+	members := make([]DebugDescriptor, len(fields))
+	for i, f := range fields {
 		member := NewMemberDerivedType(m.TypeDebugDescriptor(f.Type()))
 		member.Name = f.Name()
+		m.setSize(member, f.Type())
+		member.Offset = uint64(offsets[i] * 8)
 		members[i] = member
 	}
 	dt := NewStructCompositeType(members)
 	dt.Name = name
+	m.setSize(dt, t)
 	return dt
 }
 
+// setSize sets the size and alignment of the
+// descriptor to those of the specified type.
+func (m *TypeMap) setSize(dt TypeDebugDescriptor, t types.Type) {
+	size := m.Sizes.Sizeof(t)
+	align := m.Sizes.Alignof(t)
+	if size%align != 0 {
+		size += align - size%align
+	}
+	dt.Common().Size = uint64(size * 8)
+	dt.Common().Alignment = uint64(align * 8)
+}
+
+// typedef returns a typedef of the descriptor with the specified
+// name, or the descriptor itself if it already has that name.
+func (m *TypeMap) typedef(dt TypeDebugDescriptor, name string) TypeDebugDescriptor {
+	if dt.Common().Name == name {
+		return dt
+	}
+	td := NewTypedefDerivedType(dt)
+	td.Name = name
+	td.Size = dt.Common().Size
+	td.Alignment = dt.Common().Alignment
+	return td
+}
+
 func (m *TypeMap) descriptorNamed(t *types.Named) TypeDebugDescriptor {
 	placeholder := &PlaceholderTypeDescriptor{}
 	m.m.Set(t, placeholder)
@@ -152,32 +187,42 @@ func (m *TypeMap) descriptorNamed(t *types.Named) TypeDebugDescriptor {
 }
 
 func (m *TypeMap) descriptorArray(t *types.Array, name string) TypeDebugDescriptor {
-	return NewArrayCompositeType(m.TypeDebugDescriptor(t.Elem()), t.Len())
+	dt := NewArrayCompositeType(m.TypeDebugDescriptor(t.Elem()), t.Len())
+	dt.Name = name
+	m.setSize(dt, t)
+	return dt
 }
 
+// Slices are described as structures named for the
+// unnamed slice type, so debuggers can recognise them.
 func (m *TypeMap) descriptorSlice(t *types.Slice, name string) TypeDebugDescriptor {
 	sliceStruct := types.NewStruct([]*types.Var{
-		types.NewVar(0, nil, "ptr", types.NewPointer(t.Elem())),
+		types.NewVar(0, nil, "array", types.NewPointer(t.Elem())),
 		types.NewVar(0, nil, "len", types.Typ[types.Int]),
 		types.NewVar(0, nil, "cap", types.Typ[types.Int]),
 	}, nil)
-	return m.typeDebugDescriptor(sliceStruct, name)
+	dt := m.descriptorStruct(sliceStruct, types.TypeString(nil, t))
+	return m.typedef(dt, name)
 }
 
+// Maps and channels are pointers to runtime structures,
+// described by the types in runtime.go.
 func (m *TypeMap) descriptorMap(t *types.Map, name string) TypeDebugDescriptor {
-	return m.descriptorBasic(types.Typ[types.Uintptr], name)
+	hmap := types.NewPointer(runtimeHmap(t.Key(), t.Elem()))
+	return m.typedef(m.TypeDebugDescriptor(hmap), name)
 }
 
 func (m *TypeMap) descriptorChan(t *types.Chan, name string) TypeDebugDescriptor {
-	return m.descriptorBasic(types.Typ[types.Uintptr], name)
+	hchan := types.NewPointer(runtimeHchan(t.Elem()))
+	return m.typedef(m.TypeDebugDescriptor(hchan), name)
 }
 
 func (m *TypeMap) descriptorInterface(t *types.Interface, name string) TypeDebugDescriptor {
-	ifaceStruct := types.NewStruct([]*types.Var{
-		types.NewVar(0, nil, "type", types.NewPointer(types.Typ[types.Uint8])),
-		types.NewVar(0, nil, "data", types.NewPointer(types.Typ[types.Uint8])),
-	}, nil)
-	return m.typeDebugDescriptor(ifaceStruct, name)
+	iface := runtimeEface
+	if t.NumMethods() > 0 {
+		iface = runtimeIface
+	}
+	return m.typedef(m.TypeDebugDescriptor(iface), name)
 }
 
 func (m *TypeMap) descriptorSignature(t *types.Signature, name string) TypeDebugDescriptor {
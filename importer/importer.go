@@ -22,6 +22,10 @@ var tracing = os.Getenv("LLGO_TRACE_IMPORTER") == "1"
 
 type Importer struct {
 	context *build.Context
+
+	// Files maps import paths to the files holding their
+	// export data, in place of the files in GOPATH.
+	Files map[string]string
 }
 
 // TODO(axw) consolidate the path logic in llgo/build
@@ -50,9 +54,15 @@ func NewImporter(ctx *build.Context) *Importer {
 }
 
 func (imp *Importer) Import(imports map[string]*types.Package, path string) (pkg *types.Package, err error) {
-	exportsPath := packageExportsFile(imp.context, path)
+	exportsPath, ok := imp.Files[path]
+	if !ok {
+		exportsPath = packageExportsFile(imp.context, path)
+	}
 	data, err := ioutil.ReadFile(exportsPath)
 	if err != nil {
+		if ok {
+			return nil, err
+		}
 		// Package has not been compiled yet, so
 		// fall back to the standard GcImport.
 		tracef("Falling back to gc import data for: %s", path)
@@ -65,7 +75,13 @@ func (imp *Importer) Import(imports map[string]*types.Package, path string) (pkg
 // Export generates a file containing package export data
 // suitable for importing with Importer.Import.
 func Export(ctx *build.Context, pkg *types.Package) error {
-	exportsPath := packageExportsFile(ctx, pkg.Path())
+	return ExportFile(packageExportsFile(ctx, pkg.Path()), pkg)
+}
+
+// ExportFile writes the package export data to the specified file,
+// from which Importer.Import will read it if the file is listed
+// in the Importer's Files.
+func ExportFile(exportsPath string, pkg *types.Package) error {
 	err := os.MkdirAll(filepath.Dir(exportsPath), 0755)
 	if err != nil && !os.IsExist(err) {
 		return err
@@ -14,7 +14,7 @@ import (
 // "defer" and "go".
 func (c *compiler) indirectFunction(fn *LLVMValue, args []*LLVMValue) *LLVMValue {
 	nilarytyp := types.NewSignature(nil, nil, nil, nil, false)
-	if len(args) == 0 {
+	if len(args) == 0 && !c.raceEnabled() {
 		val := fn.LLVMValue()
 		ptr := c.builder.CreateExtractValue(val, 0, "")
 		ctx := c.builder.CreateExtractValue(val, 1, "")
@@ -90,6 +90,10 @@ func (c *compiler) indirectFunction(fn *LLVMValue, args []*LLVMValue) *LLVMValue
 	currblock := c.builder.GetInsertBlock()
 	c.builder.SetInsertPointAtEnd(llvm.AddBasicBlock(indirectfn, "entry"))
 	argstruct = indirectfn.Param(0)
+	if c.raceEnabled() {
+		// Pairs with the release in the "go" statement.
+		c.raceAcquire(argstruct)
+	}
 	newargs := make([]*LLVMValue, len(args))
 	for i := range llvmargs[nctx:] {
 		argptr := c.builder.CreateGEP(argstruct, []llvm.Value{
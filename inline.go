@@ -0,0 +1,194 @@
+// Copyright 2014 The llgo Authors.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package llgo
+
+import (
+	"math"
+
+	"code.google.com/p/go.tools/go/ssa"
+	"github.com/axw/gollvm/llvm"
+)
+
+// Some functions in the standard library are implemented by gc
+// in assembly. llgo instead lowers direct calls to them to LLVM
+// intrinsics and instructions, so that they are inlined, and
+// work on every target. Declarations of them that remain (e.g.
+// for function values, or the runtime's aliases of the sync/atomic
+// functions) are defined with linkonce_odr linkage by each module
+// that refers to them.
+
+// inlineFunc emits the instructions implementing a function,
+// given its arguments, and returns its result, if any.
+type inlineFunc func(c *compiler, args []llvm.Value) llvm.Value
+
+// inlineFuncs maps the names of functions to their implementations.
+var inlineFuncs = map[string]inlineFunc{
+	"math.Abs":      mathIntrinsic("llvm.fabs.f64", ""),
+	"math.Ceil":     mathIntrinsic("llvm.ceil.f64", "math.ceil"),
+	"math.Copysign": mathIntrinsic("llvm.copysign.f64", ""),
+	"math.Floor":    mathIntrinsic("llvm.floor.f64", "math.floor"),
+	"math.Sqrt":     (*compiler).sqrt,
+	"math.Trunc":    mathIntrinsic("llvm.trunc.f64", "math.trunc"),
+}
+
+func init() {
+	for _, t := range []string{"Int32", "Int64", "Uint32", "Uint64", "Uintptr", "Pointer"} {
+		inlineFuncs["sync/atomic.Load"+t] = (*compiler).atomicLoad
+		inlineFuncs["sync/atomic.Store"+t] = (*compiler).atomicStore
+		inlineFuncs["sync/atomic.Swap"+t] = (*compiler).atomicSwap
+		inlineFuncs["sync/atomic.CompareAndSwap"+t] = (*compiler).atomicCompareAndSwap
+		if t != "Pointer" {
+			inlineFuncs["sync/atomic.Add"+t] = (*compiler).atomicAdd
+		}
+	}
+}
+
+// atomicOrdering is the memory ordering of
+// all of the sync/atomic operations.
+const atomicOrdering = llvm.AtomicOrderingSequentiallyConsistent
+
+// inlineCall emits the implementation of a static call to one of
+// inlineFuncs. It returns nil if the callee is not one of them.
+func (fr *frame) inlineCall(call *ssa.CallCommon, args []*LLVMValue) *LLVMValue {
+	f, ok := call.Value.(*ssa.Function)
+	if !ok || f.Pkg == nil || f.Signature.Recv() != nil {
+		return nil
+	}
+	impl, ok := inlineFuncs[f.String()]
+	if !ok {
+		return nil
+	}
+	llargs := make([]llvm.Value, len(args))
+	for i, arg := range args {
+		llargs[i] = arg.LLVMValue()
+	}
+	result := impl(fr.compiler, llargs)
+	if f.Signature.Results().Len() == 0 {
+		return fr.NewValue(result, nil)
+	}
+	return fr.NewValue(result, f.Signature.Results().At(0).Type())
+}
+
+// defineInlineFuncs defines the functions declared
+// in the module that are implemented by inlineFuncs.
+func (c *compiler) defineInlineFuncs() {
+	for fn := c.module.FirstFunction(); !fn.IsNil(); fn = llvm.NextFunction(fn) {
+		impl, ok := inlineFuncs[fn.Name()]
+		if !ok || fn.BasicBlocksCount() != 0 {
+			continue
+		}
+		fn.SetLinkage(llvm.LinkOnceODRLinkage)
+		c.builder.SetCurrentDebugLocation(c.debug.MDNode(nil))
+		c.builder.SetInsertPointAtEnd(llvm.AddBasicBlock(fn, "entry"))
+		if result := impl(c, fn.Params()); result.IsNil() {
+			c.builder.CreateRetVoid()
+		} else {
+			c.builder.CreateRet(result)
+		}
+	}
+}
+
+// declareIntrinsic returns the LLVM intrinsic (or other function)
+// with the specified name, declaring it if necessary. Its parameters
+// and result all have the type of the first argument.
+func (c *compiler) declareIntrinsic(name string, args []llvm.Value) llvm.Value {
+	fn := c.module.NamedFunction(name)
+	if fn.IsNil() {
+		typ := args[0].Type()
+		params := make([]llvm.Type, len(args))
+		for i := range params {
+			params[i] = typ
+		}
+		fn = llvm.AddFunction(c.module.Module, name, llvm.FunctionType(typ, params, false))
+	}
+	return fn
+}
+
+// mathIntrinsic returns an inlineFunc calling the named intrinsic.
+// If portable is non-empty, it names the pure Go implementation in
+// package math, which is called instead in SoftFloat mode. Intrinsics
+// without one only manipulate the sign bit.
+func mathIntrinsic(name, portable string) inlineFunc {
+	return func(c *compiler, args []llvm.Value) llvm.Value {
+		fn := name
+		if c.SoftFloat && portable != "" {
+			fn = portable
+		}
+		return c.builder.CreateCall(c.declareIntrinsic(fn, args), args, "")
+	}
+}
+
+// sqrt implements math.Sqrt with llvm.sqrt.f64, whose
+// result is undefined for operands less than -0; Sqrt
+// returns NaN for them.
+func (c *compiler) sqrt(args []llvm.Value) llvm.Value {
+	if c.SoftFloat {
+		return mathIntrinsic("", "math.sqrt")(c, args)
+	}
+	x := args[0]
+	neg := c.builder.CreateFCmp(llvm.FloatOLT, x, llvm.ConstNull(x.Type()), "")
+	x = c.builder.CreateSelect(neg, llvm.ConstFloat(x.Type(), math.NaN()), x, "")
+	return c.builder.CreateCall(c.declareIntrinsic("llvm.sqrt.f64", args), []llvm.Value{x}, "")
+}
+
+// atomicAlignment returns the alignment of the operand of an
+// atomic operation, which must be naturally aligned.
+func (c *compiler) atomicAlignment(ptr llvm.Value) int {
+	return int(c.target.TypeAllocSize(ptr.Type().ElementType()))
+}
+
+func (c *compiler) atomicLoad(args []llvm.Value) llvm.Value {
+	load := c.builder.CreateLoad(args[0], "")
+	load.SetOrdering(atomicOrdering)
+	load.SetAlignment(c.atomicAlignment(args[0]))
+	return load
+}
+
+func (c *compiler) atomicStore(args []llvm.Value) llvm.Value {
+	store := c.builder.CreateStore(args[1], args[0])
+	store.SetOrdering(atomicOrdering)
+	store.SetAlignment(c.atomicAlignment(args[0]))
+	return llvm.Value{}
+}
+
+// atomicAdd returns the new value, as
+// atomicrmw yields the previous value.
+func (c *compiler) atomicAdd(args []llvm.Value) llvm.Value {
+	old := c.builder.CreateAtomicRMW(llvm.AtomicRMWBinOpAdd, args[0], args[1], atomicOrdering, false)
+	return c.builder.CreateAdd(old, args[1], "")
+}
+
+// atomicIntOperands returns the operands of an atomicrmw or cmpxchg,
+// which take only integer operands: pointer values are converted to
+// integers of the target's pointer size, and the address to theirs.
+func (c *compiler) atomicIntOperands(args []llvm.Value) []llvm.Value {
+	if args[1].Type().TypeKind() != llvm.PointerTypeKind {
+		return args
+	}
+	intptr := c.target.IntPtrType()
+	ops := make([]llvm.Value, len(args))
+	ops[0] = c.builder.CreateBitCast(args[0], llvm.PointerType(intptr, 0), "")
+	for i, arg := range args[1:] {
+		ops[i+1] = c.builder.CreatePtrToInt(arg, intptr, "")
+	}
+	return ops
+}
+
+func (c *compiler) atomicSwap(args []llvm.Value) llvm.Value {
+	ops := c.atomicIntOperands(args)
+	old := c.builder.CreateAtomicRMW(llvm.AtomicRMWBinOpXchg, ops[0], ops[1], atomicOrdering, false)
+	if old.Type() != args[1].Type() {
+		old = c.builder.CreateIntToPtr(old, args[1].Type(), "")
+	}
+	return old
+}
+
+// atomicCompareAndSwap compares the value yielded by
+// cmpxchg, the previous value, with the expected value.
+func (c *compiler) atomicCompareAndSwap(args []llvm.Value) llvm.Value {
+	ops := c.atomicIntOperands(args)
+	old := c.builder.CreateAtomicCmpXchg(ops[0], ops[1], ops[2], atomicOrdering, false)
+	return c.builder.CreateICmp(llvm.IntEQ, old, ops[1], "")
+}
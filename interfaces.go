@@ -97,8 +97,19 @@ func (c *compiler) makeInterface(v *LLVMValue, iface types.Type) *LLVMValue {
 		}
 	}
 	value := llvm.Undef(c.types.ToLLVM(iface))
+	if iface.Underlying().(*types.Interface).NumMethods() > 0 {
+		// Use a static itab if the compiler can resolve the
+		// methods; otherwise the runtime computes (and caches)
+		// the itab.
+		if itab, ok := c.types.ToItab(iface, v.Type()); ok {
+			itab = c.builder.CreateBitCast(itab, i8ptr, "")
+			value = c.builder.CreateInsertValue(value, itab, 0, "")
+			value = c.builder.CreateInsertValue(value, llv, 1, "")
+			return c.NewValue(value, iface)
+		}
+	}
 	rtype := c.types.ToRuntime(v.Type())
-	rtype = c.builder.CreateBitCast(rtype, llvm.PointerType(llvm.Int8Type(), 0), "")
+	rtype = c.builder.CreateBitCast(rtype, i8ptr, "")
 	value = c.builder.CreateInsertValue(value, rtype, 0, "")
 	value = c.builder.CreateInsertValue(value, llv, 1, "")
 	if iface.Underlying().(*types.Interface).NumMethods() > 0 {
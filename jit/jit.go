@@ -0,0 +1,304 @@
+// Copyright 2014 The llgo Authors.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+// Package jit runs programs compiled by llgo in-process, with
+// LLVM's MCJIT. It is used by "llgo -run" and by llgo-repl.
+package jit
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"unsafe"
+
+	"github.com/axw/gollvm/llvm"
+	llgobuild "github.com/axw/llgo/build"
+)
+
+// JIT is an MCJIT execution engine to which the modules of
+// the runtime, the imported packages, and the program (or
+// each snippet of a REPL session) are added in turn. Each
+// symbol is defined only once: a module's definitions of
+// symbols that are already defined by an earlier module are
+// replaced by declarations, so that later modules share the
+// state of those before them. Symbols that are not defined
+// by any module, such as those of libc, are resolved in the
+// host process.
+type JIT struct {
+	ctx     *llgobuild.Context
+	pkgroot string
+	engine  llvm.ExecutionEngine
+
+	// started is true once the runtime
+	// has been initialised by Main.
+	started bool
+
+	// defined records the names of the symbols
+	// defined by the modules in the engine.
+	defined map[string]bool
+
+	// loaded records the packages whose
+	// bitcode has been added to the engine.
+	loaded map[string]bool
+
+	// nctors is the number of modules whose
+	// static constructors have been run.
+	nctors int
+
+	// argv and envp are passed to the runtime,
+	// which refers to them for the session.
+	argv, envp []*byte
+}
+
+// New returns a JIT that loads the bitcode of packages
+// built for the context from pkgroot, as returned by
+// build.PkgRoot.
+func New(ctx *llgobuild.Context, pkgroot string) *JIT {
+	llvm.LinkInMCJIT()
+	llvm.InitializeAllAsmPrinters()
+	return &JIT{
+		ctx:     ctx,
+		pkgroot: pkgroot,
+		defined: make(map[string]bool),
+		loaded:  map[string]bool{"unsafe": true},
+	}
+}
+
+// Dispose runs the static destructors of the
+// modules, and disposes of the engine.
+func (j *JIT) Dispose() {
+	if j.engine.C != nil {
+		j.engine.RunStaticDestructors()
+		j.engine.Dispose()
+	}
+}
+
+// newPackages returns the import paths of the packages that
+// are not yet loaded, of those specified and their imports,
+// in an order such that each package precedes its importers.
+func (j *JIT) newPackages(paths []string) ([]string, error) {
+	var pkgs []string
+	seen := make(map[string]bool)
+	var add func(paths []string) error
+	add = func(paths []string) error {
+		for _, path := range paths {
+			if path == "C" {
+				return fmt.Errorf("cgo is not supported")
+			}
+			if seen[path] || j.loaded[path] {
+				continue
+			}
+			seen[path] = true
+			pkg, err := j.ctx.Import(path, "", 0)
+			if err != nil {
+				return err
+			}
+			if err := add(pkg.Imports); err != nil {
+				return err
+			}
+			pkgs = append(pkgs, path)
+		}
+		return nil
+	}
+	if err := add(paths); err != nil {
+		return nil, err
+	}
+	return pkgs, nil
+}
+
+// Load adds the bitcode of the specified packages, and those
+// they import, to the engine. If the runtime has already been
+// initialised, the newly loaded packages are initialised.
+func (j *JIT) Load(paths []string) error {
+	pkgs, err := j.newPackages(paths)
+	if err != nil {
+		return err
+	}
+	for _, path := range pkgs {
+		bcfile := filepath.Join(j.pkgroot, path+".bc")
+		if _, err := os.Stat(bcfile); err != nil {
+			return fmt.Errorf("package %q is not installed; build it with llgo-build", path)
+		}
+		m, err := llvm.ParseBitcodeFile(bcfile)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %v", bcfile, err)
+		}
+		if err := j.Add(m); err != nil {
+			return err
+		}
+		j.loaded[path] = true
+	}
+	if j.started {
+		for _, path := range pkgs {
+			if init := j.engine.FindFunction(path + ".init"); !init.IsNil() {
+				j.engine.RunFunction(init, nil)
+			}
+		}
+	}
+	return nil
+}
+
+// Add adds the module to the engine, creating the engine if
+// this is the first module, and runs the module's static
+// constructors. The module is owned by the engine thereafter.
+func (j *JIT) Add(m llvm.Module) error {
+	j.claim(m)
+	ctors := j.collectCtors(m)
+	if j.engine.C == nil {
+		engine, err := llvm.NewMCJITCompiler(m, llvm.MCJITCompilerOptions{OptLevel: 2})
+		if err != nil {
+			m.Dispose()
+			return err
+		}
+		j.engine = engine
+	} else {
+		j.engine.AddModule(m)
+	}
+	if ctors != "" {
+		return j.Run(ctors)
+	}
+	return nil
+}
+
+// claim records the external symbols defined by the module,
+// replacing any that are already defined in the engine with
+// declarations.
+func (j *JIT) claim(m llvm.Module) {
+	for f := m.FirstFunction(); !f.IsNil(); {
+		next := llvm.NextFunction(f)
+		if !f.IsDeclaration() && !isLocal(f) {
+			if name := f.Name(); j.defined[name] {
+				f.SetName("")
+				decl := llvm.AddFunction(m, name, f.Type().ElementType())
+				f.ReplaceAllUsesWith(decl)
+				f.EraseFromParentAsFunction()
+			} else {
+				j.defined[name] = true
+			}
+		}
+		f = next
+	}
+	for g := m.FirstGlobal(); !g.IsNil(); {
+		next := llvm.NextGlobal(g)
+		if !g.IsDeclaration() && !isLocal(g) {
+			if name := g.Name(); j.defined[name] {
+				g.SetName("")
+				decl := llvm.AddGlobal(m, g.Type().ElementType(), name)
+				decl.SetThreadLocal(g.IsThreadLocal())
+				g.ReplaceAllUsesWith(decl)
+				llvm.DeleteGlobal(g)
+			} else {
+				j.defined[name] = true
+			}
+		}
+		g = next
+	}
+}
+
+// ctor is an entry of llvm.global_ctors.
+type ctor struct {
+	priority uint64
+	fn       llvm.Value
+}
+
+// collectCtors replaces the module's llvm.global_ctors with a
+// function that calls each constructor in order of priority,
+// returning the name of the function, or "" if the module has
+// no constructors. The execution engine can only run the
+// constructors of every module it holds at once, which would
+// run those of the modules added earlier again.
+func (j *JIT) collectCtors(m llvm.Module) string {
+	global := m.NamedGlobal("llvm.global_ctors")
+	if global.IsNil() {
+		return ""
+	}
+	var ctors []ctor
+	if init := global.Initializer(); !init.IsNil() {
+		for i := 0; i < init.OperandsCount(); i++ {
+			entry := init.Operand(i)
+			fn := entry.Operand(1)
+			if fn.IsNull() {
+				continue
+			}
+			ctors = append(ctors, ctor{entry.Operand(0).ZExtValue(), fn})
+		}
+	}
+	sort.Stable(byPriority(ctors))
+	llvm.DeleteGlobal(global)
+
+	j.nctors++
+	name := fmt.Sprintf("__llgo.jit.ctors.%d", j.nctors)
+	fn := llvm.AddFunction(m, name, llvm.FunctionType(llvm.VoidType(), nil, false))
+	builder := llvm.NewBuilder()
+	defer builder.Dispose()
+	builder.SetInsertPointAtEnd(llvm.AddBasicBlock(fn, "entry"))
+	for _, c := range ctors {
+		builder.CreateCall(c.fn, nil, "")
+	}
+	builder.CreateRetVoid()
+	j.defined[name] = true
+	return name
+}
+
+type byPriority []ctor
+
+func (p byPriority) Len() int           { return len(p) }
+func (p byPriority) Less(i, j int) bool { return p[i].priority < p[j].priority }
+func (p byPriority) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+
+// isLocal reports whether a symbol is local to its module, and so
+// is not shared with other modules: private and internal symbols,
+// and appending globals such as llvm.global_ctors.
+func isLocal(v llvm.Value) bool {
+	switch v.Linkage() {
+	case llvm.InternalLinkage, llvm.PrivateLinkage, llvm.AppendingLinkage:
+		return true
+	}
+	return false
+}
+
+// Main runs the "main" function defined by the modules, with
+// the specified arguments (including the program name) and
+// the environment of the host process. This initialises the
+// runtime and the loaded packages, and, unless the program is
+// a REPL session whose "main" returns immediately, runs the
+// program. The result of "main" is returned.
+func (j *JIT) Main(args []string) (int, error) {
+	mainFunc := j.engine.FindFunction("main")
+	if mainFunc.IsNil() {
+		return 0, fmt.Errorf("program has no main function")
+	}
+	j.argv = cstrings(args)
+	j.envp = cstrings(os.Environ())
+	j.started = true
+	result := j.engine.RunFunction(mainFunc, []llvm.GenericValue{
+		llvm.NewGenericValueFromInt(llvm.Int32Type(), uint64(len(j.argv)-1), true),
+		llvm.NewGenericValueFromPointer(unsafe.Pointer(&j.argv[0])),
+		llvm.NewGenericValueFromPointer(unsafe.Pointer(&j.envp[0])),
+	})
+	return int(int32(result.Int(true))), nil
+}
+
+// Run runs the niladic function with the specified name.
+func (j *JIT) Run(name string) error {
+	f := j.engine.FindFunction(name)
+	if f.IsNil() {
+		return fmt.Errorf("no function %q", name)
+	}
+	j.engine.RunFunction(f, nil)
+	return nil
+}
+
+// cstrings returns a NULL-terminated array of pointers to
+// NUL-terminated copies of the strings, as passed to "main".
+func cstrings(strs []string) []*byte {
+	ptrs := make([]*byte, len(strs)+1)
+	for i, s := range strs {
+		b := make([]byte, len(s)+1)
+		copy(b, s)
+		ptrs[i] = &b[0]
+	}
+	return ptrs
+}
@@ -0,0 +1,77 @@
+// Copyright 2014 The llgo Authors.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package jit
+
+import (
+	"testing"
+
+	"github.com/axw/gollvm/llvm"
+)
+
+func init() {
+	llvm.InitializeAllTargets()
+	llvm.InitializeAllTargetMCs()
+	llvm.InitializeAllTargetInfos()
+}
+
+// ctorModule returns a module that defines the global "counter",
+// the function "get" that returns its value, and a constructor
+// that increments it.
+func ctorModule(name string) llvm.Module {
+	m := llvm.NewModule(name)
+	i32 := llvm.Int32Type()
+	counter := llvm.AddGlobal(m, i32, "counter")
+	counter.SetInitializer(llvm.ConstNull(i32))
+
+	b := llvm.NewBuilder()
+	defer b.Dispose()
+	get := llvm.AddFunction(m, "get", llvm.FunctionType(i32, nil, false))
+	b.SetInsertPointAtEnd(llvm.AddBasicBlock(get, "entry"))
+	b.CreateRet(b.CreateLoad(counter, ""))
+
+	fntyp := llvm.FunctionType(llvm.VoidType(), nil, false)
+	ctor := llvm.AddFunction(m, "ctor", fntyp)
+	ctor.SetLinkage(llvm.InternalLinkage)
+	b.SetInsertPointAtEnd(llvm.AddBasicBlock(ctor, "entry"))
+	value := b.CreateAdd(b.CreateLoad(counter, ""), llvm.ConstInt(i32, 1, false), "")
+	b.CreateStore(value, counter)
+	b.CreateRetVoid()
+
+	entrytyp := llvm.StructType([]llvm.Type{i32, llvm.PointerType(fntyp, 0)}, false)
+	entry := llvm.ConstStruct([]llvm.Value{llvm.ConstInt(i32, 65535, false), ctor}, false)
+	ctors := llvm.AddGlobal(m, llvm.ArrayType(entrytyp, 1), "llvm.global_ctors")
+	ctors.SetLinkage(llvm.AppendingLinkage)
+	ctors.SetInitializer(llvm.ConstArray(entrytyp, []llvm.Value{entry}))
+	return m
+}
+
+// TestAddRunsConstructors checks that the static constructors
+// of each module are run once, when the module is added.
+func TestAddRunsConstructors(t *testing.T) {
+	j := New(nil, "")
+	defer j.Dispose()
+	for i, name := range []string{"a", "b", "c"} {
+		if err := j.Add(ctorModule(name)); err != nil {
+			t.Fatal(err)
+		}
+		get := j.engine.FindFunction("get")
+		if get.IsNil() {
+			t.Fatal("get not found")
+		}
+		if n := j.engine.RunFunction(get, nil).Int(false); n != uint64(i+1) {
+			t.Errorf("after adding %s, counter is %d, expected %d", name, n, i+1)
+		}
+	}
+}
+
+func TestCStrings(t *testing.T) {
+	ptrs := cstrings([]string{"ab", ""})
+	if len(ptrs) != 3 || ptrs[2] != nil {
+		t.Fatalf("expected two strings and a NULL pointer, got %v", ptrs)
+	}
+	if *ptrs[1] != 0 {
+		t.Errorf("expected an empty string")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"testing"
 )
 
-func TestNew(t *testing.T) { checkOutputEqual(t, "new.go") }
+func TestNew(t *testing.T)   { checkOutputEqual(t, "new.go") }
+func TestPrint(t *testing.T) { checkOutputEqual(t, "print.go") }
 
 // vim: set ft=go:
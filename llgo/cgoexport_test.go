@@ -0,0 +1,72 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/axw/gollvm/llvm"
+	llgobuild "github.com/axw/llgo/build"
+)
+
+// calledFunctions returns the names of the functions called
+// in the entry block of fn, in the order they are called.
+func calledFunctions(fn llvm.Value) []string {
+	var names []string
+	entry := fn.EntryBasicBlock()
+	for instr := entry.FirstInstruction(); !instr.IsNil(); instr = llvm.NextInstruction(instr) {
+		if instr.InstructionOpcode() == llvm.Call {
+			callee := instr.Operand(instr.OperandsCount() - 1)
+			names = append(names, callee.Name())
+		}
+	}
+	return names
+}
+
+// checkCgoExport compiles a package that exports a function
+// to C in the specified build mode, and checks the calls made
+// by the function that C calls in its place before the Go
+// function is called.
+func checkCgoExport(t *testing.T, mode string, expected []string) {
+	*buildmode = mode
+	defer func() { *buildmode = "exe" }()
+	compiler, err := initCompiler()
+	if err != nil {
+		t.Fatalf("Failed to initialise compiler: %s", err)
+	}
+	m, err := compileFiles(compiler, testdata("cgoexport/add.go"), "main")
+	if err != nil {
+		t.Fatalf("compileFiles failed: %s", err)
+	}
+	defer m.Dispose()
+
+	callback := m.NamedFunction(llgobuild.CgoExportSymbol("main", "Add"))
+	if callback.IsNil() {
+		t.Fatal("cgo callback for Add not found")
+	}
+	calls := calledFunctions(callback)
+	if len(calls) < len(expected) {
+		t.Fatalf("%s: got calls %v, expected %v first", mode, calls, expected)
+	}
+	for i, name := range expected {
+		if calls[i] != name {
+			t.Fatalf("%s: got calls %v, expected %v first", mode, calls, expected)
+		}
+	}
+	for _, name := range calledFunctions(m.NamedFunction("main.Add")) {
+		if name == "runtime.libinit" {
+			t.Errorf("%s: main.Add initialises the runtime itself", mode)
+		}
+	}
+	if hasMain := !m.NamedFunction("main").IsNil(); hasMain != (mode == "exe") {
+		t.Errorf("%s: main function defined: %v", mode, hasMain)
+	}
+}
+
+func TestCgoExportExe(t *testing.T) {
+	checkCgoExport(t, "exe", []string{"runtime.cgocallbackenter"})
+}
+
+func TestCgoExportLibrary(t *testing.T) {
+	expected := []string{"runtime.libinit", "runtime.cgocallbackenter"}
+	checkCgoExport(t, "c-archive", expected)
+	checkCgoExport(t, "c-shared", expected)
+}
@@ -16,4 +16,8 @@ func TestChanRange(t *testing.T) {
 	checkOutputEqual(t, "chan/range.go")
 }
 
+func TestChanReflectSelect(t *testing.T) {
+	checkOutputEqual(t, "chan/reflectselect.go")
+}
+
 //func TestChanUnbuffered(t *testing.T) { checkOutputEqual(t, "chan/unbuffered.go") }
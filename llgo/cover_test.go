@@ -0,0 +1,70 @@
+package main
+
+import (
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/axw/gollvm/llvm"
+	llgobuild "github.com/axw/llgo/build"
+)
+
+// checkCoverEqual instruments cover/blocks.go with "go tool cover"
+// and with llgo in the specified mode, and checks that the blocks
+// and counters reported by each program match.
+func checkCoverEqual(t *testing.T, mode string) {
+	// First run the program instrumented by "go tool cover"
+	// to get the expected output.
+	gofile := filepath.Join(tempdir, "blocks.go")
+	cmd := exec.Command("go", "tool", "cover", "-mode="+mode, "-var=coverCounters", "-o", gofile, "testdata/cover/blocks.go")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("go tool cover failed: %v\n\t%s", err, out)
+	}
+	report, err := ioutil.ReadFile("testdata/cover/report_gc.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reportfile := filepath.Join(tempdir, "report_gc.go")
+	if err := ioutil.WriteFile(reportfile, report, 0644); err != nil {
+		t.Fatal(err)
+	}
+	cmd = exec.Command("go", "run", gofile, reportfile)
+	gorun_out, err := cmd.CombinedOutput()
+	expected := strings.Split(strings.TrimSpace(string(gorun_out)), "\n")
+	if err != nil {
+		t.Fatalf("go run failed: %v\n\t%s", err, strings.Join(expected, "\n\t"))
+	}
+
+	*covermode = mode
+	defer func() { *covermode = "" }()
+	testCompiler, err = initCompiler()
+	if err != nil {
+		t.Fatalf("Failed to initialise compiler: %s", err)
+	}
+	m, err := compileFiles(testCompiler, testdata("cover/blocks.go", "cover/report_llgo.go"), "main")
+	if err != nil {
+		t.Fatalf("compileFiles failed: %s", err)
+	}
+
+	// Point report_llgo.go's coverFiles at the coverage data.
+	coverFiles := m.NamedGlobal("llgo_test_coverfiles")
+	coverData := m.NamedGlobal(llgobuild.CoverSymbol("main"))
+	if coverFiles.IsNil() || coverData.IsNil() {
+		t.Fatal("coverage variables not found")
+	}
+	coverFiles.ReplaceAllUsesWith(llvm.ConstBitCast(coverData, coverFiles.Type()))
+	llvm.DeleteGlobal(coverFiles)
+
+	output, err := runMainFunction(m)
+	if err != nil {
+		t.Fatalf("runMainFunction failed: %s\n\t%s", err, strings.Join(output, "\n\t"))
+	}
+	if err := checkStringsEqual(output, expected); err != nil {
+		t.Fatalf("mode %s: %v", mode, err)
+	}
+}
+
+func TestCoverSet(t *testing.T)   { checkCoverEqual(t, "set") }
+func TestCoverCount(t *testing.T) { checkCoverEqual(t, "count") }
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/axw/gollvm/llvm"
+)
+
+// compileDebug compiles the specified files with debug information,
+// verifies the module, and returns its disassembly.
+func compileDebug(files ...string) (string, error) {
+	*generateDebug = true
+	var err error
+	testCompiler, err = initCompiler()
+	if err != nil {
+		return "", fmt.Errorf("Failed to initialise compiler: %s", err)
+	}
+	m, err := compileFiles(testCompiler, testdata(files...), "main")
+	if err != nil {
+		return "", fmt.Errorf("compileFiles failed: %s", err)
+	}
+	defer m.Dispose()
+	if err := llvm.VerifyModule(m.Module, llvm.ReturnStatusAction); err != nil {
+		return "", fmt.Errorf("Verification failed: %v", err)
+	}
+
+	bcpath := filepath.Join(tempdir, "debug.bc")
+	bcfile, err := os.Create(bcpath)
+	if err != nil {
+		return "", err
+	}
+	err = llvm.WriteBitcodeToFile(m.Module, bcfile)
+	bcfile.Close()
+	if err != nil {
+		return "", err
+	}
+	data, err := exec.Command("llvm-dis", "-o", "-", bcpath).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("llvm-dis failed: %v\n%s", err, data)
+	}
+	return string(data), nil
+}
+
+func TestDebugRuntimeTypes(t *testing.T) {
+	ir, err := compileDebug("debug/runtime.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The runtime-gdb.py pretty printers
+	// recognise types by these names.
+	for _, name := range []string{
+		"runtime.eface",
+		"runtime.iface",
+		"runtime.itab",
+		"runtime.rtype",
+		"runtime.hchan<int>",
+		"runtime.WaitQ",
+		"runtime.SudoG",
+		"runtime.hmap<string,int>",
+		"runtime.mapentry<string,int>",
+		"[]byte",
+		"string",
+	} {
+		if !strings.Contains(ir, `"`+name+`"`) {
+			t.Errorf("no debug type named %q", name)
+		}
+	}
+	checkOutputEqual(t, "debug/runtime.go")
+}
+
+var (
+	irDefine   = regexp.MustCompile(`^define .*@([-a-zA-Z$._0-9]+|"[^"]+")\(`)
+	irLabel    = regexp.MustCompile(`^(?:([-a-zA-Z$._0-9]+):|; <label>:(\d+))`)
+	irDef      = regexp.MustCompile(`^\s+%([-a-zA-Z$._0-9]+) = `)
+	irSucc     = regexp.MustCompile(`label %([-a-zA-Z$._0-9]+)`)
+	irDbgValue = regexp.MustCompile(`@llvm\.dbg\.value\(metadata !?\{?[^%,]*%([-a-zA-Z$._0-9]+)`)
+)
+
+// checkDebugValues checks that the operand of each llvm.dbg.value
+// call in the disassembled module is defined by an instruction that
+// dominates the call.
+func checkDebugValues(ir string) error {
+	type instr struct {
+		block string
+		index int
+	}
+	var fn string
+	var block string
+	var index int
+	var blocks []string
+	var preds map[string][]string
+	var defs map[string]instr
+	var uses map[string][]instr
+	check := func() error {
+		// Compute the dominators of each block iteratively.
+		dom := make(map[string]map[string]bool)
+		for i, b := range blocks {
+			dom[b] = map[string]bool{b: true}
+			if i > 0 {
+				for _, d := range blocks {
+					dom[b][d] = true
+				}
+			}
+		}
+		for changed := true; changed; {
+			changed = false
+			for _, b := range blocks[1:] {
+				for d := range dom[b] {
+					if d == b {
+						continue
+					}
+					for _, p := range preds[b] {
+						if !dom[p][d] {
+							delete(dom[b], d)
+							changed = true
+							break
+						}
+					}
+				}
+			}
+		}
+		for name, calls := range uses {
+			def, ok := defs[name]
+			if !ok {
+				continue // a parameter
+			}
+			for _, use := range calls {
+				if def.block == use.block && def.index >= use.index || !dom[use.block][def.block] {
+					return fmt.Errorf("%s: llvm.dbg.value in %s refers to %%%s, which does not dominate it", fn, use.block, name)
+				}
+			}
+		}
+		return nil
+	}
+	for _, line := range strings.Split(ir, "\n") {
+		switch {
+		case irDefine.MatchString(line):
+			fn = irDefine.FindStringSubmatch(line)[1]
+			block, index = "", 0
+			blocks = []string{block}
+			preds = make(map[string][]string)
+			defs = make(map[string]instr)
+			uses = make(map[string][]instr)
+			continue
+		case fn == "":
+			continue
+		case line == "}":
+			if err := check(); err != nil {
+				return err
+			}
+			fn = ""
+			continue
+		}
+		if m := irLabel.FindStringSubmatch(line); m != nil {
+			// Drop the implicit entry block if it is labelled.
+			if index == 0 && len(blocks) == 1 {
+				blocks = blocks[:0]
+			}
+			block, index = m[1]+m[2], 0
+			blocks = append(blocks, block)
+			continue
+		}
+		index++
+		if m := irDef.FindStringSubmatch(line); m != nil {
+			defs[m[1]] = instr{block, index}
+		}
+		if m := irDbgValue.FindStringSubmatch(line); m != nil {
+			uses[m[1]] = append(uses[m[1]], instr{block, index})
+		} else if !strings.Contains(line, " phi ") {
+			for _, m := range irSucc.FindAllStringSubmatch(line, -1) {
+				preds[m[1]] = append(preds[m[1]], block)
+			}
+		}
+	}
+	return nil
+}
+
+func TestDebugValues(t *testing.T) {
+	ir, err := compileDebug("debug/values.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(ir, "@llvm.dbg.value(") {
+		t.Fatal("no llvm.dbg.value calls emitted")
+	}
+	if err := checkDebugValues(ir); err != nil {
+		t.Fatal(err)
+	}
+	checkOutputEqual(t, "debug/values.go")
+}
+
+func TestDebugGdbScript(t *testing.T) {
+	var python string
+	for _, name := range []string{"python", "python3"} {
+		if path, err := exec.LookPath(name); err == nil {
+			python = path
+			break
+		}
+	}
+	if python == "" {
+		t.Skip("python not found")
+	}
+	script := filepath.Join("..", "pkg", "runtime", "runtime-gdb.py")
+	src := fmt.Sprintf("compile(open(%q).read(), %q, 'exec')", script, script)
+	if out, err := exec.Command(python, "-c", src).CombinedOutput(); err != nil {
+		t.Fatalf("%s does not compile: %v\n%s", script, err, out)
+	}
+}
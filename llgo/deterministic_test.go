@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/axw/gollvm/llvm"
+)
+
+// compileToBitcode compiles the files with a new compiler,
+// and returns the bitcode of the resulting module.
+func compileToBitcode(files []string) ([]byte, error) {
+	compiler, err := initCompiler()
+	if err != nil {
+		return nil, err
+	}
+	m, err := compileFiles(compiler, files, "main")
+	if err != nil {
+		return nil, err
+	}
+	defer m.Dispose()
+	f, err := ioutil.TempFile(tempdir, "deterministic")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	if err := llvm.WriteBitcodeToFile(m.Module, f); err != nil {
+		return nil, err
+	}
+	return ioutil.ReadFile(f.Name())
+}
+
+// Test that compiling the same source twice
+// produces identical bitcode.
+func TestDeterministicBitcode(t *testing.T) {
+	// Files that are compiled together with others,
+	// and the files they are compiled with.
+	together := map[string][]string{
+		"init.go":  {"init.go", "init2.go"},
+		"init2.go": nil,
+	}
+	var programs [][]string
+	err := filepath.Walk("testdata", func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if info.Name() == "programs" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, ".go") {
+			return nil
+		}
+		files, ok := together[info.Name()]
+		if !ok {
+			files = []string{info.Name()}
+		}
+		if len(files) > 0 {
+			program := make([]string, len(files))
+			for i, file := range files {
+				program[i] = filepath.Join(filepath.Dir(path), file)
+			}
+			programs = append(programs, program)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, files := range programs {
+		first, err := compileToBitcode(files)
+		if err != nil {
+			t.Errorf("%v: %v", files, err)
+			continue
+		}
+		second, err := compileToBitcode(files)
+		if err != nil {
+			t.Errorf("%v: %v", files, err)
+			continue
+		}
+		if !bytes.Equal(first, second) {
+			t.Errorf("%v: bitcode differs between compilations", files)
+		}
+	}
+}
+
+// vim: set ft=go:
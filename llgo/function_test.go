@@ -12,5 +12,7 @@ func TestMethodValues(t *testing.T)      { checkOutputEqual(t, "methods/methodva
 func TestClosure(t *testing.T)           { checkOutputEqual(t, "closures/basic.go") }
 func TestMultiValueCall(t *testing.T)    { checkOutputEqual(t, "functions/multivalue.go") }
 func TestUnreachableCode(t *testing.T)   { checkOutputEqual(t, "functions/unreachable.go") }
+func TestLexicalScopes(t *testing.T)     { checkOutputEqual(t, "functions/scopes.go") }
+func TestIntrinsics(t *testing.T)        { checkOutputEqual(t, "functions/intrinsics.go") }
 
 // vim: set ft=go:
@@ -17,6 +17,7 @@ func TestError(t *testing.T)             { checkOutputEqual(t, "interfaces/error
 func TestInterfaceWordSize(t *testing.T) { checkOutputEqual(t, "interfaces/wordsize.go") }
 func TestCompareI2V(t *testing.T)        { checkOutputEqual(t, "interfaces/comparei2v.go") }
 func TestCompareI2I(t *testing.T)        { checkOutputEqual(t, "interfaces/comparei2i.go") }
+func TestInterfaceItab(t *testing.T)     { checkOutputEqual(t, "interfaces/itab.go") }
 
 //func TestInterfaceImport(t *testing.T) { checkOutputEqual(t, "interfaces/import.go") }
 
@@ -15,11 +15,13 @@ import (
 	"fmt"
 	"github.com/axw/gollvm/llvm"
 	"github.com/axw/llgo"
+	llgobuild "github.com/axw/llgo/build"
 	"go/scanner"
 	"log"
 	"os"
 	"runtime"
 	"sort"
+	"strings"
 )
 
 var dump = flag.Bool(
@@ -45,6 +47,36 @@ var printTriple = flag.Bool("print-triple", false, "Print out target triple and
 var compileOnly = flag.Bool("c", false, "Compile only, don't link")
 var generateDebug = flag.Bool("g", true, "Generate source level debug information")
 var outputFile = flag.String("o", "-", "Output filename")
+var race = flag.Bool("race", false, "Instrument code for data race detection")
+var covermode = flag.String("covermode", "", "Instrument code for coverage analysis: set, count or atomic")
+var buildmode = flag.String("buildmode", "exe", "Build mode for package main: exe, c-archive or c-shared")
+var run = flag.Bool("run", false, "Compile and run the program with the JIT: -run files.go [arguments]")
+var softfloat = flag.Bool("softfloat", false, "Implement floating point operations in software")
+var exportFile = flag.String("exportfile", "", "Write the package's export data to the file, instead of in GOPATH")
+var stringVars = make(llgobuild.StringVarsFlag)
+var importFiles = make(importFilesFlag)
+
+func init() {
+	flag.Var(stringVars, "X", "Set the value of a string variable: importpath.name=value (may be repeated)")
+	flag.Var(importFiles, "importfile", "Read a package's export data from a file, instead of from GOPATH: importpath=file (may be repeated)")
+}
+
+// importFilesFlag is a flag.Value that accumulates
+// importpath=file pairs.
+type importFilesFlag map[string]string
+
+func (f importFilesFlag) String() string {
+	return ""
+}
+
+func (f importFilesFlag) Set(s string) error {
+	eq := strings.Index(s, "=")
+	if eq == -1 {
+		return fmt.Errorf("expected importpath=file, got %q", s)
+	}
+	f[s[:eq]] = s[eq+1:]
+	return nil
+}
 
 var exitCode = 0
 
@@ -57,6 +89,28 @@ func compileFiles(compiler *llgo.Compiler, filenames []string, importpath string
 	return compiler.Compile(filenames, importpath)
 }
 
+// isBitcodeFile reports whether the arguments name a single
+// bitcode file, such as a program linked by llgo-build.
+func isBitcodeFile(args []string) bool {
+	return len(args) == 1 && strings.HasSuffix(args[0], ".bc")
+}
+
+// setStringVars reads the linked program in the bitcode file,
+// sets the string variables named with -X, and writes the
+// program to the output file.
+func setStringVars(bcfile string) error {
+	m, err := llvm.ParseBitcodeFile(bcfile)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %v", bcfile, err)
+	}
+	module := &llgo.Module{Module: m}
+	defer module.Dispose()
+	if err := llgo.SetStringVars(m, stringVars); err != nil {
+		return err
+	}
+	return writeObjectFile(module)
+}
+
 func writeObjectFile(m *llgo.Module) error {
 	var outfile *os.File
 	switch *outputFile {
@@ -183,6 +237,12 @@ func initCompiler() (*llgo.Compiler, error) {
 		opts.OrderedCompilation = true
 	}
 	opts.GenerateDebug = *generateDebug
+	opts.RaceDetector = *race
+	opts.CoverMode = *covermode
+	opts.BuildMode = *buildmode
+	opts.SoftFloat = *softfloat
+	opts.ExportFile = *exportFile
+	opts.ImportFiles = importFiles
 	return llgo.NewCompiler(opts)
 }
 
@@ -201,12 +261,42 @@ func main() {
 		os.Exit(0)
 	}
 
+	if isBitcodeFile(flag.Args()) {
+		if err := setStringVars(flag.Arg(0)); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
 	compiler, err := initCompiler()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "initCompiler failed: %s\n", err)
 		os.Exit(1)
 	}
 
+	if *run {
+		if *buildmode != "exe" {
+			fmt.Fprintln(os.Stderr, "-run may only be used with -buildmode=exe")
+			os.Exit(1)
+		}
+		files, args := splitRunArgs(flag.Args())
+		if len(files) == 0 {
+			fmt.Fprintln(os.Stderr, "-run requires at least one Go file")
+			os.Exit(1)
+		}
+		rc, err := runFiles(compiler, files, args)
+		if err != nil {
+			report(err)
+			os.Exit(exitCode)
+		}
+		os.Exit(rc)
+	}
+
+	if len(stringVars) > 0 {
+		fmt.Fprintln(os.Stderr, "-X may only be used with -run, or with a linked bitcode file")
+		os.Exit(1)
+	}
 	module, err := compileFiles(compiler, flag.Args(), *importpath)
 	if err == nil {
 		defer module.Dispose()
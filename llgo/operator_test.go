@@ -1,9 +1,126 @@
 package main
 
 import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"regexp"
 	"testing"
+
+	"github.com/axw/gollvm/llvm"
+	llgobuild "github.com/axw/llgo/build"
 )
 
 func TestOperators(t *testing.T)               { checkOutputEqual(t, "operators/basics.go") }
 func TestBinaryUntypedConversion(t *testing.T) { checkOutputEqual(t, "operators/binary_untyped.go") }
 func TestShifts(t *testing.T)                  { checkOutputEqual(t, "operators/shifts.go") }
+
+// TestSoftFloat checks the results of floating point
+// operations implemented in software against gc's.
+func TestSoftFloat(t *testing.T) {
+	*softfloat = true
+	defer func() { *softfloat = false }()
+	checkOutputEqual(t, "operators/softfloat.go")
+}
+
+// softFloatInstr matches LLVM floating point instructions
+// that a soft-float module should not contain.
+var softFloatInstr = regexp.MustCompile(`= (fadd|fsub|fmul|fdiv|frem|fcmp|fptosi|fptoui|sitofp|uitofp|fptrunc|fpext) `)
+
+// vfpInstr matches ARM VFP instructions, and calls to the
+// EABI's floating point helpers.
+var vfpInstr = regexp.MustCompile(`\tv(add|sub|mul|div|neg|cmpe?|cvt|mov|ldr|str|ldm|stm|push|pop)[.a-z0-9]*\t|__aeabi_([df](add|sub|mul|div|cmp|2)|u?[il]2[df])`)
+
+// TestSoftFloatCrossTriple compiles a program and the runtime's Go
+// files with -softfloat for an ARM triple, and checks that neither
+// contains floating point instructions, before or after
+// code generation for a target with soft-float.
+func TestSoftFloatCrossTriple(t *testing.T) {
+	const armTriple = "armv7-unknown-linux-gnueabihf"
+	llvm.InitializeAllTargetInfos()
+	llvm.InitializeAllTargets()
+	llvm.InitializeAllTargetMCs()
+	if _, err := llvm.GetTargetFromTriple(armTriple); err != nil {
+		t.Skipf("ARM target unavailable: %v", err)
+	}
+
+	oldTriple := *triple
+	*triple, *softfloat = armTriple, true
+	defer func() { *triple, *softfloat = oldTriple, false }()
+	if flags := llgobuild.SoftFloatFlags(armTriple); !reflect.DeepEqual(flags, []string{"-mfloat-abi=soft"}) {
+		t.Errorf("clang is passed %q for %s", flags, armTriple)
+	}
+
+	compiler, err := initCompiler()
+	if err != nil {
+		t.Fatalf("Failed to initialise compiler: %s", err)
+	}
+	ctx, err := llgobuild.ContextFromTriple(armTriple)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg, err := ctx.Import("github.com/axw/llgo/pkg/runtime", "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var runtimeFiles []string
+	for _, name := range pkg.GoFiles {
+		runtimeFiles = append(runtimeFiles, filepath.Join(pkg.Dir, name))
+	}
+
+	var bcfiles []string
+	for _, p := range []struct {
+		name  string
+		files []string
+	}{
+		{"runtime", runtimeFiles},
+		{"main", testdata("operators/softfloat.go")},
+	} {
+		m, err := compileFiles(compiler, p.files, p.name)
+		if err != nil {
+			t.Fatalf("compileFiles(%s) failed: %s", p.name, err)
+		}
+		if err := llvm.VerifyModule(m.Module, llvm.ReturnStatusAction); err != nil {
+			m.Dispose()
+			t.Fatalf("%s: verification failed: %v", p.name, err)
+		}
+		bcpath := filepath.Join(tempdir, "softfloat_"+p.name+".bc")
+		f, err := os.Create(bcpath)
+		if err == nil {
+			err = llvm.WriteBitcodeToFile(m.Module, f)
+			f.Close()
+		}
+		m.Dispose()
+		if err != nil {
+			t.Fatal(err)
+		}
+		bcfiles = append(bcfiles, bcpath)
+	}
+
+	bcpath := filepath.Join(tempdir, "softfloat.bc")
+	args := append([]string{"-o", bcpath}, bcfiles...)
+	if out, err := exec.Command("llvm-link", args...).CombinedOutput(); err != nil {
+		t.Fatalf("llvm-link failed: %v\n%s", err, out)
+	}
+	ir, err := exec.Command("llvm-dis", "-o", "-", bcpath).CombinedOutput()
+	if err != nil {
+		t.Fatalf("llvm-dis failed: %v\n%s", err, ir)
+	}
+	if m := softFloatInstr.Find(ir); m != nil {
+		t.Errorf("soft-float module contains %q", m)
+	}
+
+	// Generate code as clang does with -mfloat-abi=soft.
+	llc, err := exec.LookPath("llc")
+	if err != nil {
+		t.Skip("llc not found")
+	}
+	asm, err := exec.Command(llc, "-mtriple="+armTriple, "-float-abi=soft", "-mattr=+soft-float", "-o", "-", bcpath).CombinedOutput()
+	if err != nil {
+		t.Fatalf("llc failed: %v\n%s", err, asm)
+	}
+	if m := vfpInstr.Find(asm); m != nil {
+		t.Errorf("soft-float code contains %q", m)
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// checkRaceReported compiles and runs the specified files with the
+// race detector enabled, and checks that a data race is reported.
+func checkRaceReported(t *testing.T, files ...string) {
+	*race = true
+	defer func() { *race = false }()
+	compiler, err := initCompiler()
+	if err != nil {
+		t.Fatalf("Failed to initialise compiler: %s", err)
+	}
+	m, err := compileFiles(compiler, testdata(files...), "main")
+	if err != nil {
+		t.Fatalf("compileFiles failed: %s", err)
+	}
+	output, err := runMainFunction(m)
+	if err == nil {
+		t.Fatalf("expected the race detector to fail the program:\n\t%s", strings.Join(output, "\n\t"))
+	}
+	if !strings.Contains(strings.Join(output, "\n"), "WARNING: DATA RACE") {
+		t.Fatalf("no data race reported: %s\n\t%s", err, strings.Join(output, "\n\t"))
+	}
+}
+
+func TestRaceVariable(t *testing.T) { checkRaceReported(t, "race/variable.go") }
+func TestRaceMap(t *testing.T)      { checkRaceReported(t, "race/map.go") }
+func TestRaceCopy(t *testing.T)     { checkRaceReported(t, "race/copy.go") }
+
+// checkRaceOutputEqual is checkOutputEqual with the race detector
+// enabled. checkOutputEqual sets testCompiler, so it is restored to a
+// compiler without the race detector for the tests that follow.
+func checkRaceOutputEqual(t *testing.T, files ...string) {
+	*race = true
+	defer func() {
+		*race = false
+		var err error
+		if testCompiler, err = initCompiler(); err != nil {
+			t.Fatalf("Failed to initialise compiler: %s", err)
+		}
+	}()
+	checkOutputEqual(t, files...)
+}
+
+func TestRaceRecover(t *testing.T) { checkRaceOutputEqual(t, "race/recover.go") }
+func TestRaceChan(t *testing.T)    { checkRaceOutputEqual(t, "race/chan.go") }
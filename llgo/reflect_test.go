@@ -0,0 +1,30 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	llgobuild "github.com/axw/llgo/build"
+)
+
+// TestReflectCall runs a program that calls functions, closures
+// and methods with reflect.Value.Call, and functions made with
+// reflect.MakeFunc, and panics if any result is wrong.
+func TestReflectCall(t *testing.T) {
+	root := llgobuild.PkgRoot(computeTriple(), false, false)
+	if _, err := os.Stat(filepath.Join(root, "reflect.bc")); err != nil {
+		t.Skip("reflect has not been installed with llgo-build")
+	}
+	compiler, err := initCompiler()
+	if err != nil {
+		t.Fatalf("Failed to initialise compiler: %s", err)
+	}
+	rc, err := runFiles(compiler, testdata("reflect/call.go"), nil)
+	if err != nil {
+		t.Fatalf("runFiles failed: %s", err)
+	}
+	if rc != 0 {
+		t.Errorf("got exit code %d, expected 0", rc)
+	}
+}
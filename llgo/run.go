@@ -0,0 +1,147 @@
+// Copyright 2014 The llgo Authors.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/axw/gollvm/llvm"
+	"github.com/axw/llgo"
+	llgobuild "github.com/axw/llgo/build"
+	"github.com/axw/llgo/jit"
+)
+
+// splitRunArgs splits the arguments to "llgo -run" into
+// the Go files to compile, and the arguments to pass to
+// the program, as "go run" does.
+func splitRunArgs(args []string) (files, progargs []string) {
+	i := 0
+	for i < len(args) && strings.HasSuffix(args[i], ".go") {
+		i++
+	}
+	return args[:i], args[i:]
+}
+
+// runDeps returns the import paths of the packages that
+// the files depend upon, directly or indirectly, in an
+// order such that each package precedes its importers.
+func runDeps(ctx *llgobuild.Context, filenames []string) ([]string, error) {
+	var deps []string
+	seen := map[string]bool{"unsafe": true}
+	var add func(imports []string) error
+	add = func(imports []string) error {
+		for _, path := range imports {
+			if path == "C" {
+				return fmt.Errorf("cgo is not supported with -run")
+			}
+			if seen[path] {
+				continue
+			}
+			seen[path] = true
+			pkg, err := ctx.Import(path, "", 0)
+			if err != nil {
+				return err
+			}
+			if err := add(pkg.Imports); err != nil {
+				return err
+			}
+			deps = append(deps, path)
+		}
+		return nil
+	}
+
+	// The runtime is implicitly imported by every package.
+	if err := add([]string{"runtime"}); err != nil {
+		return nil, err
+	}
+	fset := token.NewFileSet()
+	for _, filename := range filenames {
+		f, err := parser.ParseFile(fset, filename, nil, parser.ImportsOnly)
+		if err != nil {
+			return nil, err
+		}
+		for _, spec := range f.Imports {
+			path, err := strconv.Unquote(spec.Path.Value)
+			if err != nil {
+				return nil, err
+			}
+			if err := add([]string{path}); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return deps, nil
+}
+
+// linkRunDeps links the bitcode of each dependency,
+// installed by llgo-build, into the module.
+func linkRunDeps(module *llgo.Module, root string, deps []string) error {
+	for _, path := range deps {
+		bcfile := filepath.Join(root, path+".bc")
+		if _, err := os.Stat(bcfile); err != nil {
+			return fmt.Errorf("package %q is not installed; build it with llgo-build", path)
+		}
+		dep, err := llvm.ParseBitcodeFile(bcfile)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %v", bcfile, err)
+		}
+		if err := llvm.LinkModules(module.Module, dep, llvm.LinkerDestroySource); err != nil {
+			return fmt.Errorf("failed to link %s: %v", bcfile, err)
+		}
+	}
+	return nil
+}
+
+// runFiles compiles the files as package main, links the result
+// with the runtime and the packages it imports, and runs it with
+// the MCJIT execution engine. Symbols that are not defined by the
+// program, such as those of libc, are resolved in the llgo process.
+// The program's exit code is returned.
+func runFiles(compiler *llgo.Compiler, filenames, args []string) (int, error) {
+	triple := computeTriple()
+	ctx, err := llgobuild.ContextFromTriple(triple)
+	if err != nil {
+		return 0, err
+	}
+	if *race {
+		ctx.BuildTags = append(ctx.BuildTags, "race")
+	}
+	deps, err := runDeps(ctx, filenames)
+	if err != nil {
+		return 0, err
+	}
+
+	module, err := compiler.Compile(filenames, "main")
+	if err != nil {
+		return 0, err
+	}
+	root := llgobuild.PkgRoot(triple, *race, *softfloat)
+	if err := linkRunDeps(module, root, deps); err != nil {
+		module.Dispose()
+		return 0, err
+	}
+	if err := llgo.SetStringVars(module.Module, stringVars); err != nil {
+		module.Dispose()
+		return 0, err
+	}
+	if err := llvm.VerifyModule(module.Module, llvm.ReturnStatusAction); err != nil {
+		module.Dispose()
+		return 0, fmt.Errorf("Verification failed: %v", err)
+	}
+
+	// The JIT takes ownership of the module.
+	j := jit.New(ctx, root)
+	defer j.Dispose()
+	if err := j.Add(module.Module); err != nil {
+		return 0, err
+	}
+	return j.Main(append([]string{filenames[0]}, args...))
+}
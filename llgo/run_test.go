@@ -0,0 +1,48 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	llgobuild "github.com/axw/llgo/build"
+)
+
+func TestSplitRunArgs(t *testing.T) {
+	files, args := splitRunArgs([]string{"a.go", "b.go", "x", "c.go"})
+	if !reflect.DeepEqual(files, []string{"a.go", "b.go"}) {
+		t.Errorf("got files %v", files)
+	}
+	if !reflect.DeepEqual(args, []string{"x", "c.go"}) {
+		t.Errorf("got args %v", args)
+	}
+}
+
+// TestRunFiles runs a program with "llgo -run", which
+// panics unless its version is set with -X, and checks
+// the exit code in each case.
+func TestRunFiles(t *testing.T) {
+	root := llgobuild.PkgRoot(computeTriple(), false, false)
+	if _, err := os.Stat(filepath.Join(root, "runtime.bc")); err != nil {
+		t.Skip("the runtime has not been installed with llgo-build")
+	}
+	compiler, err := initCompiler()
+	if err != nil {
+		t.Fatalf("Failed to initialise compiler: %s", err)
+	}
+	file := testdata("run/version.go")
+	if rc, err := runFiles(compiler, file, nil); err != nil {
+		t.Fatalf("runFiles failed: %s", err)
+	} else if rc == 0 {
+		t.Errorf("got exit code 0, expected the program to panic")
+	}
+
+	stringVars["main.version"] = "1.0"
+	defer delete(stringVars, "main.version")
+	if rc, err := runFiles(compiler, file, nil); err != nil {
+		t.Fatalf("runFiles failed: %s", err)
+	} else if rc != 0 {
+		t.Errorf("got exit code %d, expected 0", rc)
+	}
+}
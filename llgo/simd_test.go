@@ -0,0 +1,31 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	llgobuild "github.com/axw/llgo/build"
+)
+
+// TestSIMDMethods runs a program that calls each of the simd
+// package's methods both directly, which lowers them to vector
+// instructions, and through interfaces, which calls their scalar
+// implementations, and panics if any of the results differ.
+func TestSIMDMethods(t *testing.T) {
+	root := llgobuild.PkgRoot(computeTriple(), false, false)
+	if _, err := os.Stat(filepath.Join(root, "github.com/axw/llgo/pkg/llgo/simd.bc")); err != nil {
+		t.Skip("simd has not been installed with llgo-build")
+	}
+	compiler, err := initCompiler()
+	if err != nil {
+		t.Fatalf("Failed to initialise compiler: %s", err)
+	}
+	rc, err := runFiles(compiler, testdata("simd/methods.go"), nil)
+	if err != nil {
+		t.Fatalf("runFiles failed: %s", err)
+	}
+	if rc != 0 {
+		t.Errorf("got exit code %d, expected 0", rc)
+	}
+}
@@ -15,10 +15,33 @@ func TestSwitchScope(t *testing.T)              { checkOutputEqual(t, "switch/sc
 func TestSwitchBranching(t *testing.T)          { checkOutputEqual(t, "switch/branch.go") }
 func TestSwitchStrings(t *testing.T)            { checkOutputEqual(t, "switch/strings.go") }
 func TestTypeSwitch(t *testing.T)               { checkOutputEqual(t, "switch/type.go") }
+func TestTypeSwitchHash(t *testing.T)           { checkOutputEqual(t, "switch/typehash.go") }
 func TestIfLazy(t *testing.T)                   { checkOutputEqual(t, "if/lazy.go") }
 func TestGoto(t *testing.T)                     { checkOutputEqual(t, "branching/goto.go") }
 func TestRecover(t *testing.T)                  { checkOutputEqual(t, "errors/recover.go") }
 func TestLabeledBranching(t *testing.T)         { checkOutputEqual(t, "branching/labeled.go") }
 func TestDefer(t *testing.T)                    { checkOutputEqual(t, "defer.go") }
 
+// TestTypeSwitchHashFallback checks that a type switch matches
+// a dynamic type whose descriptor is not the compiler's, and so
+// may not be found by its hash or address.
+func TestTypeSwitchHashFallback(t *testing.T) {
+	compiler, err := initCompiler()
+	if err != nil {
+		t.Fatalf("Failed to initialise compiler: %s", err)
+	}
+	m, err := compileFiles(compiler, testdata("switch/typehashcopy.go"), "main")
+	if err != nil {
+		t.Fatalf("compileFiles failed: %s", err)
+	}
+	output, err := runMainFunction(m)
+	if err != nil {
+		t.Fatalf("runMainFunction failed: %s", err)
+	}
+	expected := []string{"[]int 3", "[]int 3", "[]int 3", "default"}
+	if err := checkStringsEqual(output, expected); err != nil {
+		t.Fatal(err)
+	}
+}
+
 // vim: set ft=go:
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/axw/llgo"
+)
+
+func TestStringVars(t *testing.T) {
+	compiler, err := initCompiler()
+	if err != nil {
+		t.Fatalf("Failed to initialise compiler: %s", err)
+	}
+	m, err := compileFiles(compiler, testdata("stringvars/main.go"), "main")
+	if err != nil {
+		t.Fatalf("compileFiles failed: %s", err)
+	}
+	vars := map[string]string{
+		"main.version": "1.0",
+		"main.unset":   "set",
+		"main.named":   "y",
+	}
+	if err := llgo.SetStringVars(m.Module, vars); err != nil {
+		t.Fatalf("SetStringVars failed: %s", err)
+	}
+	output, err := runMainFunction(m)
+	if err != nil {
+		t.Fatalf("runMainFunction failed: %s", err)
+	}
+	expected := []string{"1.0", "set", "y", "1.0!", "untouched"}
+	if err := checkStringsEqual(output, expected); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStringVarsErrors(t *testing.T) {
+	compiler, err := initCompiler()
+	if err != nil {
+		t.Fatalf("Failed to initialise compiler: %s", err)
+	}
+	m, err := compileFiles(compiler, testdata("stringvars/main.go"), "main")
+	if err != nil {
+		t.Fatalf("compileFiles failed: %s", err)
+	}
+	defer m.Dispose()
+	for _, name := range []string{"main.missing", "main.main", "main.computed", "main.pair"} {
+		if err := llgo.SetStringVars(m.Module, map[string]string{name: "x"}); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
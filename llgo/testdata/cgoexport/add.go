@@ -0,0 +1,8 @@
+package main
+
+//export Add
+func Add(a, b int32) int32 {
+	return a + b
+}
+
+func main() {}
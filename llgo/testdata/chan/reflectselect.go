@@ -0,0 +1,33 @@
+package main
+
+import "reflect"
+
+func main() {
+	c := make(chan int, 1)
+	s := make(chan string, 1)
+	cases := []reflect.SelectCase{
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(c)},
+		{Dir: reflect.SelectSend, Chan: reflect.ValueOf(c), Send: reflect.ValueOf(123)},
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(s)},
+		{Dir: reflect.SelectDefault},
+	}
+	for i := 0; i < 3; i++ {
+		chosen, recv, recvOK := reflect.Select(cases)
+		switch chosen {
+		case 0:
+			println("received", recv.Int(), recvOK)
+			cases[0].Chan = reflect.ValueOf((chan int)(nil))
+		case 1:
+			println("sent a value")
+			cases[1].Chan = reflect.ValueOf((chan int)(nil))
+			s <- "hello"
+		case 2:
+			println("received", recv.String(), recvOK)
+		case 3:
+			println("default")
+		}
+	}
+	close(s)
+	chosen, recv, recvOK := reflect.Select(cases[2:3])
+	println(chosen, recv.String() == "", recvOK)
+}
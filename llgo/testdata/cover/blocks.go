@@ -0,0 +1,132 @@
+package main
+
+func classify(i int) string {
+	if i < 0 {
+		return "negative"
+	} else if i == 0 {
+		return "zero"
+	} else {
+		i++
+	}
+	switch {
+	case i < 10:
+		return "small"
+	case i < 100:
+	default:
+		return "large"
+	}
+	return "medium"
+}
+
+func sum(values []int) (total int) {
+	for _, v := range values {
+		if v < 0 {
+			continue
+		}
+		total += v
+	}
+	for i := 0; i < 3; i++ {
+	}
+	return
+}
+
+func typeName(x interface{}) string {
+	switch x.(type) {
+	case int:
+		return "int"
+	case string:
+		return "string"
+	}
+	return "other"
+}
+
+func selects(c chan int) int {
+	select {
+	case v := <-c:
+		return v
+	default:
+	}
+	return -1
+}
+
+func closure() func() int {
+	n := 0
+	f := func() int {
+		n++
+		return n
+	}
+	f()
+	return f
+}
+
+func recovered() (err interface{}) {
+	defer func() {
+		err = recover()
+	}()
+	panic("oops")
+}
+
+func labels() int {
+	n := 0
+outer:
+	for i := 0; i < 10; i++ {
+		for j := 0; j < 10; j++ {
+			if j == 3 {
+				continue outer
+			}
+			if i == 5 {
+				break outer
+			}
+			n++
+		}
+	}
+	{
+		n++
+	}
+	return n
+}
+
+func unused() {}
+
+func gaps(n int) int {
+	x := n
+
+	// A comment, and blank lines,
+	// separate ranges of code.
+
+	x *= 2
+	const (
+		a = 1
+
+		b = 2
+	)
+	if f := func() bool { return x > a+b }; f() {
+		x++
+	}
+	if n > 0 {
+		goto done
+	}
+	x--
+done:
+	x += 10
+	return x
+}
+
+func main() {
+	for _, i := range []int{-1, 0, 5, 50, 500} {
+		classify(i)
+	}
+	sum([]int{1, -2, 3})
+	typeName(1)
+	typeName(1.5)
+	c := make(chan int, 1)
+	selects(c)
+	c <- 1
+	selects(c)
+	closure()()
+	recovered()
+	labels()
+	gaps(1)
+	gaps(-1)
+	report()
+}
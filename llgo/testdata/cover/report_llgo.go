@@ -0,0 +1,29 @@
+package main
+
+type coverFile struct {
+	Name     string
+	Counters []uint32
+	Pos      []uint32
+	NumStmt  []uint16
+}
+
+// coverFiles is replaced by the test with the
+// variable named by build.CoverSymbol("main").
+//
+// #llgo name: llgo_test_coverfiles
+var coverFiles []coverFile
+
+// report prints the coverage blocks of blocks.go, as
+// instrumented by llgo.
+func report() {
+	const suffix = "/blocks.go"
+	for _, f := range coverFiles {
+		if len(f.Name) < len(suffix) || f.Name[len(f.Name)-len(suffix):] != suffix {
+			continue
+		}
+		for i := range f.Counters {
+			cols := f.Pos[3*i+2]
+			println(f.Pos[3*i], uint16(cols), f.Pos[3*i+1], uint16(cols>>16), f.NumStmt[i], f.Counters[i])
+		}
+	}
+}
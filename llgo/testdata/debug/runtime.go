@@ -0,0 +1,23 @@
+package main
+
+type stringer interface {
+	String() string
+}
+
+type name string
+
+func (n name) String() string {
+	return string(n)
+}
+
+// describe has parameters of each of the types
+// that are described by the runtime's layouts.
+func describe(m map[string]int, c chan int, e interface{}, s stringer, b []byte) {
+	println(len(m), m["one"], len(c), cap(c), e.(int), s.String(), string(b))
+}
+
+func main() {
+	c := make(chan int, 2)
+	c <- 1
+	describe(map[string]int{"one": 1}, c, 2, name("three"), []byte("four"))
+}
@@ -0,0 +1,52 @@
+package main
+
+// The variables below are held in registers, and are
+// described by llvm.dbg.value calls; those updated in
+// loops are phis in the loop headers, which follow
+// the loop bodies.
+
+func sum(n int) int {
+	total := 0
+	for i := 0; i < n; i++ {
+		total += i
+	}
+	return total
+}
+
+func fib(n int) int {
+	a, b := 0, 1
+	for n > 0 {
+		a, b = b, a+b
+		n--
+	}
+	return a
+}
+
+func count(s string) (letters, others int) {
+	for _, c := range s {
+		switch {
+		case c >= 'a' && c <= 'z':
+			letters++
+		default:
+			others++
+		}
+	}
+	return
+}
+
+func main() {
+	println(sum(10), fib(10))
+	println(count("debug values!"))
+	f := func(x int) int {
+		y := x
+		for y > 1 {
+			if y%2 == 0 {
+				y /= 2
+			} else {
+				y = 3*y + 1
+			}
+		}
+		return y
+	}
+	println(f(27))
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"math"
+	"sync/atomic"
+	"unsafe"
+)
+
+func testMath() {
+	for _, x := range []float64{2.5, -2.5, 16, 0.1} {
+		println(math.Abs(x), math.Floor(x), math.Ceil(x), math.Trunc(x))
+		println(math.Copysign(3, x), math.Sqrt(x))
+	}
+	println(math.Sqrt(math.Inf(1)), math.IsNaN(math.Sqrt(-1)))
+
+	// Function values refer to the out-of-line definitions.
+	fns := []func(float64) float64{math.Sqrt, math.Floor, math.Abs}
+	for _, f := range fns {
+		println(f(-9), f(9))
+	}
+}
+
+func testAtomic() {
+	var i32 int32
+	println(atomic.AddInt32(&i32, 5), atomic.AddInt32(&i32, -7), atomic.LoadInt32(&i32))
+	println(atomic.CompareAndSwapInt32(&i32, 0, 1), atomic.CompareAndSwapInt32(&i32, -2, 1), i32)
+	println(atomic.SwapInt32(&i32, 9), i32)
+
+	var u64 uint64
+	atomic.StoreUint64(&u64, 1<<40)
+	println(atomic.AddUint64(&u64, 1), atomic.LoadUint64(&u64))
+	println(atomic.CompareAndSwapUint64(&u64, 1<<40+1, 2), atomic.SwapUint64(&u64, 3), u64)
+
+	var uptr uintptr
+	println(atomic.AddUintptr(&uptr, 10), atomic.LoadUintptr(&uptr))
+
+	x, y := 1, 2
+	p := unsafe.Pointer(&x)
+	println(atomic.CompareAndSwapPointer(&p, unsafe.Pointer(&y), nil), *(*int)(atomic.LoadPointer(&p)))
+	atomic.StorePointer(&p, unsafe.Pointer(&y))
+	println(*(*int)(atomic.SwapPointer(&p, nil)), p == nil)
+
+	add := atomic.AddInt64
+	var i64 int64
+	add(&i64, 3)
+	println(add(&i64, 4))
+}
+
+func main() {
+	testMath()
+	testAtomic()
+}
@@ -0,0 +1,55 @@
+package main
+
+type T struct{ x int }
+
+func (t T) get() int { return t.x }
+
+type getter interface {
+	get() int
+}
+
+func shadow(x int) int {
+	sum := x
+	for i := 0; i < 3; i++ {
+		x := i * 2
+		if x > 1 {
+			x := x + 1
+			sum += x
+		} else {
+			sum -= x
+		}
+	}
+	switch y := sum % 2; y {
+	case 0:
+		x := "even"
+		println(x)
+	default:
+		x := "odd"
+		println(x)
+	}
+	return sum + x
+}
+
+func closure(n int) func() int {
+	total := 0
+	return func() int {
+		for i := 0; i < n; i++ {
+			j := i
+			total += j
+		}
+		return total
+	}
+}
+
+func main() {
+	println(shadow(10))
+	f := closure(4)
+	println(f(), f())
+
+	// Calls through the interface and the pointer method
+	// set use synthetic wrappers.
+	var g getter = &T{7}
+	println(g.get())
+	h := T.get
+	println(h(T{8}))
+}
@@ -0,0 +1,68 @@
+package main
+
+type Small int
+
+func (s Small) Zeta() int  { return int(s) }
+func (s Small) Alpha() int { return -int(s) }
+func (s Small) mid() int   { return 2 * int(s) }
+
+type Large struct{ a, b, c int }
+
+func (l Large) Zeta() int   { return l.a + l.b + l.c }
+func (l Large) Alpha() int  { return l.a }
+func (l *Large) Beta() int  { return l.b }
+func (l Large) mid() int    { return l.c }
+func (l Large) Other() bool { return true }
+
+type ZA interface {
+	Zeta() int
+	Alpha() int
+}
+
+type ZAM interface {
+	mid() int
+	Alpha() int
+	Zeta() int
+}
+
+type B interface {
+	Beta() int
+}
+
+func describe(x interface{}) {
+	if i, ok := x.(ZAM); ok {
+		println("ZAM:", i.Alpha(), i.mid(), i.Zeta())
+	} else if i, ok := x.(ZA); ok {
+		println("ZA:", i.Alpha(), i.Zeta())
+	} else {
+		println("neither")
+	}
+	if i, ok := x.(B); ok {
+		println("B:", i.Beta())
+	}
+}
+
+func main() {
+	// Static conversions.
+	var za ZA = Small(3)
+	println(za.Alpha(), za.Zeta())
+	var zam ZAM = Large{1, 2, 3}
+	println(zam.Alpha(), zam.mid(), zam.Zeta())
+	var b B = &Large{4, 5, 6}
+	println(b.Beta())
+
+	// Dynamic conversions, repeated so
+	// that the cached itabs are used.
+	for i := 0; i < 2; i++ {
+		describe(Small(i))
+		describe(Large{i, i + 1, i + 2})
+		describe(&Large{i, i + 1, i + 2})
+		describe(i)
+	}
+
+	// Interface to interface conversions.
+	za = zam
+	println(za.Alpha(), za.Zeta())
+	_, ok := za.(B)
+	println(ok)
+}
@@ -0,0 +1,65 @@
+package main
+
+func arith64(x, y float64) {
+	println(x+y, x-y, x*y, x/y, -x)
+	println(x == y, x != y, x < y, x <= y, x > y, x >= y)
+}
+
+func arith32(x, y float32) {
+	println(x+y, x-y, x*y, x/y, -x)
+	println(x == y, x != y, x < y, x <= y, x > y, x >= y)
+}
+
+func main() {
+	var zero float64
+	nan := zero / zero
+	inf := 1 / zero
+
+	for _, y := range []float64{1.5, -2.25, 1e300, 1e-300, 0.1, inf, nan} {
+		arith64(3.75, y)
+		arith64(y, y)
+	}
+	for _, y := range []float32{1.5, -2.25, 1e38, 1e-38, 0.1, float32(inf), float32(nan)} {
+		arith32(3.75, y)
+		arith32(y, y)
+	}
+
+	// float <-> float
+	for _, f := range []float64{0.1, 1e39, 1e-46, 16777217, -inf} {
+		println(float32(f), float64(float32(f)))
+	}
+
+	// float -> int
+	for _, f := range []float64{0.9, -0.9, 123.456, -123.456} {
+		println(int8(f), int16(f), int32(f), int64(f))
+	}
+	for _, f := range []float64{-2147483648.5, 4294967295.5, 1 << 62, -1 << 63} {
+		println(int64(f))
+	}
+	for _, f := range []float64{0.9, 123.456} {
+		println(uint8(f), uint16(f), uint32(f), uint64(f))
+	}
+	for _, f := range []float64{4294967295.5, 1 << 62, 1 << 63, 18446744073709549568} {
+		println(uint64(f))
+	}
+	for _, f := range []float32{123.456, -123.456} {
+		println(int32(f), int64(f))
+	}
+	for _, f := range []float32{123.456, 1 << 40, 1 << 63} {
+		println(uint64(f))
+	}
+
+	// int -> float
+	for _, i := range []int64{0, 1, -1, 1<<53 + 1, 1<<60 + 1<<36 + 1, -1 << 63, 1<<63 - 1} {
+		println(float32(i), float64(i), float32(int8(i)), float64(int32(i)))
+	}
+	for _, u := range []uint64{0, 1, 1<<53 + 1, 1 << 63, 1<<63 + 1025, 1<<63 + 1<<39 + 1, 1<<64 - 1} {
+		println(float32(u), float64(u), float32(uint8(u)), float64(uint32(u)))
+	}
+
+	// complex
+	a, b := complex(1.5, -2), complex(zero, 2)
+	println(a+b, a-b, a*b, a/b, a == b, a != b)
+	c64 := complex64(a)
+	println(c64*c64, complex128(c64/complex64(b)))
+}
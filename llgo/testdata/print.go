@@ -0,0 +1,21 @@
+package main
+
+type T int
+
+func main() {
+	print("a", 1, "b", 2.5, true, "\n")
+	println(int8(-128), uint8(255), int64(-1)<<63, ^uint64(0))
+	println(float32(1.25), -0.1, 1e100, 0.0)
+	println(complex(1, -2), complex64(complex(-0.5, 0.5)))
+	println(T(42), uintptr(7), false)
+
+	var s []int
+	var p *int
+	var m map[int]int
+	var c chan int
+	var e interface{}
+	var err error
+	println(s, p, m, c, e, err)
+	println()
+	print()
+}
@@ -0,0 +1,52 @@
+package main
+
+// Each channel operation orders the accesses to x,
+// so the race detector should not report any races.
+
+var x int
+
+// A receive from an unbuffered channel happens
+// before the send on that channel completes.
+func recvBeforeSend() {
+	c := make(chan bool)
+	go func() {
+		x++
+		<-c
+	}()
+	c <- true
+	x++
+}
+
+// A send on a channel happens before
+// the corresponding receive completes.
+func sendBeforeRecv(c chan bool) {
+	go func() {
+		x++
+		c <- true
+	}()
+	<-c
+	x++
+}
+
+// The kth receive on a channel with capacity C
+// happens before the k+Cth send completes.
+func semaphore() {
+	sem := make(chan bool, 1)
+	sem <- true
+	go func() {
+		x++
+		<-sem
+	}()
+	sem <- true
+	x++
+}
+
+func main() {
+	for i := 0; i < 100; i++ {
+		recvBeforeSend()
+		sendBeforeRecv(make(chan bool))
+		sendBeforeRecv(make(chan bool, 1))
+		semaphore()
+	}
+	println(x)
+}
@@ -0,0 +1,13 @@
+package main
+
+func main() {
+	s := make([]int, 8)
+	done := make(chan bool)
+	go func() {
+		s = append(s[:4], 1, 2, 3, 4)
+		done <- true
+	}()
+	t := make([]int, 8)
+	println(copy(t, s[:cap(s)]))
+	<-done
+}
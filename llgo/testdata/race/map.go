@@ -0,0 +1,12 @@
+package main
+
+func main() {
+	m := make(map[int]int)
+	done := make(chan bool)
+	go func() {
+		m[1] = 1
+		done <- true
+	}()
+	println(len(m), m[1])
+	<-done
+}
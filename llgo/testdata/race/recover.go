@@ -0,0 +1,27 @@
+package main
+
+// Each recovered panic unwinds the frame of f. If the frames
+// were not popped from the race detector's shadow stack, the
+// stack would overflow.
+
+func f(i int) int {
+	if i >= 0 {
+		panic(i)
+	}
+	return i
+}
+
+func g(i int) (result int) {
+	defer func() {
+		result = recover().(int)
+	}()
+	return f(i)
+}
+
+func main() {
+	var sum int
+	for i := 0; i < 200000; i++ {
+		sum += g(i)
+	}
+	println(sum)
+}
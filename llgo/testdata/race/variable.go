@@ -0,0 +1,14 @@
+package main
+
+var x int
+
+func main() {
+	done := make(chan bool)
+	go func() {
+		x = 1
+		done <- true
+	}()
+	x = 2
+	<-done
+	println(x)
+}
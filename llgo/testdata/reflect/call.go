@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+type small int
+
+func (s small) Add(n int) int { return int(s) + n }
+
+type large struct {
+	a, b, c int
+}
+
+func (l large) Sum(scale int) (int, string) {
+	return (l.a + l.b + l.c) * scale, "sum"
+}
+
+func (l *large) Set(a int) { l.a = a }
+
+type adder interface {
+	Add(int) int
+}
+
+func check(what string, got, expected interface{}) {
+	if !reflect.DeepEqual(got, expected) {
+		panic(fmt.Sprintf("%s: got %v, expected %v", what, got, expected))
+	}
+}
+
+func call(fn interface{}, args ...interface{}) []interface{} {
+	in := make([]reflect.Value, len(args))
+	for i, arg := range args {
+		in[i] = reflect.ValueOf(arg)
+	}
+	var out []interface{}
+	for _, v := range reflect.ValueOf(fn).Call(in) {
+		out = append(out, v.Interface())
+	}
+	return out
+}
+
+func divmod(a, b int) (int, int) { return a / b, a % b }
+
+func join(sep string, parts ...string) string { return strings.Join(parts, sep) }
+
+func main() {
+	// Functions, with several results.
+	check("divmod", call(divmod, 7, 2), []interface{}{3, 1})
+
+	// Closures.
+	n := 10
+	add := func(x int8, y float64, s string) string {
+		return fmt.Sprint(int(x)+n, y, s)
+	}
+	check("closure", call(add, int8(1), 2.5, "x"), []interface{}{"11 2.5x"})
+
+	// Variadic functions, with Call and CallSlice.
+	check("variadic", call(join, ",", "a", "b", "c"), []interface{}{"a,b,c"})
+	slice := reflect.ValueOf([]string{"d", "e"})
+	out := reflect.ValueOf(join).CallSlice([]reflect.Value{reflect.ValueOf("+"), slice})
+	check("CallSlice", out[0].String(), "d+e")
+
+	// Method values, of values stored directly and
+	// indirectly in interfaces, and of interfaces.
+	check("small", call(reflect.ValueOf(small(2)).Method(0).Interface(), 3), []interface{}{5})
+	l := &large{1, 2, 3}
+	out = reflect.ValueOf(*l).MethodByName("Sum").Call([]reflect.Value{reflect.ValueOf(2)})
+	check("large", []interface{}{out[0].Int(), out[1].String()}, []interface{}{int64(12), "sum"})
+	reflect.ValueOf(l).MethodByName("Set").Call([]reflect.Value{reflect.ValueOf(7)})
+	check("pointer", l.a, 7)
+	var a adder = small(4)
+	iface := reflect.ValueOf(&a).Elem()
+	check("interface", iface.Method(0).Call([]reflect.Value{reflect.ValueOf(1)})[0].Int(), int64(5))
+
+	// Method expressions, as net/rpc calls them.
+	m, _ := reflect.TypeOf(small(0)).MethodByName("Add")
+	out = m.Func.Call([]reflect.Value{reflect.ValueOf(small(5)), reflect.ValueOf(6)})
+	check("Method.Func", out[0].Int(), int64(11))
+
+	// Functions made by MakeFunc, called directly
+	// and with Call.
+	var swap func(int, string) (string, int)
+	swapImpl := func(in []reflect.Value) []reflect.Value {
+		return []reflect.Value{in[1], in[0]}
+	}
+	reflect.ValueOf(&swap).Elem().Set(reflect.MakeFunc(reflect.TypeOf(swap), swapImpl))
+	s, i := swap(1, "one")
+	check("swap", []interface{}{s, i}, []interface{}{"one", 1})
+	check("swap Call", call(swap, 2, "two"), []interface{}{"two", 2})
+
+	var sum func(...float64) float64
+	sumImpl := func(in []reflect.Value) []reflect.Value {
+		var total float64
+		for i := 0; i < in[0].Len(); i++ {
+			total += in[0].Index(i).Float()
+		}
+		return []reflect.Value{reflect.ValueOf(total)}
+	}
+	reflect.ValueOf(&sum).Elem().Set(reflect.MakeFunc(reflect.TypeOf(sum), sumImpl))
+	check("sum", sum(1, 2, 3.5), 6.5)
+
+	fmt.Println("ok")
+}
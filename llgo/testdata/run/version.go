@@ -0,0 +1,9 @@
+package main
+
+var version = "devel"
+
+func main() {
+	if version != "1.0" {
+		panic("version is " + version)
+	}
+}
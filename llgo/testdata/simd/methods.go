@@ -0,0 +1,456 @@
+// The simd package's methods are lowered to vector instructions when
+// called directly, and implemented with scalar code otherwise, as they
+// are with gc. This program checks that each lowered method returns
+// what the scalar implementation does, by calling it through an
+// interface, including on vectors stored in memory allocated by the
+// runtime, which may be less aligned than the vectors' types are.
+package main
+
+import "github.com/axw/llgo/pkg/llgo/simd"
+
+var failed bool
+
+func check(name string, ok bool) {
+	if !ok {
+		println("FAIL:", name)
+		failed = true
+	}
+}
+
+type float32x4 interface {
+	Add(simd.Float32x4) simd.Float32x4
+	Sub(simd.Float32x4) simd.Float32x4
+	Mul(simd.Float32x4) simd.Float32x4
+	Div(simd.Float32x4) simd.Float32x4
+	Min(simd.Float32x4) simd.Float32x4
+	Max(simd.Float32x4) simd.Float32x4
+	HAdd() float32
+	Shuffle(simd.Float32x4, [4]int) simd.Float32x4
+}
+
+var float32x4Mask = [4]int{7, 4, 1, 6}
+
+func testFloat32x4(a, b simd.Float32x4) {
+	var x float32x4 = a
+	check("Float32x4.Add", [4]float32(a.Add(b)) == [4]float32(x.Add(b)))
+	check("Float32x4.Sub", [4]float32(a.Sub(b)) == [4]float32(x.Sub(b)))
+	check("Float32x4.Mul", [4]float32(a.Mul(b)) == [4]float32(x.Mul(b)))
+	check("Float32x4.Div", [4]float32(a.Div(b)) == [4]float32(x.Div(b)))
+	check("Float32x4.Min", [4]float32(a.Min(b)) == [4]float32(x.Min(b)))
+	check("Float32x4.Max", [4]float32(a.Max(b)) == [4]float32(x.Max(b)))
+	check("Float32x4.HAdd", a.HAdd() == x.HAdd())
+	check("Float32x4.Shuffle", [4]float32(a.Shuffle(b, [4]int{3, 0, 5, 2})) == [4]float32(x.Shuffle(b, [4]int{3, 0, 5, 2})))
+	check("Float32x4.Shuffle (mask variable)", [4]float32(a.Shuffle(b, float32x4Mask)) == [4]float32(x.Shuffle(b, float32x4Mask)))
+	check("Float32x4 ==", (a == b) == ([4]float32(a) == [4]float32(b)) && a == x.(simd.Float32x4))
+	check("Float32x4 !=", (a != b) == ([4]float32(a) != [4]float32(b)) && !(a != x.(simd.Float32x4)))
+}
+
+type float32x8 interface {
+	Add(simd.Float32x8) simd.Float32x8
+	Sub(simd.Float32x8) simd.Float32x8
+	Mul(simd.Float32x8) simd.Float32x8
+	Div(simd.Float32x8) simd.Float32x8
+	Min(simd.Float32x8) simd.Float32x8
+	Max(simd.Float32x8) simd.Float32x8
+	HAdd() float32
+	Shuffle(simd.Float32x8, [8]int) simd.Float32x8
+}
+
+var float32x8Mask = [8]int{15, 12, 9, 6, 3, 0, 13, 10}
+
+func testFloat32x8(a, b simd.Float32x8) {
+	var x float32x8 = a
+	check("Float32x8.Add", [8]float32(a.Add(b)) == [8]float32(x.Add(b)))
+	check("Float32x8.Sub", [8]float32(a.Sub(b)) == [8]float32(x.Sub(b)))
+	check("Float32x8.Mul", [8]float32(a.Mul(b)) == [8]float32(x.Mul(b)))
+	check("Float32x8.Div", [8]float32(a.Div(b)) == [8]float32(x.Div(b)))
+	check("Float32x8.Min", [8]float32(a.Min(b)) == [8]float32(x.Min(b)))
+	check("Float32x8.Max", [8]float32(a.Max(b)) == [8]float32(x.Max(b)))
+	check("Float32x8.HAdd", a.HAdd() == x.HAdd())
+	check("Float32x8.Shuffle", [8]float32(a.Shuffle(b, [8]int{3, 8, 13, 2, 7, 12, 1, 6})) == [8]float32(x.Shuffle(b, [8]int{3, 8, 13, 2, 7, 12, 1, 6})))
+	check("Float32x8.Shuffle (mask variable)", [8]float32(a.Shuffle(b, float32x8Mask)) == [8]float32(x.Shuffle(b, float32x8Mask)))
+	check("Float32x8 ==", (a == b) == ([8]float32(a) == [8]float32(b)) && a == x.(simd.Float32x8))
+	check("Float32x8 !=", (a != b) == ([8]float32(a) != [8]float32(b)) && !(a != x.(simd.Float32x8)))
+}
+
+type float64x2 interface {
+	Add(simd.Float64x2) simd.Float64x2
+	Sub(simd.Float64x2) simd.Float64x2
+	Mul(simd.Float64x2) simd.Float64x2
+	Div(simd.Float64x2) simd.Float64x2
+	Min(simd.Float64x2) simd.Float64x2
+	Max(simd.Float64x2) simd.Float64x2
+	HAdd() float64
+	Shuffle(simd.Float64x2, [2]int) simd.Float64x2
+}
+
+var float64x2Mask = [2]int{3, 0}
+
+func testFloat64x2(a, b simd.Float64x2) {
+	var x float64x2 = a
+	check("Float64x2.Add", [2]float64(a.Add(b)) == [2]float64(x.Add(b)))
+	check("Float64x2.Sub", [2]float64(a.Sub(b)) == [2]float64(x.Sub(b)))
+	check("Float64x2.Mul", [2]float64(a.Mul(b)) == [2]float64(x.Mul(b)))
+	check("Float64x2.Div", [2]float64(a.Div(b)) == [2]float64(x.Div(b)))
+	check("Float64x2.Min", [2]float64(a.Min(b)) == [2]float64(x.Min(b)))
+	check("Float64x2.Max", [2]float64(a.Max(b)) == [2]float64(x.Max(b)))
+	check("Float64x2.HAdd", a.HAdd() == x.HAdd())
+	check("Float64x2.Shuffle", [2]float64(a.Shuffle(b, [2]int{3, 0})) == [2]float64(x.Shuffle(b, [2]int{3, 0})))
+	check("Float64x2.Shuffle (mask variable)", [2]float64(a.Shuffle(b, float64x2Mask)) == [2]float64(x.Shuffle(b, float64x2Mask)))
+	check("Float64x2 ==", (a == b) == ([2]float64(a) == [2]float64(b)) && a == x.(simd.Float64x2))
+	check("Float64x2 !=", (a != b) == ([2]float64(a) != [2]float64(b)) && !(a != x.(simd.Float64x2)))
+}
+
+type float64x4 interface {
+	Add(simd.Float64x4) simd.Float64x4
+	Sub(simd.Float64x4) simd.Float64x4
+	Mul(simd.Float64x4) simd.Float64x4
+	Div(simd.Float64x4) simd.Float64x4
+	Min(simd.Float64x4) simd.Float64x4
+	Max(simd.Float64x4) simd.Float64x4
+	HAdd() float64
+	Shuffle(simd.Float64x4, [4]int) simd.Float64x4
+}
+
+var float64x4Mask = [4]int{7, 4, 1, 6}
+
+func testFloat64x4(a, b simd.Float64x4) {
+	var x float64x4 = a
+	check("Float64x4.Add", [4]float64(a.Add(b)) == [4]float64(x.Add(b)))
+	check("Float64x4.Sub", [4]float64(a.Sub(b)) == [4]float64(x.Sub(b)))
+	check("Float64x4.Mul", [4]float64(a.Mul(b)) == [4]float64(x.Mul(b)))
+	check("Float64x4.Div", [4]float64(a.Div(b)) == [4]float64(x.Div(b)))
+	check("Float64x4.Min", [4]float64(a.Min(b)) == [4]float64(x.Min(b)))
+	check("Float64x4.Max", [4]float64(a.Max(b)) == [4]float64(x.Max(b)))
+	check("Float64x4.HAdd", a.HAdd() == x.HAdd())
+	check("Float64x4.Shuffle", [4]float64(a.Shuffle(b, [4]int{3, 0, 5, 2})) == [4]float64(x.Shuffle(b, [4]int{3, 0, 5, 2})))
+	check("Float64x4.Shuffle (mask variable)", [4]float64(a.Shuffle(b, float64x4Mask)) == [4]float64(x.Shuffle(b, float64x4Mask)))
+	check("Float64x4 ==", (a == b) == ([4]float64(a) == [4]float64(b)) && a == x.(simd.Float64x4))
+	check("Float64x4 !=", (a != b) == ([4]float64(a) != [4]float64(b)) && !(a != x.(simd.Float64x4)))
+}
+
+type int8x16 interface {
+	Add(simd.Int8x16) simd.Int8x16
+	Sub(simd.Int8x16) simd.Int8x16
+	Mul(simd.Int8x16) simd.Int8x16
+	And(simd.Int8x16) simd.Int8x16
+	Or(simd.Int8x16) simd.Int8x16
+	Xor(simd.Int8x16) simd.Int8x16
+	Min(simd.Int8x16) simd.Int8x16
+	Max(simd.Int8x16) simd.Int8x16
+	HAdd() int8
+	Shuffle(simd.Int8x16, [16]int) simd.Int8x16
+}
+
+var int8x16Mask = [16]int{31, 28, 25, 22, 19, 16, 13, 10, 7, 4, 1, 30, 27, 24, 21, 18}
+
+func testInt8x16(a, b simd.Int8x16) {
+	var x int8x16 = a
+	check("Int8x16.Add", [16]int8(a.Add(b)) == [16]int8(x.Add(b)))
+	check("Int8x16.Sub", [16]int8(a.Sub(b)) == [16]int8(x.Sub(b)))
+	check("Int8x16.Mul", [16]int8(a.Mul(b)) == [16]int8(x.Mul(b)))
+	check("Int8x16.And", [16]int8(a.And(b)) == [16]int8(x.And(b)))
+	check("Int8x16.Or", [16]int8(a.Or(b)) == [16]int8(x.Or(b)))
+	check("Int8x16.Xor", [16]int8(a.Xor(b)) == [16]int8(x.Xor(b)))
+	check("Int8x16.Min", [16]int8(a.Min(b)) == [16]int8(x.Min(b)))
+	check("Int8x16.Max", [16]int8(a.Max(b)) == [16]int8(x.Max(b)))
+	check("Int8x16.HAdd", a.HAdd() == x.HAdd())
+	check("Int8x16.Shuffle", [16]int8(a.Shuffle(b, [16]int{3, 8, 13, 18, 23, 28, 1, 6, 11, 16, 21, 26, 31, 4, 9, 14})) == [16]int8(x.Shuffle(b, [16]int{3, 8, 13, 18, 23, 28, 1, 6, 11, 16, 21, 26, 31, 4, 9, 14})))
+	check("Int8x16.Shuffle (mask variable)", [16]int8(a.Shuffle(b, int8x16Mask)) == [16]int8(x.Shuffle(b, int8x16Mask)))
+	check("Int8x16 ==", (a == b) == ([16]int8(a) == [16]int8(b)) && a == x.(simd.Int8x16))
+	check("Int8x16 !=", (a != b) == ([16]int8(a) != [16]int8(b)) && !(a != x.(simd.Int8x16)))
+}
+
+type int16x8 interface {
+	Add(simd.Int16x8) simd.Int16x8
+	Sub(simd.Int16x8) simd.Int16x8
+	Mul(simd.Int16x8) simd.Int16x8
+	And(simd.Int16x8) simd.Int16x8
+	Or(simd.Int16x8) simd.Int16x8
+	Xor(simd.Int16x8) simd.Int16x8
+	Min(simd.Int16x8) simd.Int16x8
+	Max(simd.Int16x8) simd.Int16x8
+	HAdd() int16
+	Shuffle(simd.Int16x8, [8]int) simd.Int16x8
+}
+
+var int16x8Mask = [8]int{15, 12, 9, 6, 3, 0, 13, 10}
+
+func testInt16x8(a, b simd.Int16x8) {
+	var x int16x8 = a
+	check("Int16x8.Add", [8]int16(a.Add(b)) == [8]int16(x.Add(b)))
+	check("Int16x8.Sub", [8]int16(a.Sub(b)) == [8]int16(x.Sub(b)))
+	check("Int16x8.Mul", [8]int16(a.Mul(b)) == [8]int16(x.Mul(b)))
+	check("Int16x8.And", [8]int16(a.And(b)) == [8]int16(x.And(b)))
+	check("Int16x8.Or", [8]int16(a.Or(b)) == [8]int16(x.Or(b)))
+	check("Int16x8.Xor", [8]int16(a.Xor(b)) == [8]int16(x.Xor(b)))
+	check("Int16x8.Min", [8]int16(a.Min(b)) == [8]int16(x.Min(b)))
+	check("Int16x8.Max", [8]int16(a.Max(b)) == [8]int16(x.Max(b)))
+	check("Int16x8.HAdd", a.HAdd() == x.HAdd())
+	check("Int16x8.Shuffle", [8]int16(a.Shuffle(b, [8]int{3, 8, 13, 2, 7, 12, 1, 6})) == [8]int16(x.Shuffle(b, [8]int{3, 8, 13, 2, 7, 12, 1, 6})))
+	check("Int16x8.Shuffle (mask variable)", [8]int16(a.Shuffle(b, int16x8Mask)) == [8]int16(x.Shuffle(b, int16x8Mask)))
+	check("Int16x8 ==", (a == b) == ([8]int16(a) == [8]int16(b)) && a == x.(simd.Int16x8))
+	check("Int16x8 !=", (a != b) == ([8]int16(a) != [8]int16(b)) && !(a != x.(simd.Int16x8)))
+}
+
+type int32x4 interface {
+	Add(simd.Int32x4) simd.Int32x4
+	Sub(simd.Int32x4) simd.Int32x4
+	Mul(simd.Int32x4) simd.Int32x4
+	And(simd.Int32x4) simd.Int32x4
+	Or(simd.Int32x4) simd.Int32x4
+	Xor(simd.Int32x4) simd.Int32x4
+	Min(simd.Int32x4) simd.Int32x4
+	Max(simd.Int32x4) simd.Int32x4
+	HAdd() int32
+	Shuffle(simd.Int32x4, [4]int) simd.Int32x4
+}
+
+var int32x4Mask = [4]int{7, 4, 1, 6}
+
+func testInt32x4(a, b simd.Int32x4) {
+	var x int32x4 = a
+	check("Int32x4.Add", [4]int32(a.Add(b)) == [4]int32(x.Add(b)))
+	check("Int32x4.Sub", [4]int32(a.Sub(b)) == [4]int32(x.Sub(b)))
+	check("Int32x4.Mul", [4]int32(a.Mul(b)) == [4]int32(x.Mul(b)))
+	check("Int32x4.And", [4]int32(a.And(b)) == [4]int32(x.And(b)))
+	check("Int32x4.Or", [4]int32(a.Or(b)) == [4]int32(x.Or(b)))
+	check("Int32x4.Xor", [4]int32(a.Xor(b)) == [4]int32(x.Xor(b)))
+	check("Int32x4.Min", [4]int32(a.Min(b)) == [4]int32(x.Min(b)))
+	check("Int32x4.Max", [4]int32(a.Max(b)) == [4]int32(x.Max(b)))
+	check("Int32x4.HAdd", a.HAdd() == x.HAdd())
+	check("Int32x4.Shuffle", [4]int32(a.Shuffle(b, [4]int{3, 0, 5, 2})) == [4]int32(x.Shuffle(b, [4]int{3, 0, 5, 2})))
+	check("Int32x4.Shuffle (mask variable)", [4]int32(a.Shuffle(b, int32x4Mask)) == [4]int32(x.Shuffle(b, int32x4Mask)))
+	check("Int32x4 ==", (a == b) == ([4]int32(a) == [4]int32(b)) && a == x.(simd.Int32x4))
+	check("Int32x4 !=", (a != b) == ([4]int32(a) != [4]int32(b)) && !(a != x.(simd.Int32x4)))
+}
+
+type int32x8 interface {
+	Add(simd.Int32x8) simd.Int32x8
+	Sub(simd.Int32x8) simd.Int32x8
+	Mul(simd.Int32x8) simd.Int32x8
+	And(simd.Int32x8) simd.Int32x8
+	Or(simd.Int32x8) simd.Int32x8
+	Xor(simd.Int32x8) simd.Int32x8
+	Min(simd.Int32x8) simd.Int32x8
+	Max(simd.Int32x8) simd.Int32x8
+	HAdd() int32
+	Shuffle(simd.Int32x8, [8]int) simd.Int32x8
+}
+
+var int32x8Mask = [8]int{15, 12, 9, 6, 3, 0, 13, 10}
+
+func testInt32x8(a, b simd.Int32x8) {
+	var x int32x8 = a
+	check("Int32x8.Add", [8]int32(a.Add(b)) == [8]int32(x.Add(b)))
+	check("Int32x8.Sub", [8]int32(a.Sub(b)) == [8]int32(x.Sub(b)))
+	check("Int32x8.Mul", [8]int32(a.Mul(b)) == [8]int32(x.Mul(b)))
+	check("Int32x8.And", [8]int32(a.And(b)) == [8]int32(x.And(b)))
+	check("Int32x8.Or", [8]int32(a.Or(b)) == [8]int32(x.Or(b)))
+	check("Int32x8.Xor", [8]int32(a.Xor(b)) == [8]int32(x.Xor(b)))
+	check("Int32x8.Min", [8]int32(a.Min(b)) == [8]int32(x.Min(b)))
+	check("Int32x8.Max", [8]int32(a.Max(b)) == [8]int32(x.Max(b)))
+	check("Int32x8.HAdd", a.HAdd() == x.HAdd())
+	check("Int32x8.Shuffle", [8]int32(a.Shuffle(b, [8]int{3, 8, 13, 2, 7, 12, 1, 6})) == [8]int32(x.Shuffle(b, [8]int{3, 8, 13, 2, 7, 12, 1, 6})))
+	check("Int32x8.Shuffle (mask variable)", [8]int32(a.Shuffle(b, int32x8Mask)) == [8]int32(x.Shuffle(b, int32x8Mask)))
+	check("Int32x8 ==", (a == b) == ([8]int32(a) == [8]int32(b)) && a == x.(simd.Int32x8))
+	check("Int32x8 !=", (a != b) == ([8]int32(a) != [8]int32(b)) && !(a != x.(simd.Int32x8)))
+}
+
+type int64x2 interface {
+	Add(simd.Int64x2) simd.Int64x2
+	Sub(simd.Int64x2) simd.Int64x2
+	Mul(simd.Int64x2) simd.Int64x2
+	And(simd.Int64x2) simd.Int64x2
+	Or(simd.Int64x2) simd.Int64x2
+	Xor(simd.Int64x2) simd.Int64x2
+	Min(simd.Int64x2) simd.Int64x2
+	Max(simd.Int64x2) simd.Int64x2
+	HAdd() int64
+	Shuffle(simd.Int64x2, [2]int) simd.Int64x2
+}
+
+var int64x2Mask = [2]int{3, 0}
+
+func testInt64x2(a, b simd.Int64x2) {
+	var x int64x2 = a
+	check("Int64x2.Add", [2]int64(a.Add(b)) == [2]int64(x.Add(b)))
+	check("Int64x2.Sub", [2]int64(a.Sub(b)) == [2]int64(x.Sub(b)))
+	check("Int64x2.Mul", [2]int64(a.Mul(b)) == [2]int64(x.Mul(b)))
+	check("Int64x2.And", [2]int64(a.And(b)) == [2]int64(x.And(b)))
+	check("Int64x2.Or", [2]int64(a.Or(b)) == [2]int64(x.Or(b)))
+	check("Int64x2.Xor", [2]int64(a.Xor(b)) == [2]int64(x.Xor(b)))
+	check("Int64x2.Min", [2]int64(a.Min(b)) == [2]int64(x.Min(b)))
+	check("Int64x2.Max", [2]int64(a.Max(b)) == [2]int64(x.Max(b)))
+	check("Int64x2.HAdd", a.HAdd() == x.HAdd())
+	check("Int64x2.Shuffle", [2]int64(a.Shuffle(b, [2]int{3, 0})) == [2]int64(x.Shuffle(b, [2]int{3, 0})))
+	check("Int64x2.Shuffle (mask variable)", [2]int64(a.Shuffle(b, int64x2Mask)) == [2]int64(x.Shuffle(b, int64x2Mask)))
+	check("Int64x2 ==", (a == b) == ([2]int64(a) == [2]int64(b)) && a == x.(simd.Int64x2))
+	check("Int64x2 !=", (a != b) == ([2]int64(a) != [2]int64(b)) && !(a != x.(simd.Int64x2)))
+}
+
+type uint8x16 interface {
+	Add(simd.Uint8x16) simd.Uint8x16
+	Sub(simd.Uint8x16) simd.Uint8x16
+	Mul(simd.Uint8x16) simd.Uint8x16
+	And(simd.Uint8x16) simd.Uint8x16
+	Or(simd.Uint8x16) simd.Uint8x16
+	Xor(simd.Uint8x16) simd.Uint8x16
+	Min(simd.Uint8x16) simd.Uint8x16
+	Max(simd.Uint8x16) simd.Uint8x16
+	HAdd() uint8
+	Shuffle(simd.Uint8x16, [16]int) simd.Uint8x16
+}
+
+var uint8x16Mask = [16]int{31, 28, 25, 22, 19, 16, 13, 10, 7, 4, 1, 30, 27, 24, 21, 18}
+
+func testUint8x16(a, b simd.Uint8x16) {
+	var x uint8x16 = a
+	check("Uint8x16.Add", [16]uint8(a.Add(b)) == [16]uint8(x.Add(b)))
+	check("Uint8x16.Sub", [16]uint8(a.Sub(b)) == [16]uint8(x.Sub(b)))
+	check("Uint8x16.Mul", [16]uint8(a.Mul(b)) == [16]uint8(x.Mul(b)))
+	check("Uint8x16.And", [16]uint8(a.And(b)) == [16]uint8(x.And(b)))
+	check("Uint8x16.Or", [16]uint8(a.Or(b)) == [16]uint8(x.Or(b)))
+	check("Uint8x16.Xor", [16]uint8(a.Xor(b)) == [16]uint8(x.Xor(b)))
+	check("Uint8x16.Min", [16]uint8(a.Min(b)) == [16]uint8(x.Min(b)))
+	check("Uint8x16.Max", [16]uint8(a.Max(b)) == [16]uint8(x.Max(b)))
+	check("Uint8x16.HAdd", a.HAdd() == x.HAdd())
+	check("Uint8x16.Shuffle", [16]uint8(a.Shuffle(b, [16]int{3, 8, 13, 18, 23, 28, 1, 6, 11, 16, 21, 26, 31, 4, 9, 14})) == [16]uint8(x.Shuffle(b, [16]int{3, 8, 13, 18, 23, 28, 1, 6, 11, 16, 21, 26, 31, 4, 9, 14})))
+	check("Uint8x16.Shuffle (mask variable)", [16]uint8(a.Shuffle(b, uint8x16Mask)) == [16]uint8(x.Shuffle(b, uint8x16Mask)))
+	check("Uint8x16 ==", (a == b) == ([16]uint8(a) == [16]uint8(b)) && a == x.(simd.Uint8x16))
+	check("Uint8x16 !=", (a != b) == ([16]uint8(a) != [16]uint8(b)) && !(a != x.(simd.Uint8x16)))
+}
+
+type uint16x8 interface {
+	Add(simd.Uint16x8) simd.Uint16x8
+	Sub(simd.Uint16x8) simd.Uint16x8
+	Mul(simd.Uint16x8) simd.Uint16x8
+	And(simd.Uint16x8) simd.Uint16x8
+	Or(simd.Uint16x8) simd.Uint16x8
+	Xor(simd.Uint16x8) simd.Uint16x8
+	Min(simd.Uint16x8) simd.Uint16x8
+	Max(simd.Uint16x8) simd.Uint16x8
+	HAdd() uint16
+	Shuffle(simd.Uint16x8, [8]int) simd.Uint16x8
+}
+
+var uint16x8Mask = [8]int{15, 12, 9, 6, 3, 0, 13, 10}
+
+func testUint16x8(a, b simd.Uint16x8) {
+	var x uint16x8 = a
+	check("Uint16x8.Add", [8]uint16(a.Add(b)) == [8]uint16(x.Add(b)))
+	check("Uint16x8.Sub", [8]uint16(a.Sub(b)) == [8]uint16(x.Sub(b)))
+	check("Uint16x8.Mul", [8]uint16(a.Mul(b)) == [8]uint16(x.Mul(b)))
+	check("Uint16x8.And", [8]uint16(a.And(b)) == [8]uint16(x.And(b)))
+	check("Uint16x8.Or", [8]uint16(a.Or(b)) == [8]uint16(x.Or(b)))
+	check("Uint16x8.Xor", [8]uint16(a.Xor(b)) == [8]uint16(x.Xor(b)))
+	check("Uint16x8.Min", [8]uint16(a.Min(b)) == [8]uint16(x.Min(b)))
+	check("Uint16x8.Max", [8]uint16(a.Max(b)) == [8]uint16(x.Max(b)))
+	check("Uint16x8.HAdd", a.HAdd() == x.HAdd())
+	check("Uint16x8.Shuffle", [8]uint16(a.Shuffle(b, [8]int{3, 8, 13, 2, 7, 12, 1, 6})) == [8]uint16(x.Shuffle(b, [8]int{3, 8, 13, 2, 7, 12, 1, 6})))
+	check("Uint16x8.Shuffle (mask variable)", [8]uint16(a.Shuffle(b, uint16x8Mask)) == [8]uint16(x.Shuffle(b, uint16x8Mask)))
+	check("Uint16x8 ==", (a == b) == ([8]uint16(a) == [8]uint16(b)) && a == x.(simd.Uint16x8))
+	check("Uint16x8 !=", (a != b) == ([8]uint16(a) != [8]uint16(b)) && !(a != x.(simd.Uint16x8)))
+}
+
+type uint32x4 interface {
+	Add(simd.Uint32x4) simd.Uint32x4
+	Sub(simd.Uint32x4) simd.Uint32x4
+	Mul(simd.Uint32x4) simd.Uint32x4
+	And(simd.Uint32x4) simd.Uint32x4
+	Or(simd.Uint32x4) simd.Uint32x4
+	Xor(simd.Uint32x4) simd.Uint32x4
+	Min(simd.Uint32x4) simd.Uint32x4
+	Max(simd.Uint32x4) simd.Uint32x4
+	HAdd() uint32
+	Shuffle(simd.Uint32x4, [4]int) simd.Uint32x4
+}
+
+var uint32x4Mask = [4]int{7, 4, 1, 6}
+
+func testUint32x4(a, b simd.Uint32x4) {
+	var x uint32x4 = a
+	check("Uint32x4.Add", [4]uint32(a.Add(b)) == [4]uint32(x.Add(b)))
+	check("Uint32x4.Sub", [4]uint32(a.Sub(b)) == [4]uint32(x.Sub(b)))
+	check("Uint32x4.Mul", [4]uint32(a.Mul(b)) == [4]uint32(x.Mul(b)))
+	check("Uint32x4.And", [4]uint32(a.And(b)) == [4]uint32(x.And(b)))
+	check("Uint32x4.Or", [4]uint32(a.Or(b)) == [4]uint32(x.Or(b)))
+	check("Uint32x4.Xor", [4]uint32(a.Xor(b)) == [4]uint32(x.Xor(b)))
+	check("Uint32x4.Min", [4]uint32(a.Min(b)) == [4]uint32(x.Min(b)))
+	check("Uint32x4.Max", [4]uint32(a.Max(b)) == [4]uint32(x.Max(b)))
+	check("Uint32x4.HAdd", a.HAdd() == x.HAdd())
+	check("Uint32x4.Shuffle", [4]uint32(a.Shuffle(b, [4]int{3, 0, 5, 2})) == [4]uint32(x.Shuffle(b, [4]int{3, 0, 5, 2})))
+	check("Uint32x4.Shuffle (mask variable)", [4]uint32(a.Shuffle(b, uint32x4Mask)) == [4]uint32(x.Shuffle(b, uint32x4Mask)))
+	check("Uint32x4 ==", (a == b) == ([4]uint32(a) == [4]uint32(b)) && a == x.(simd.Uint32x4))
+	check("Uint32x4 !=", (a != b) == ([4]uint32(a) != [4]uint32(b)) && !(a != x.(simd.Uint32x4)))
+}
+
+type uint64x2 interface {
+	Add(simd.Uint64x2) simd.Uint64x2
+	Sub(simd.Uint64x2) simd.Uint64x2
+	Mul(simd.Uint64x2) simd.Uint64x2
+	And(simd.Uint64x2) simd.Uint64x2
+	Or(simd.Uint64x2) simd.Uint64x2
+	Xor(simd.Uint64x2) simd.Uint64x2
+	Min(simd.Uint64x2) simd.Uint64x2
+	Max(simd.Uint64x2) simd.Uint64x2
+	HAdd() uint64
+	Shuffle(simd.Uint64x2, [2]int) simd.Uint64x2
+}
+
+var uint64x2Mask = [2]int{3, 0}
+
+func testUint64x2(a, b simd.Uint64x2) {
+	var x uint64x2 = a
+	check("Uint64x2.Add", [2]uint64(a.Add(b)) == [2]uint64(x.Add(b)))
+	check("Uint64x2.Sub", [2]uint64(a.Sub(b)) == [2]uint64(x.Sub(b)))
+	check("Uint64x2.Mul", [2]uint64(a.Mul(b)) == [2]uint64(x.Mul(b)))
+	check("Uint64x2.And", [2]uint64(a.And(b)) == [2]uint64(x.And(b)))
+	check("Uint64x2.Or", [2]uint64(a.Or(b)) == [2]uint64(x.Or(b)))
+	check("Uint64x2.Xor", [2]uint64(a.Xor(b)) == [2]uint64(x.Xor(b)))
+	check("Uint64x2.Min", [2]uint64(a.Min(b)) == [2]uint64(x.Min(b)))
+	check("Uint64x2.Max", [2]uint64(a.Max(b)) == [2]uint64(x.Max(b)))
+	check("Uint64x2.HAdd", a.HAdd() == x.HAdd())
+	check("Uint64x2.Shuffle", [2]uint64(a.Shuffle(b, [2]int{3, 0})) == [2]uint64(x.Shuffle(b, [2]int{3, 0})))
+	check("Uint64x2.Shuffle (mask variable)", [2]uint64(a.Shuffle(b, uint64x2Mask)) == [2]uint64(x.Shuffle(b, uint64x2Mask)))
+	check("Uint64x2 ==", (a == b) == ([2]uint64(a) == [2]uint64(b)) && a == x.(simd.Uint64x2))
+	check("Uint64x2 !=", (a != b) == ([2]uint64(a) != [2]uint64(b)) && !(a != x.(simd.Uint64x2)))
+}
+
+func main() {
+	testFloat32x4(simd.Float32x4{1, -2.5, 3, 8}, simd.Float32x4{0.5, 4, -3, 2})
+	testFloat32x8(simd.Float32x8{1, -2.5, 3, 8, 0.25, -7, 6, 100}, simd.Float32x8{0.5, 4, -3, 2, 16, -7.5, -1, 3})
+	testFloat64x2(simd.Float64x2{1, -2.5}, simd.Float64x2{0.5, 4})
+	testFloat64x4(simd.Float64x4{1, -2.5, 3, 8}, simd.Float64x4{0.5, 4, -3, 2})
+	testInt8x16(simd.Int8x16{1, -2, 3, 100, -128, 7, -6, 42, 5, -9, 11, -13, 15, -17, 19, 21}, simd.Int8x16{-1, 4, -3, 2, 127, -7, -6, -42, 8, 9, -11, 12, -15, 16, -19, 20})
+	testInt16x8(simd.Int16x8{1, -2, 3, 100, -128, 7, -6, 42}, simd.Int16x8{-1, 4, -3, 2, 127, -7, -6, -42})
+	testInt32x4(simd.Int32x4{1, -2, 3, 100}, simd.Int32x4{-1, 4, -3, 2})
+	testInt32x8(simd.Int32x8{1, -2, 3, 100, -128, 7, -6, 42}, simd.Int32x8{-1, 4, -3, 2, 127, -7, -6, -42})
+	testInt64x2(simd.Int64x2{1, -2}, simd.Int64x2{-1, 4})
+	testUint8x16(simd.Uint8x16{1, 2, 250, 100, 128, 7, 6, 42, 5, 9, 11, 13, 15, 17, 19, 21}, simd.Uint8x16{3, 200, 3, 2, 127, 70, 6, 42, 8, 9, 110, 12, 16, 16, 190, 20})
+	testUint16x8(simd.Uint16x8{1, 2, 250, 100, 128, 7, 6, 42}, simd.Uint16x8{3, 200, 3, 2, 127, 70, 6, 42})
+	testUint32x4(simd.Uint32x4{1, 2, 250, 100}, simd.Uint32x4{3, 200, 3, 2})
+	testUint64x2(simd.Uint64x2{1, 2}, simd.Uint64x2{3, 200})
+
+	// Vectors in memory allocated by the runtime.
+	s := make([]simd.Float32x8, 3)
+	s[1] = simd.Float32x8{1, 2, 3, 4, 5, 6, 7, 8}
+	s[2] = s[1].Add(s[1])
+	p := new(struct {
+		b byte
+		v simd.Float64x4
+	})
+	p.v = simd.Float64x4{1, 2, 3, 4}
+	m := map[int]simd.Int32x8{1: {1, 2, 3, 4, 5, 6, 7, 8}}
+	m[2] = m[1].Mul(m[1])
+	testFloat32x8(s[1], s[2])
+	testFloat64x4(p.v, p.v.Add(p.v))
+	testInt32x8(m[1], m[2])
+	check("heap Float32x8", s[2] == simd.Float32x8{2, 4, 6, 8, 10, 12, 14, 16})
+	check("heap Int32x8", m[2] == simd.Int32x8{1, 4, 9, 16, 25, 36, 49, 64})
+
+	if failed {
+		panic("simd methods disagree with their scalar implementations")
+	}
+	println("ok")
+}
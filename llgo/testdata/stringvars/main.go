@@ -0,0 +1,23 @@
+package main
+
+type name string
+
+var version = "devel"
+var unset string
+var named name = "x"
+var computed = version + "!"
+var untouched = "untouched"
+
+// pair has the same representation as a string.
+var pair struct {
+	p *int8
+	n int
+}
+
+func main() {
+	println(version)
+	println(unset)
+	println(named)
+	println(computed)
+	println(untouched)
+}
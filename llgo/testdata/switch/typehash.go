@@ -0,0 +1,77 @@
+package main
+
+type small struct{ a int8 }
+
+type large struct{ a, b, c int }
+
+type N int
+
+func (n N) String() string { return "N" }
+
+type stringer interface {
+	String() string
+}
+
+func describe(i interface{}) string {
+	switch x := i.(type) {
+	case nil:
+		return "nil"
+	case int:
+		println("int", x)
+	case string:
+		println("string", x)
+	case stringer:
+		println("stringer", x.String())
+	case small:
+		println("small", x.a)
+	case large:
+		println("large", x.a, x.b, x.c)
+	case *large:
+		println("*large", x.c)
+	case []int:
+		println("[]int", len(x))
+	case float64, uint16:
+		println("float64 or uint16")
+	default:
+		return "default"
+	}
+	return "matched"
+}
+
+func nonempty(s stringer) {
+	switch s.(type) {
+	case N:
+		println("N")
+	case *P:
+		println("*P")
+	default:
+		println("other")
+	}
+}
+
+type P int
+
+func (p *P) String() string { return "*P" }
+
+type M int
+
+func (m M) String() string { return "M" }
+
+func main() {
+	values := []interface{}{
+		nil, 123, "abc", N(1), small{7}, large{1, 2, 3},
+		&large{4, 5, 6}, []int{1, 2}, 1.5, uint16(1), int8(1),
+	}
+	n := 0
+	for _, v := range values {
+		if describe(v) == "matched" {
+			n++
+		}
+	}
+	println(n)
+	nonempty(N(1))
+	nonempty(new(P))
+	nonempty(M(1))
+	var s stringer
+	nonempty(s)
+}
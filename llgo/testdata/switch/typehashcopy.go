@@ -0,0 +1,52 @@
+package main
+
+import "unsafe"
+
+// sliceType mirrors llgo's runtime descriptor of a slice type.
+// This program relies on the layout, and so is not run with gc.
+type sliceType struct {
+	size       uintptr
+	hash       uint32
+	_          uint8
+	align      uint8
+	fieldAlign uint8
+	kind       uint8
+	alg        unsafe.Pointer
+	gc         unsafe.Pointer
+	string     *string
+	uncommon   unsafe.Pointer
+	ptrToThis  unsafe.Pointer
+	elem       unsafe.Pointer
+}
+
+func describe(i interface{}) {
+	switch x := i.(type) {
+	case []string:
+		println("[]string", len(x))
+	case []int:
+		println("[]int", len(x))
+	default:
+		println("default")
+	}
+}
+
+// copyType replaces the dynamic type of i with a copy of
+// its descriptor, as reflect may construct, with the hash
+// incremented by delta.
+func copyType(i interface{}, delta uint32) interface{} {
+	e := (*[2]unsafe.Pointer)(unsafe.Pointer(&i))
+	t := *(*sliceType)(e[0])
+	t.hash += delta
+	e[0] = unsafe.Pointer(&t)
+	return i
+}
+
+func main() {
+	x := []int{1, 2, 3}
+	describe(x)
+	// The same hash, at a different address.
+	describe(copyType(x, 0))
+	// A different hash.
+	describe(copyType(x, 1))
+	describe(copyType([]float64{1}, 1))
+}
@@ -0,0 +1,48 @@
+package main
+
+import "unsafe"
+
+type stringer interface {
+	String() string
+}
+
+type readWriter interface {
+	Read([]byte) (int, error)
+	Write([]byte) (int, error)
+	Close() error
+}
+
+type S struct {
+	a stringer
+	b readWriter
+	c interface{}
+	d int8
+}
+
+type T int
+
+func (T) String() string { return "T" }
+
+func main() {
+	var e interface{}
+	var s stringer
+	var rw readWriter
+	var x S
+	var a [3]readWriter
+	println(unsafe.Sizeof(e) == 2*unsafe.Sizeof(uintptr(0)))
+	println(unsafe.Sizeof(s) == unsafe.Sizeof(e))
+	println(unsafe.Sizeof(rw) == unsafe.Sizeof(e))
+	println(unsafe.Sizeof(a) == 3*unsafe.Sizeof(e))
+	println(unsafe.Offsetof(x.b) == unsafe.Sizeof(e))
+	println(unsafe.Offsetof(x.c) == 2*unsafe.Sizeof(e))
+	println(unsafe.Offsetof(x.d) == 3*unsafe.Sizeof(e))
+
+	// The values stored in a struct of interfaces
+	// are where the struct's layout puts them.
+	ss := []S{{a: T(1), d: 1}, {a: T(2), c: 3, d: 2}}
+	for _, x := range ss {
+		println(x.a.String(), x.b == nil, x.c == nil, x.c == 3, x.d)
+	}
+	p := (*int8)(unsafe.Pointer(uintptr(unsafe.Pointer(&ss[1])) + unsafe.Offsetof(ss[1].d)))
+	println(*p)
+}
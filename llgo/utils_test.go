@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/axw/gollvm/llvm"
 	"github.com/axw/llgo"
+	llgobuild "github.com/axw/llgo/build"
 	"go/build"
 	"io/ioutil"
 	"os"
@@ -19,11 +20,29 @@ import (
 )
 
 var (
-	testCompiler      *llgo.Compiler
-	tempdir           string
-	runtimemodulefile string
+	testCompiler *llgo.Compiler
+	tempdir      string
+
+	// runtimemodulefiles holds the linked runtime modules,
+	// with and without the race detector and soft-float.
+	runtimemodulefiles = make(map[runtimeConfig]string)
 )
 
+// runtimeConfig identifies a configuration
+// in which the runtime module is compiled.
+type runtimeConfig struct {
+	race, softfloat bool
+}
+
+// clangArgs returns the arguments with which the tests
+// invoke clang to compile C files and link programs.
+func clangArgs() []string {
+	if *softfloat {
+		return llgobuild.SoftFloatFlags(computeTriple())
+	}
+	return nil
+}
+
 func testdata(files ...string) []string {
 	for i, f := range files {
 		files[i] = "testdata/" + f
@@ -70,7 +89,11 @@ func init() {
 func getRuntimeFiles() (gofiles []string, llfiles []string, cfiles []string, err error) {
 	var pkg *build.Package
 	pkgpath := "github.com/axw/llgo/pkg/runtime"
-	pkg, err = build.Import(pkgpath, "", 0)
+	ctx := build.Default
+	if *race {
+		ctx.BuildTags = append(ctx.BuildTags, "race")
+	}
+	pkg, err = ctx.Import(pkgpath, "", 0)
 	if err != nil {
 		return
 	}
@@ -91,8 +114,9 @@ func getRuntimeFiles() (gofiles []string, llfiles []string, cfiles []string, err
 }
 
 func getRuntimeModuleFile() (string, error) {
-	if runtimemodulefile != "" {
-		return runtimemodulefile, nil
+	config := runtimeConfig{*race, *softfloat}
+	if file := runtimemodulefiles[config]; file != "" {
+		return file, nil
 	}
 
 	gofiles, llfiles, cfiles, err := getRuntimeFiles()
@@ -100,14 +124,28 @@ func getRuntimeModuleFile() (string, error) {
 		return "", err
 	}
 
+	// Compile the runtime with a compiler of its own, so
+	// that it is built for the current configuration
+	// whatever testCompiler the caller has used.
+	compiler, err := initCompiler()
+	if err != nil {
+		return "", err
+	}
 	var runtimeModule *llgo.Module
-	runtimeModule, err = compileFiles(testCompiler, gofiles, "runtime")
+	runtimeModule, err = compileFiles(compiler, gofiles, "runtime")
 	defer runtimeModule.Dispose()
 	if err != nil {
 		return "", err
 	}
 
-	outfile := filepath.Join(tempdir, "runtime.bc")
+	outfile := "runtime"
+	if *race {
+		outfile += "_race"
+	}
+	if *softfloat {
+		outfile += "_softfloat"
+	}
+	outfile = filepath.Join(tempdir, outfile+".bc")
 	f, err := os.Create(outfile)
 	if err != nil {
 		return "", err
@@ -122,6 +160,7 @@ func getRuntimeModuleFile() (string, error) {
 	for i, cfile := range cfiles {
 		bcfile := filepath.Join(tempdir, fmt.Sprintf("%d.bc", i))
 		args := []string{"-c", "-emit-llvm", "-o", bcfile, cfile}
+		args = append(args, clangArgs()...)
 		if runtime.GOOS != "darwin" {
 			// TODO(q): -g breaks badly on my system at the moment,
 			// so is not enabled on darwin for now
@@ -146,8 +185,8 @@ func getRuntimeModuleFile() (string, error) {
 		}
 	}
 
-	runtimemodulefile = outfile
-	return runtimemodulefile, nil
+	runtimemodulefiles[config] = outfile
+	return outfile, nil
 }
 
 func runMainFunction(m *llgo.Module) (output []string, err error) {
@@ -178,6 +217,10 @@ func runMainFunction(m *llgo.Module) (output []string, err error) {
 
 	exepath := filepath.Join(tempdir, "test")
 	args := []string{"-pthread", "-o", exepath, bcpath}
+	if *race {
+		args = append(args, "-fsanitize=thread")
+	}
+	args = append(args, clangArgs()...)
 	if runtime.GOOS != "darwin" {
 		// TODO(q): -g breaks badly on my system at the moment, so is not enabled on darwin for now
 		args = append([]string{"-g"}, args...)
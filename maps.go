@@ -26,6 +26,9 @@ func (c *compiler) makeMap(typ types.Type, cap_ *LLVMValue) *LLVMValue {
 
 // mapLookup implements v[, ok] = m[k]
 func (c *compiler) mapLookup(m, k *LLVMValue, commaOk bool) *LLVMValue {
+	if c.raceEnabled() {
+		c.raceMapAccess(m.LLVMValue(), false)
+	}
 	dyntyp := c.types.ToRuntime(m.Type())
 	dyntyp = c.builder.CreatePtrToInt(dyntyp, c.target.IntPtrType(), "")
 
@@ -64,6 +67,9 @@ func (c *compiler) mapUpdate(m_, k_, v_ *LLVMValue) {
 	dyntyp = c.builder.CreatePtrToInt(dyntyp, c.target.IntPtrType(), "")
 	m := m_.LLVMValue()
 	k := k_.LLVMValue()
+	if c.raceEnabled() {
+		c.raceMapAccess(m, true)
+	}
 
 	stackptr := c.stacksave()
 	pk := c.builder.CreateAlloca(k.Type(), "")
@@ -81,6 +87,9 @@ func (c *compiler) mapUpdate(m_, k_, v_ *LLVMValue) {
 // mapIterInit creates a map iterator
 func (c *compiler) mapIterInit(m *LLVMValue) *LLVMValue {
 	// TODO allocate iterator on stack at usage site
+	if c.raceEnabled() {
+		c.raceMapAccess(m.LLVMValue(), false)
+	}
 	f := c.runtime.mapiterinit.LLVMValue()
 	dyntyp := c.types.ToRuntime(m.Type())
 	iter := c.builder.CreateCall(f, []llvm.Value{
@@ -0,0 +1,313 @@
+// Copyright 2014 The llgo Authors.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package simd
+
+// Add returns the element-wise sum of a and b.
+func (a Float32x4) Add(b Float32x4) Float32x4 {
+	for i := range a {
+		a[i] += b[i]
+	}
+	return a
+}
+
+// Sub returns the element-wise difference of a and b.
+func (a Float32x4) Sub(b Float32x4) Float32x4 {
+	for i := range a {
+		a[i] -= b[i]
+	}
+	return a
+}
+
+// Mul returns the element-wise product of a and b.
+func (a Float32x4) Mul(b Float32x4) Float32x4 {
+	for i := range a {
+		a[i] *= b[i]
+	}
+	return a
+}
+
+// Div returns the element-wise quotient of a and b.
+func (a Float32x4) Div(b Float32x4) Float32x4 {
+	for i := range a {
+		a[i] /= b[i]
+	}
+	return a
+}
+
+// Min returns the element-wise minimum of a and b.
+func (a Float32x4) Min(b Float32x4) Float32x4 {
+	for i := range a {
+		if b[i] < a[i] {
+			a[i] = b[i]
+		}
+	}
+	return a
+}
+
+// Max returns the element-wise maximum of a and b.
+func (a Float32x4) Max(b Float32x4) Float32x4 {
+	for i := range a {
+		if a[i] < b[i] {
+			a[i] = b[i]
+		}
+	}
+	return a
+}
+
+// HAdd returns the sum of the elements of a.
+func (a Float32x4) HAdd() float32 {
+	for n := len(a) / 2; n > 0; n /= 2 {
+		for i := 0; i < n; i++ {
+			a[i] += a[i+n]
+		}
+	}
+	return a[0]
+}
+
+// Shuffle returns a vector whose elements are selected from a and
+// b by the indices in mask: indices less than 4 select elements
+// of a, and the others select elements of b.
+func (a Float32x4) Shuffle(b Float32x4, mask [4]int) Float32x4 {
+	var r Float32x4
+	for i, m := range mask {
+		if m < len(a) {
+			r[i] = a[m]
+		} else {
+			r[i] = b[m-len(a)]
+		}
+	}
+	return r
+}
+
+// Add returns the element-wise sum of a and b.
+func (a Float32x8) Add(b Float32x8) Float32x8 {
+	for i := range a {
+		a[i] += b[i]
+	}
+	return a
+}
+
+// Sub returns the element-wise difference of a and b.
+func (a Float32x8) Sub(b Float32x8) Float32x8 {
+	for i := range a {
+		a[i] -= b[i]
+	}
+	return a
+}
+
+// Mul returns the element-wise product of a and b.
+func (a Float32x8) Mul(b Float32x8) Float32x8 {
+	for i := range a {
+		a[i] *= b[i]
+	}
+	return a
+}
+
+// Div returns the element-wise quotient of a and b.
+func (a Float32x8) Div(b Float32x8) Float32x8 {
+	for i := range a {
+		a[i] /= b[i]
+	}
+	return a
+}
+
+// Min returns the element-wise minimum of a and b.
+func (a Float32x8) Min(b Float32x8) Float32x8 {
+	for i := range a {
+		if b[i] < a[i] {
+			a[i] = b[i]
+		}
+	}
+	return a
+}
+
+// Max returns the element-wise maximum of a and b.
+func (a Float32x8) Max(b Float32x8) Float32x8 {
+	for i := range a {
+		if a[i] < b[i] {
+			a[i] = b[i]
+		}
+	}
+	return a
+}
+
+// HAdd returns the sum of the elements of a.
+func (a Float32x8) HAdd() float32 {
+	for n := len(a) / 2; n > 0; n /= 2 {
+		for i := 0; i < n; i++ {
+			a[i] += a[i+n]
+		}
+	}
+	return a[0]
+}
+
+// Shuffle returns a vector whose elements are selected from a and
+// b by the indices in mask: indices less than 8 select elements
+// of a, and the others select elements of b.
+func (a Float32x8) Shuffle(b Float32x8, mask [8]int) Float32x8 {
+	var r Float32x8
+	for i, m := range mask {
+		if m < len(a) {
+			r[i] = a[m]
+		} else {
+			r[i] = b[m-len(a)]
+		}
+	}
+	return r
+}
+
+// Add returns the element-wise sum of a and b.
+func (a Float64x2) Add(b Float64x2) Float64x2 {
+	for i := range a {
+		a[i] += b[i]
+	}
+	return a
+}
+
+// Sub returns the element-wise difference of a and b.
+func (a Float64x2) Sub(b Float64x2) Float64x2 {
+	for i := range a {
+		a[i] -= b[i]
+	}
+	return a
+}
+
+// Mul returns the element-wise product of a and b.
+func (a Float64x2) Mul(b Float64x2) Float64x2 {
+	for i := range a {
+		a[i] *= b[i]
+	}
+	return a
+}
+
+// Div returns the element-wise quotient of a and b.
+func (a Float64x2) Div(b Float64x2) Float64x2 {
+	for i := range a {
+		a[i] /= b[i]
+	}
+	return a
+}
+
+// Min returns the element-wise minimum of a and b.
+func (a Float64x2) Min(b Float64x2) Float64x2 {
+	for i := range a {
+		if b[i] < a[i] {
+			a[i] = b[i]
+		}
+	}
+	return a
+}
+
+// Max returns the element-wise maximum of a and b.
+func (a Float64x2) Max(b Float64x2) Float64x2 {
+	for i := range a {
+		if a[i] < b[i] {
+			a[i] = b[i]
+		}
+	}
+	return a
+}
+
+// HAdd returns the sum of the elements of a.
+func (a Float64x2) HAdd() float64 {
+	for n := len(a) / 2; n > 0; n /= 2 {
+		for i := 0; i < n; i++ {
+			a[i] += a[i+n]
+		}
+	}
+	return a[0]
+}
+
+// Shuffle returns a vector whose elements are selected from a and
+// b by the indices in mask: indices less than 2 select elements
+// of a, and the others select elements of b.
+func (a Float64x2) Shuffle(b Float64x2, mask [2]int) Float64x2 {
+	var r Float64x2
+	for i, m := range mask {
+		if m < len(a) {
+			r[i] = a[m]
+		} else {
+			r[i] = b[m-len(a)]
+		}
+	}
+	return r
+}
+
+// Add returns the element-wise sum of a and b.
+func (a Float64x4) Add(b Float64x4) Float64x4 {
+	for i := range a {
+		a[i] += b[i]
+	}
+	return a
+}
+
+// Sub returns the element-wise difference of a and b.
+func (a Float64x4) Sub(b Float64x4) Float64x4 {
+	for i := range a {
+		a[i] -= b[i]
+	}
+	return a
+}
+
+// Mul returns the element-wise product of a and b.
+func (a Float64x4) Mul(b Float64x4) Float64x4 {
+	for i := range a {
+		a[i] *= b[i]
+	}
+	return a
+}
+
+// Div returns the element-wise quotient of a and b.
+func (a Float64x4) Div(b Float64x4) Float64x4 {
+	for i := range a {
+		a[i] /= b[i]
+	}
+	return a
+}
+
+// Min returns the element-wise minimum of a and b.
+func (a Float64x4) Min(b Float64x4) Float64x4 {
+	for i := range a {
+		if b[i] < a[i] {
+			a[i] = b[i]
+		}
+	}
+	return a
+}
+
+// Max returns the element-wise maximum of a and b.
+func (a Float64x4) Max(b Float64x4) Float64x4 {
+	for i := range a {
+		if a[i] < b[i] {
+			a[i] = b[i]
+		}
+	}
+	return a
+}
+
+// HAdd returns the sum of the elements of a.
+func (a Float64x4) HAdd() float64 {
+	for n := len(a) / 2; n > 0; n /= 2 {
+		for i := 0; i < n; i++ {
+			a[i] += a[i+n]
+		}
+	}
+	return a[0]
+}
+
+// Shuffle returns a vector whose elements are selected from a and
+// b by the indices in mask: indices less than 4 select elements
+// of a, and the others select elements of b.
+func (a Float64x4) Shuffle(b Float64x4, mask [4]int) Float64x4 {
+	var r Float64x4
+	for i, m := range mask {
+		if m < len(a) {
+			r[i] = a[m]
+		} else {
+			r[i] = b[m-len(a)]
+		}
+	}
+	return r
+}
@@ -0,0 +1,842 @@
+// Copyright 2014 The llgo Authors.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package simd
+
+// Add returns the element-wise sum of a and b.
+func (a Int8x16) Add(b Int8x16) Int8x16 {
+	for i := range a {
+		a[i] += b[i]
+	}
+	return a
+}
+
+// Sub returns the element-wise difference of a and b.
+func (a Int8x16) Sub(b Int8x16) Int8x16 {
+	for i := range a {
+		a[i] -= b[i]
+	}
+	return a
+}
+
+// Mul returns the element-wise product of a and b.
+func (a Int8x16) Mul(b Int8x16) Int8x16 {
+	for i := range a {
+		a[i] *= b[i]
+	}
+	return a
+}
+
+// And returns the element-wise bitwise AND of a and b.
+func (a Int8x16) And(b Int8x16) Int8x16 {
+	for i := range a {
+		a[i] &= b[i]
+	}
+	return a
+}
+
+// Or returns the element-wise bitwise OR of a and b.
+func (a Int8x16) Or(b Int8x16) Int8x16 {
+	for i := range a {
+		a[i] |= b[i]
+	}
+	return a
+}
+
+// Xor returns the element-wise bitwise XOR of a and b.
+func (a Int8x16) Xor(b Int8x16) Int8x16 {
+	for i := range a {
+		a[i] ^= b[i]
+	}
+	return a
+}
+
+// Min returns the element-wise minimum of a and b.
+func (a Int8x16) Min(b Int8x16) Int8x16 {
+	for i := range a {
+		if b[i] < a[i] {
+			a[i] = b[i]
+		}
+	}
+	return a
+}
+
+// Max returns the element-wise maximum of a and b.
+func (a Int8x16) Max(b Int8x16) Int8x16 {
+	for i := range a {
+		if a[i] < b[i] {
+			a[i] = b[i]
+		}
+	}
+	return a
+}
+
+// HAdd returns the sum of the elements of a.
+func (a Int8x16) HAdd() int8 {
+	for n := len(a) / 2; n > 0; n /= 2 {
+		for i := 0; i < n; i++ {
+			a[i] += a[i+n]
+		}
+	}
+	return a[0]
+}
+
+// Shuffle returns a vector whose elements are selected from a and
+// b by the indices in mask: indices less than 16 select elements
+// of a, and the others select elements of b.
+func (a Int8x16) Shuffle(b Int8x16, mask [16]int) Int8x16 {
+	var r Int8x16
+	for i, m := range mask {
+		if m < len(a) {
+			r[i] = a[m]
+		} else {
+			r[i] = b[m-len(a)]
+		}
+	}
+	return r
+}
+
+// Add returns the element-wise sum of a and b.
+func (a Int16x8) Add(b Int16x8) Int16x8 {
+	for i := range a {
+		a[i] += b[i]
+	}
+	return a
+}
+
+// Sub returns the element-wise difference of a and b.
+func (a Int16x8) Sub(b Int16x8) Int16x8 {
+	for i := range a {
+		a[i] -= b[i]
+	}
+	return a
+}
+
+// Mul returns the element-wise product of a and b.
+func (a Int16x8) Mul(b Int16x8) Int16x8 {
+	for i := range a {
+		a[i] *= b[i]
+	}
+	return a
+}
+
+// And returns the element-wise bitwise AND of a and b.
+func (a Int16x8) And(b Int16x8) Int16x8 {
+	for i := range a {
+		a[i] &= b[i]
+	}
+	return a
+}
+
+// Or returns the element-wise bitwise OR of a and b.
+func (a Int16x8) Or(b Int16x8) Int16x8 {
+	for i := range a {
+		a[i] |= b[i]
+	}
+	return a
+}
+
+// Xor returns the element-wise bitwise XOR of a and b.
+func (a Int16x8) Xor(b Int16x8) Int16x8 {
+	for i := range a {
+		a[i] ^= b[i]
+	}
+	return a
+}
+
+// Min returns the element-wise minimum of a and b.
+func (a Int16x8) Min(b Int16x8) Int16x8 {
+	for i := range a {
+		if b[i] < a[i] {
+			a[i] = b[i]
+		}
+	}
+	return a
+}
+
+// Max returns the element-wise maximum of a and b.
+func (a Int16x8) Max(b Int16x8) Int16x8 {
+	for i := range a {
+		if a[i] < b[i] {
+			a[i] = b[i]
+		}
+	}
+	return a
+}
+
+// HAdd returns the sum of the elements of a.
+func (a Int16x8) HAdd() int16 {
+	for n := len(a) / 2; n > 0; n /= 2 {
+		for i := 0; i < n; i++ {
+			a[i] += a[i+n]
+		}
+	}
+	return a[0]
+}
+
+// Shuffle returns a vector whose elements are selected from a and
+// b by the indices in mask: indices less than 8 select elements
+// of a, and the others select elements of b.
+func (a Int16x8) Shuffle(b Int16x8, mask [8]int) Int16x8 {
+	var r Int16x8
+	for i, m := range mask {
+		if m < len(a) {
+			r[i] = a[m]
+		} else {
+			r[i] = b[m-len(a)]
+		}
+	}
+	return r
+}
+
+// Add returns the element-wise sum of a and b.
+func (a Int32x4) Add(b Int32x4) Int32x4 {
+	for i := range a {
+		a[i] += b[i]
+	}
+	return a
+}
+
+// Sub returns the element-wise difference of a and b.
+func (a Int32x4) Sub(b Int32x4) Int32x4 {
+	for i := range a {
+		a[i] -= b[i]
+	}
+	return a
+}
+
+// Mul returns the element-wise product of a and b.
+func (a Int32x4) Mul(b Int32x4) Int32x4 {
+	for i := range a {
+		a[i] *= b[i]
+	}
+	return a
+}
+
+// And returns the element-wise bitwise AND of a and b.
+func (a Int32x4) And(b Int32x4) Int32x4 {
+	for i := range a {
+		a[i] &= b[i]
+	}
+	return a
+}
+
+// Or returns the element-wise bitwise OR of a and b.
+func (a Int32x4) Or(b Int32x4) Int32x4 {
+	for i := range a {
+		a[i] |= b[i]
+	}
+	return a
+}
+
+// Xor returns the element-wise bitwise XOR of a and b.
+func (a Int32x4) Xor(b Int32x4) Int32x4 {
+	for i := range a {
+		a[i] ^= b[i]
+	}
+	return a
+}
+
+// Min returns the element-wise minimum of a and b.
+func (a Int32x4) Min(b Int32x4) Int32x4 {
+	for i := range a {
+		if b[i] < a[i] {
+			a[i] = b[i]
+		}
+	}
+	return a
+}
+
+// Max returns the element-wise maximum of a and b.
+func (a Int32x4) Max(b Int32x4) Int32x4 {
+	for i := range a {
+		if a[i] < b[i] {
+			a[i] = b[i]
+		}
+	}
+	return a
+}
+
+// HAdd returns the sum of the elements of a.
+func (a Int32x4) HAdd() int32 {
+	for n := len(a) / 2; n > 0; n /= 2 {
+		for i := 0; i < n; i++ {
+			a[i] += a[i+n]
+		}
+	}
+	return a[0]
+}
+
+// Shuffle returns a vector whose elements are selected from a and
+// b by the indices in mask: indices less than 4 select elements
+// of a, and the others select elements of b.
+func (a Int32x4) Shuffle(b Int32x4, mask [4]int) Int32x4 {
+	var r Int32x4
+	for i, m := range mask {
+		if m < len(a) {
+			r[i] = a[m]
+		} else {
+			r[i] = b[m-len(a)]
+		}
+	}
+	return r
+}
+
+// Add returns the element-wise sum of a and b.
+func (a Int32x8) Add(b Int32x8) Int32x8 {
+	for i := range a {
+		a[i] += b[i]
+	}
+	return a
+}
+
+// Sub returns the element-wise difference of a and b.
+func (a Int32x8) Sub(b Int32x8) Int32x8 {
+	for i := range a {
+		a[i] -= b[i]
+	}
+	return a
+}
+
+// Mul returns the element-wise product of a and b.
+func (a Int32x8) Mul(b Int32x8) Int32x8 {
+	for i := range a {
+		a[i] *= b[i]
+	}
+	return a
+}
+
+// And returns the element-wise bitwise AND of a and b.
+func (a Int32x8) And(b Int32x8) Int32x8 {
+	for i := range a {
+		a[i] &= b[i]
+	}
+	return a
+}
+
+// Or returns the element-wise bitwise OR of a and b.
+func (a Int32x8) Or(b Int32x8) Int32x8 {
+	for i := range a {
+		a[i] |= b[i]
+	}
+	return a
+}
+
+// Xor returns the element-wise bitwise XOR of a and b.
+func (a Int32x8) Xor(b Int32x8) Int32x8 {
+	for i := range a {
+		a[i] ^= b[i]
+	}
+	return a
+}
+
+// Min returns the element-wise minimum of a and b.
+func (a Int32x8) Min(b Int32x8) Int32x8 {
+	for i := range a {
+		if b[i] < a[i] {
+			a[i] = b[i]
+		}
+	}
+	return a
+}
+
+// Max returns the element-wise maximum of a and b.
+func (a Int32x8) Max(b Int32x8) Int32x8 {
+	for i := range a {
+		if a[i] < b[i] {
+			a[i] = b[i]
+		}
+	}
+	return a
+}
+
+// HAdd returns the sum of the elements of a.
+func (a Int32x8) HAdd() int32 {
+	for n := len(a) / 2; n > 0; n /= 2 {
+		for i := 0; i < n; i++ {
+			a[i] += a[i+n]
+		}
+	}
+	return a[0]
+}
+
+// Shuffle returns a vector whose elements are selected from a and
+// b by the indices in mask: indices less than 8 select elements
+// of a, and the others select elements of b.
+func (a Int32x8) Shuffle(b Int32x8, mask [8]int) Int32x8 {
+	var r Int32x8
+	for i, m := range mask {
+		if m < len(a) {
+			r[i] = a[m]
+		} else {
+			r[i] = b[m-len(a)]
+		}
+	}
+	return r
+}
+
+// Add returns the element-wise sum of a and b.
+func (a Int64x2) Add(b Int64x2) Int64x2 {
+	for i := range a {
+		a[i] += b[i]
+	}
+	return a
+}
+
+// Sub returns the element-wise difference of a and b.
+func (a Int64x2) Sub(b Int64x2) Int64x2 {
+	for i := range a {
+		a[i] -= b[i]
+	}
+	return a
+}
+
+// Mul returns the element-wise product of a and b.
+func (a Int64x2) Mul(b Int64x2) Int64x2 {
+	for i := range a {
+		a[i] *= b[i]
+	}
+	return a
+}
+
+// And returns the element-wise bitwise AND of a and b.
+func (a Int64x2) And(b Int64x2) Int64x2 {
+	for i := range a {
+		a[i] &= b[i]
+	}
+	return a
+}
+
+// Or returns the element-wise bitwise OR of a and b.
+func (a Int64x2) Or(b Int64x2) Int64x2 {
+	for i := range a {
+		a[i] |= b[i]
+	}
+	return a
+}
+
+// Xor returns the element-wise bitwise XOR of a and b.
+func (a Int64x2) Xor(b Int64x2) Int64x2 {
+	for i := range a {
+		a[i] ^= b[i]
+	}
+	return a
+}
+
+// Min returns the element-wise minimum of a and b.
+func (a Int64x2) Min(b Int64x2) Int64x2 {
+	for i := range a {
+		if b[i] < a[i] {
+			a[i] = b[i]
+		}
+	}
+	return a
+}
+
+// Max returns the element-wise maximum of a and b.
+func (a Int64x2) Max(b Int64x2) Int64x2 {
+	for i := range a {
+		if a[i] < b[i] {
+			a[i] = b[i]
+		}
+	}
+	return a
+}
+
+// HAdd returns the sum of the elements of a.
+func (a Int64x2) HAdd() int64 {
+	for n := len(a) / 2; n > 0; n /= 2 {
+		for i := 0; i < n; i++ {
+			a[i] += a[i+n]
+		}
+	}
+	return a[0]
+}
+
+// Shuffle returns a vector whose elements are selected from a and
+// b by the indices in mask: indices less than 2 select elements
+// of a, and the others select elements of b.
+func (a Int64x2) Shuffle(b Int64x2, mask [2]int) Int64x2 {
+	var r Int64x2
+	for i, m := range mask {
+		if m < len(a) {
+			r[i] = a[m]
+		} else {
+			r[i] = b[m-len(a)]
+		}
+	}
+	return r
+}
+
+// Add returns the element-wise sum of a and b.
+func (a Uint8x16) Add(b Uint8x16) Uint8x16 {
+	for i := range a {
+		a[i] += b[i]
+	}
+	return a
+}
+
+// Sub returns the element-wise difference of a and b.
+func (a Uint8x16) Sub(b Uint8x16) Uint8x16 {
+	for i := range a {
+		a[i] -= b[i]
+	}
+	return a
+}
+
+// Mul returns the element-wise product of a and b.
+func (a Uint8x16) Mul(b Uint8x16) Uint8x16 {
+	for i := range a {
+		a[i] *= b[i]
+	}
+	return a
+}
+
+// And returns the element-wise bitwise AND of a and b.
+func (a Uint8x16) And(b Uint8x16) Uint8x16 {
+	for i := range a {
+		a[i] &= b[i]
+	}
+	return a
+}
+
+// Or returns the element-wise bitwise OR of a and b.
+func (a Uint8x16) Or(b Uint8x16) Uint8x16 {
+	for i := range a {
+		a[i] |= b[i]
+	}
+	return a
+}
+
+// Xor returns the element-wise bitwise XOR of a and b.
+func (a Uint8x16) Xor(b Uint8x16) Uint8x16 {
+	for i := range a {
+		a[i] ^= b[i]
+	}
+	return a
+}
+
+// Min returns the element-wise minimum of a and b.
+func (a Uint8x16) Min(b Uint8x16) Uint8x16 {
+	for i := range a {
+		if b[i] < a[i] {
+			a[i] = b[i]
+		}
+	}
+	return a
+}
+
+// Max returns the element-wise maximum of a and b.
+func (a Uint8x16) Max(b Uint8x16) Uint8x16 {
+	for i := range a {
+		if a[i] < b[i] {
+			a[i] = b[i]
+		}
+	}
+	return a
+}
+
+// HAdd returns the sum of the elements of a.
+func (a Uint8x16) HAdd() uint8 {
+	for n := len(a) / 2; n > 0; n /= 2 {
+		for i := 0; i < n; i++ {
+			a[i] += a[i+n]
+		}
+	}
+	return a[0]
+}
+
+// Shuffle returns a vector whose elements are selected from a and
+// b by the indices in mask: indices less than 16 select elements
+// of a, and the others select elements of b.
+func (a Uint8x16) Shuffle(b Uint8x16, mask [16]int) Uint8x16 {
+	var r Uint8x16
+	for i, m := range mask {
+		if m < len(a) {
+			r[i] = a[m]
+		} else {
+			r[i] = b[m-len(a)]
+		}
+	}
+	return r
+}
+
+// Add returns the element-wise sum of a and b.
+func (a Uint16x8) Add(b Uint16x8) Uint16x8 {
+	for i := range a {
+		a[i] += b[i]
+	}
+	return a
+}
+
+// Sub returns the element-wise difference of a and b.
+func (a Uint16x8) Sub(b Uint16x8) Uint16x8 {
+	for i := range a {
+		a[i] -= b[i]
+	}
+	return a
+}
+
+// Mul returns the element-wise product of a and b.
+func (a Uint16x8) Mul(b Uint16x8) Uint16x8 {
+	for i := range a {
+		a[i] *= b[i]
+	}
+	return a
+}
+
+// And returns the element-wise bitwise AND of a and b.
+func (a Uint16x8) And(b Uint16x8) Uint16x8 {
+	for i := range a {
+		a[i] &= b[i]
+	}
+	return a
+}
+
+// Or returns the element-wise bitwise OR of a and b.
+func (a Uint16x8) Or(b Uint16x8) Uint16x8 {
+	for i := range a {
+		a[i] |= b[i]
+	}
+	return a
+}
+
+// Xor returns the element-wise bitwise XOR of a and b.
+func (a Uint16x8) Xor(b Uint16x8) Uint16x8 {
+	for i := range a {
+		a[i] ^= b[i]
+	}
+	return a
+}
+
+// Min returns the element-wise minimum of a and b.
+func (a Uint16x8) Min(b Uint16x8) Uint16x8 {
+	for i := range a {
+		if b[i] < a[i] {
+			a[i] = b[i]
+		}
+	}
+	return a
+}
+
+// Max returns the element-wise maximum of a and b.
+func (a Uint16x8) Max(b Uint16x8) Uint16x8 {
+	for i := range a {
+		if a[i] < b[i] {
+			a[i] = b[i]
+		}
+	}
+	return a
+}
+
+// HAdd returns the sum of the elements of a.
+func (a Uint16x8) HAdd() uint16 {
+	for n := len(a) / 2; n > 0; n /= 2 {
+		for i := 0; i < n; i++ {
+			a[i] += a[i+n]
+		}
+	}
+	return a[0]
+}
+
+// Shuffle returns a vector whose elements are selected from a and
+// b by the indices in mask: indices less than 8 select elements
+// of a, and the others select elements of b.
+func (a Uint16x8) Shuffle(b Uint16x8, mask [8]int) Uint16x8 {
+	var r Uint16x8
+	for i, m := range mask {
+		if m < len(a) {
+			r[i] = a[m]
+		} else {
+			r[i] = b[m-len(a)]
+		}
+	}
+	return r
+}
+
+// Add returns the element-wise sum of a and b.
+func (a Uint32x4) Add(b Uint32x4) Uint32x4 {
+	for i := range a {
+		a[i] += b[i]
+	}
+	return a
+}
+
+// Sub returns the element-wise difference of a and b.
+func (a Uint32x4) Sub(b Uint32x4) Uint32x4 {
+	for i := range a {
+		a[i] -= b[i]
+	}
+	return a
+}
+
+// Mul returns the element-wise product of a and b.
+func (a Uint32x4) Mul(b Uint32x4) Uint32x4 {
+	for i := range a {
+		a[i] *= b[i]
+	}
+	return a
+}
+
+// And returns the element-wise bitwise AND of a and b.
+func (a Uint32x4) And(b Uint32x4) Uint32x4 {
+	for i := range a {
+		a[i] &= b[i]
+	}
+	return a
+}
+
+// Or returns the element-wise bitwise OR of a and b.
+func (a Uint32x4) Or(b Uint32x4) Uint32x4 {
+	for i := range a {
+		a[i] |= b[i]
+	}
+	return a
+}
+
+// Xor returns the element-wise bitwise XOR of a and b.
+func (a Uint32x4) Xor(b Uint32x4) Uint32x4 {
+	for i := range a {
+		a[i] ^= b[i]
+	}
+	return a
+}
+
+// Min returns the element-wise minimum of a and b.
+func (a Uint32x4) Min(b Uint32x4) Uint32x4 {
+	for i := range a {
+		if b[i] < a[i] {
+			a[i] = b[i]
+		}
+	}
+	return a
+}
+
+// Max returns the element-wise maximum of a and b.
+func (a Uint32x4) Max(b Uint32x4) Uint32x4 {
+	for i := range a {
+		if a[i] < b[i] {
+			a[i] = b[i]
+		}
+	}
+	return a
+}
+
+// HAdd returns the sum of the elements of a.
+func (a Uint32x4) HAdd() uint32 {
+	for n := len(a) / 2; n > 0; n /= 2 {
+		for i := 0; i < n; i++ {
+			a[i] += a[i+n]
+		}
+	}
+	return a[0]
+}
+
+// Shuffle returns a vector whose elements are selected from a and
+// b by the indices in mask: indices less than 4 select elements
+// of a, and the others select elements of b.
+func (a Uint32x4) Shuffle(b Uint32x4, mask [4]int) Uint32x4 {
+	var r Uint32x4
+	for i, m := range mask {
+		if m < len(a) {
+			r[i] = a[m]
+		} else {
+			r[i] = b[m-len(a)]
+		}
+	}
+	return r
+}
+
+// Add returns the element-wise sum of a and b.
+func (a Uint64x2) Add(b Uint64x2) Uint64x2 {
+	for i := range a {
+		a[i] += b[i]
+	}
+	return a
+}
+
+// Sub returns the element-wise difference of a and b.
+func (a Uint64x2) Sub(b Uint64x2) Uint64x2 {
+	for i := range a {
+		a[i] -= b[i]
+	}
+	return a
+}
+
+// Mul returns the element-wise product of a and b.
+func (a Uint64x2) Mul(b Uint64x2) Uint64x2 {
+	for i := range a {
+		a[i] *= b[i]
+	}
+	return a
+}
+
+// And returns the element-wise bitwise AND of a and b.
+func (a Uint64x2) And(b Uint64x2) Uint64x2 {
+	for i := range a {
+		a[i] &= b[i]
+	}
+	return a
+}
+
+// Or returns the element-wise bitwise OR of a and b.
+func (a Uint64x2) Or(b Uint64x2) Uint64x2 {
+	for i := range a {
+		a[i] |= b[i]
+	}
+	return a
+}
+
+// Xor returns the element-wise bitwise XOR of a and b.
+func (a Uint64x2) Xor(b Uint64x2) Uint64x2 {
+	for i := range a {
+		a[i] ^= b[i]
+	}
+	return a
+}
+
+// Min returns the element-wise minimum of a and b.
+func (a Uint64x2) Min(b Uint64x2) Uint64x2 {
+	for i := range a {
+		if b[i] < a[i] {
+			a[i] = b[i]
+		}
+	}
+	return a
+}
+
+// Max returns the element-wise maximum of a and b.
+func (a Uint64x2) Max(b Uint64x2) Uint64x2 {
+	for i := range a {
+		if a[i] < b[i] {
+			a[i] = b[i]
+		}
+	}
+	return a
+}
+
+// HAdd returns the sum of the elements of a.
+func (a Uint64x2) HAdd() uint64 {
+	for n := len(a) / 2; n > 0; n /= 2 {
+		for i := 0; i < n; i++ {
+			a[i] += a[i+n]
+		}
+	}
+	return a[0]
+}
+
+// Shuffle returns a vector whose elements are selected from a and
+// b by the indices in mask: indices less than 2 select elements
+// of a, and the others select elements of b.
+func (a Uint64x2) Shuffle(b Uint64x2, mask [2]int) Uint64x2 {
+	var r Uint64x2
+	for i, m := range mask {
+		if m < len(a) {
+			r[i] = a[m]
+		} else {
+			r[i] = b[m-len(a)]
+		}
+	}
+	return r
+}
@@ -0,0 +1,39 @@
+// Copyright 2014 The llgo Authors.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+// Package simd provides fixed-size vector types, and operations
+// on them that map to SIMD instructions.
+//
+// When compiled by llgo, the types in this package are represented
+// by LLVM vector types, and direct calls to their methods are lowered
+// to vector instructions rather than called. Shuffle is lowered only
+// when its mask is a composite literal of constants.
+//
+// When compiled by gc, or when called indirectly (e.g. through an
+// interface or method value), the methods are implemented with
+// scalar code which computes the same results.
+//
+// With llgo, unsafe.Alignof reports the alignment of the vector
+// types, which may be greater than that of arrays of their element
+// type; but values of these types are loaded and stored with the
+// alignment of their elements, so they need not be so aligned.
+package simd
+
+type (
+	Float32x4 [4]float32
+	Float32x8 [8]float32
+	Float64x2 [2]float64
+	Float64x4 [4]float64
+
+	Int8x16 [16]int8
+	Int16x8 [8]int16
+	Int32x4 [4]int32
+	Int32x8 [8]int32
+	Int64x2 [2]int64
+
+	Uint8x16 [16]uint8
+	Uint16x8 [8]uint16
+	Uint32x4 [4]uint32
+	Uint64x2 [2]uint64
+)
@@ -49,21 +49,6 @@ declare double @math.exp2(double)
 declare double @math.expm1(double)
 @math.Expm1 = alias double (double)* @math.expm1
 
-declare double @math.floor(double)
-@math.Floor = alias double (double)* @math.floor
-
-declare double @math.sqrt(double)
-@math.Sqrt = alias double (double)* @math.sqrt
-
-declare double @math.abs(double)
-@math.Abs = alias double (double)* @math.abs
-
-declare double @math.ceil(double)
-@math.Ceil = alias double (double)* @math.ceil
-
-declare double @math.trunc(double)
-@math.Trunc = alias double (double)* @math.trunc
-
 declare double @math.dim(double, double)
 @math.Dim = alias double (double, double)* @math.dim
 
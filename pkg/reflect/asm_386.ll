@@ -0,0 +1,15 @@
+; Copyright 2013 Andrew Wilkins.
+; Use of this source code is governed by an MIT-style
+; license that can be found in the LICENSE file.
+
+declare void @llvm.trap() noreturn nounwind
+
+; methodValueCall is gc's code for method values. The method
+; values made by llgo's makeMethodValue call the method
+; directly, so it is never called: reflect.Value.Pointer
+; only returns its address.
+define void @reflect.methodValueCall() {
+entry:
+	call void @llvm.trap()
+	unreachable
+}
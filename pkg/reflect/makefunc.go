@@ -0,0 +1,106 @@
+// Copyright 2014 The llgo Authors.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+// MakeFunc and method values for llgo, whose function values are
+// {code, context} pairs rather than pointers to closures.
+
+package reflect
+
+import "unsafe"
+
+// makeFuncImpl is the context of a function made by MakeFunc. Its
+// code is the MakeFunc stub of its type, which stores the arguments
+// in a frame and calls the runtime's makeFuncStub, which calls the
+// first field with the makeFuncImpl and the frame.
+type makeFuncImpl struct {
+	call func(*makeFuncImpl, unsafe.Pointer)
+	typ  *funcType
+	fn   func([]Value) []Value
+}
+
+// MakeFunc returns a new function of the given Type
+// that wraps the function fn. When called, that new function
+// does the following:
+//
+//   - converts its arguments to a list of Values args.
+//   - runs results := fn(args).
+//   - returns the results as a slice of Values, one per formal result.
+//
+// The implementation fn can assume that the argument Value slice
+// has the number and type of arguments given by typ.
+// If typ describes a variadic function, the final Value is itself
+// a slice representing the variadic arguments, as in the
+// body of a variadic function. The result Value slice returned by fn
+// must have the number and type of results given by typ.
+//
+// The Value.Call method allows the caller to invoke a typed function
+// in terms of Values; in contrast, MakeFunc allows the caller to implement
+// a typed function in terms of Values.
+func MakeFunc(typ Type, fn func(args []Value) (results []Value)) Value {
+	if typ.Kind() != Func {
+		panic("reflect: call of MakeFunc with non-Func type")
+	}
+	t := typ.common()
+	ftyp := (*funcType)(unsafe.Pointer(t))
+	impl := &makeFuncImpl{call: callReflect, typ: ftyp, fn: fn}
+	fv := &[2]unsafe.Pointer{funcStub(unsafe.Pointer(t)), unsafe.Pointer(impl)}
+	return Value{t, unsafe.Pointer(fv), flagIndir | flag(Func)<<flagKindShift}
+}
+
+// funcStub returns the MakeFunc stub of a function type.
+// Implemented in the runtime.
+func funcStub(t unsafe.Pointer) unsafe.Pointer
+
+// methodValue is gc's representation of a method value, which is
+// used by callMethod. The method values made by makeMethodValue
+// call the method directly instead.
+type methodValue struct {
+	fn     uintptr
+	method int
+	rcvr   Value
+}
+
+// makeMethodValue converts v from the rcvr+method index representation
+// of a method value to an actual func value. The func value's code is
+// the method's interface function, which takes the receiver's word, as
+// stored in an interface value, as its first argument; that word is
+// the func value's context.
+func makeMethodValue(op string, v Value) Value {
+	if v.flag&flagMethod == 0 {
+		panic("reflect: internal error: invalid use of makeMethodValue")
+	}
+	ftyp := v.Type()
+	i := int(v.flag) >> flagMethodShift
+	rcvr := Value{v.typ, v.val, v.flag&(flagIndir|flagAddr) | flag(v.typ.Kind())<<flagKindShift}
+
+	var code, word unsafe.Pointer
+	if rcvr.Kind() == Interface {
+		// An interface value is two words, and so is
+		// stored indirectly: {itab, word}.
+		iface := (*[2]unsafe.Pointer)(rcvr.val)
+		if iface[0] == nil {
+			panic("reflect: " + op + " of method on nil interface value")
+		}
+		code = itabMethod(iface[0], i)
+		word = iface[1]
+	} else {
+		x := rcvr.Interface()
+		word = (*[2]unsafe.Pointer)(unsafe.Pointer(&x))[1]
+		code = typeMethod(unsafe.Pointer(rcvr.typ), i)
+	}
+	fv := &[2]unsafe.Pointer{code, word}
+	fl := v.flag&flagRO | flagIndir | flag(Func)<<flagKindShift
+	return Value{ftyp.common(), unsafe.Pointer(fv), fl}
+}
+
+// itabMethod and typeMethod return the code of the i'th method
+// of an itab and of a type, respectively, which takes the word
+// of an interface value as its receiver. Implemented in the runtime.
+func itabMethod(tab unsafe.Pointer, i int) unsafe.Pointer
+func typeMethod(t unsafe.Pointer, i int) unsafe.Pointer
+
+// methodValueCall is gc's code for method values, whose address
+// Value.Pointer returns for any method value. It is never called.
+// Implemented in asm_*.ll.
+func methodValueCall()
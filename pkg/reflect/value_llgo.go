@@ -0,0 +1,139 @@
+// Copyright 2014 The llgo Authors.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package reflect
+
+import "unsafe"
+
+// llgoCall is passed to call as its fn argument by valueCall:
+// the function's type, whose call shim the runtime calls with
+// the frame, and a pointer to the function value.
+type llgoCall struct {
+	typ *rtype
+	fn  unsafe.Pointer
+}
+
+// valueCall replaces Value.call, which passes call only the code
+// of a function, and not the context of an llgo function value.
+// The arguments and results are laid out in the frame as they
+// are for gc.
+//
+// #llgo replace: (reflect.Value).call
+func valueCall(v Value, op string, in []Value) []Value {
+	if v.flag&flagMethod != 0 {
+		v = makeMethodValue(op, v)
+	}
+	// A function value is a {code, context} pair, to which v.val
+	// points. (The values of Method.Func, which point into a
+	// method table, are not marked indirect, but are pairs too.)
+	t := v.typ
+	fn := v.val
+	if *(*unsafe.Pointer)(fn) == nil {
+		panic("reflect.Value.Call: call of nil function")
+	}
+
+	isSlice := op == "CallSlice"
+	n := t.NumIn()
+	if isSlice {
+		if !t.IsVariadic() {
+			panic("reflect: CallSlice of non-variadic function")
+		}
+		if len(in) < n {
+			panic("reflect: CallSlice with too few input arguments")
+		}
+		if len(in) > n {
+			panic("reflect: CallSlice with too many input arguments")
+		}
+	} else {
+		if t.IsVariadic() {
+			n--
+		}
+		if len(in) < n {
+			panic("reflect: Call with too few input arguments")
+		}
+		if !t.IsVariadic() && len(in) > n {
+			panic("reflect: Call with too many input arguments")
+		}
+	}
+	for _, x := range in {
+		if x.Kind() == Invalid {
+			panic("reflect: " + op + " using zero Value argument")
+		}
+	}
+	for i := 0; i < n; i++ {
+		if xt, targ := in[i].Type(), t.In(i); !xt.AssignableTo(targ) {
+			panic("reflect: " + op + " using " + xt.String() + " as type " + targ.String())
+		}
+	}
+	if !isSlice && t.IsVariadic() {
+		// prepare slice for remaining values
+		m := len(in) - n
+		slice := MakeSlice(t.In(n), m, m)
+		elem := t.In(n).Elem()
+		for i := 0; i < m; i++ {
+			x := in[n+i]
+			if xt := x.Type(); !xt.AssignableTo(elem) {
+				panic("reflect: cannot use " + xt.String() + " as type " + elem.String() + " in " + op)
+			}
+			slice.Index(i).Set(x)
+		}
+		origIn := in
+		in = make([]Value, n+1)
+		copy(in[:n], origIn)
+		in[n] = slice
+	}
+
+	nin := len(in)
+	if nin != t.NumIn() {
+		panic("reflect.Value.Call: wrong argument count")
+	}
+	nout := t.NumOut()
+
+	// Lay out the frame: the arguments, each aligned to its
+	// type's alignment, then the results, from the next word.
+	alignFrame := func(off, a uintptr) uintptr {
+		return (off + a - 1) &^ (a - 1)
+	}
+	off := uintptr(0)
+	argOffsets := make([]uintptr, nin)
+	for i := 0; i < nin; i++ {
+		targ := t.In(i)
+		off = alignFrame(off, uintptr(targ.Align()))
+		argOffsets[i] = off
+		off += targ.Size()
+	}
+	off = alignFrame(off, ptrSize)
+	retOffsets := make([]uintptr, nout)
+	for i := 0; i < nout; i++ {
+		tv := t.Out(i)
+		off = alignFrame(off, uintptr(tv.Align()))
+		retOffsets[i] = off
+		off += tv.Size()
+	}
+	size := off
+
+	// The frame is allocated as []uint64 so that
+	// it is aligned to 8 bytes, as the compiler's
+	// call shims expect.
+	args := unsafe.Pointer(&make([]uint64, size/8+1)[0])
+	for i, x := range in {
+		// Values obtained through unexported fields
+		// may be passed, as they may be with gc.
+		x.flag &^= flagRO
+		addr := unsafe.Pointer(uintptr(args) + argOffsets[i])
+		NewAt(t.In(i), addr).Elem().Set(x)
+	}
+
+	// Call.
+	c := llgoCall{t, fn}
+	call(unsafe.Pointer(&c), args, uint32(size))
+
+	ret := make([]Value, nout)
+	for i := 0; i < nout; i++ {
+		tv := t.Out(i)
+		fl := flagIndir | flag(tv.Kind())<<flagKindShift
+		ret[i] = Value{tv.common(), unsafe.Pointer(uintptr(args) + retOffsets[i]), fl}
+	}
+	return ret
+}
@@ -0,0 +1,38 @@
+// Copyright 2014 The llgo Authors.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package runtime
+
+// #llgo name: sync/atomic.AddInt64
+func atomicadd64(addr *int64, delta int64) int64
+
+// #llgo name: sync/atomic.LoadInt64
+func atomicload64(addr *int64) int64
+
+// #llgo name: abort
+// #llgo attr: noreturn
+func c_abort()
+
+// ncgocall is the number of calls made from C
+// into Go functions exported with cgo.
+var ncgocall int64
+
+// cgocallbackenter is called on entry to a function exported
+// with cgo, before calling the Go function. The calling thread
+// may have been created outside of the runtime, in which case
+// a G is attached to it so that the function may block.
+func cgocallbackenter() {
+	myg()
+	atomicadd64(&ncgocall, 1)
+}
+
+// cgocallbackpanic is called if a panic escapes a function
+// exported with cgo. There is no Go caller to recover the
+// panic, and it cannot unwind the C frames, so the program
+// is aborted.
+func cgocallbackpanic() {
+	printpanics()
+	println("fatal error: panic in Go function called from C")
+	c_abort()
+}
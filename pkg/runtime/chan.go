@@ -186,7 +186,6 @@ func chansend(t *chanType, c_, ptr unsafe.Pointer, nb bool) bool {
 	}
 
 	c.lock.lock()
-
 	if c.closed {
 		goto closed
 	}
@@ -195,6 +194,10 @@ func chansend(t *chanType, c_, ptr unsafe.Pointer, nb bool) bool {
 		goto asynch
 	}
 
+	// A send on a synchronous channel happens before the receive
+	// completes, and the receive happens before the send completes:
+	// each side releases the channel before handing off to the
+	// other, and acquires it once the handoff is done.
 	if sg := c.recvq.dequeue(); sg != nil {
 		c.lock.unlock()
 		gp := sg.g
@@ -203,7 +206,13 @@ func chansend(t *chanType, c_, ptr unsafe.Pointer, nb bool) bool {
 			// TODO use copy alg
 			memcpy(sg.elem, ptr, uintptr(c.elemsize))
 		}
+		if raceenabled {
+			racerelease(uintptr(c_))
+		}
 		gp.ready()
+		if raceenabled {
+			raceacquire(uintptr(c_))
+		}
 		return true
 	}
 
@@ -216,6 +225,9 @@ func chansend(t *chanType, c_, ptr unsafe.Pointer, nb bool) bool {
 	mysg.g = myg()
 	mysg.g.param = nil
 	mysg.selgen = _NOSELGEN
+	if raceenabled {
+		racerelease(uintptr(c_))
+	}
 	c.sendq.enqueue(&mysg)
 	c.lock.unlock()
 	mysg.g.park("chan send")
@@ -226,6 +238,9 @@ func chansend(t *chanType, c_, ptr unsafe.Pointer, nb bool) bool {
 		}
 		goto closed
 	}
+	if raceenabled {
+		raceacquire(uintptr(c_))
+	}
 	return true
 
 asynch:
@@ -245,6 +260,14 @@ asynch:
 		goto asynch
 	}
 
+	if raceenabled {
+		// The kth receive happens before the k+Cth send
+		// completes, and each send before its receive.
+		slot := uintptr(c.chanbuf(c.sendx))
+		raceacquire(slot)
+		racerelease(slot)
+	}
+
 	// TODO use copy alg
 	memcpy(c.chanbuf(c.sendx), ptr, uintptr(c.elemsize))
 	if c.sendx++; c.sendx == c.dataqsiz {
@@ -303,7 +326,13 @@ func chanrecv(t *chanType, c_, ptr unsafe.Pointer, nb bool) (received bool) {
 		}
 		gp := sg.g
 		gp.param = unsafe.Pointer(sg)
+		if raceenabled {
+			racerelease(uintptr(c_))
+		}
 		gp.ready()
+		if raceenabled {
+			raceacquire(uintptr(c_))
+		}
 		return true
 	}
 
@@ -319,6 +348,9 @@ func chanrecv(t *chanType, c_, ptr unsafe.Pointer, nb bool) (received bool) {
 	mysg.g = myg()
 	mysg.selgen = _NOSELGEN
 	mysg.g.param = nil
+	if raceenabled {
+		racerelease(uintptr(c_))
+	}
 	c.recvq.enqueue(&mysg)
 	c.lock.unlock()
 	mysg.g.park("chan receive")
@@ -329,6 +361,9 @@ func chanrecv(t *chanType, c_, ptr unsafe.Pointer, nb bool) (received bool) {
 		}
 		goto closed
 	}
+	if raceenabled {
+		raceacquire(uintptr(c_))
+	}
 	return true
 
 asynch:
@@ -354,6 +389,11 @@ asynch:
 		goto asynch
 	}
 
+	if raceenabled {
+		slot := uintptr(c.chanbuf(c.recvx))
+		raceacquire(slot)
+		racerelease(slot)
+	}
 	if ptr != nil {
 		// TODO use copy alg
 		memcpy(ptr, c.chanbuf(c.recvx), uintptr(c.elemsize))
@@ -374,6 +414,9 @@ asynch:
 	return true
 
 closed:
+	if raceenabled {
+		raceacquire(uintptr(c_))
+	}
 	c.lock.unlock()
 	if ptr != nil {
 		bzero(ptr, uintptr(c.elemsize))
@@ -397,6 +440,9 @@ func chanclose(c_ unsafe.Pointer) {
 		c.lock.unlock()
 		panic("close of closed channel")
 	}
+	if raceenabled {
+		racerelease(uintptr(c_))
+	}
 
 	c.closed = true
 
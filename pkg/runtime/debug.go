@@ -37,9 +37,10 @@ func NumCPU() int {
 }
 
 // NumCgoCall returns the number of cgo calls made by the current process.
+//
+// Only calls from C into Go functions exported with cgo are counted.
 func NumCgoCall() int64 {
-	// TODO
-	return 0
+	return atomicload64(&ncgocall)
 }
 
 // NumGoroutine returns the number of goroutines that currently exist.
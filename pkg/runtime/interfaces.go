@@ -46,46 +46,124 @@ func convertI2E(i iface) eface {
 
 const ptrsize = unsafe.Sizeof(uintptr(0))
 
-func convertE2I(e eface, typ unsafe.Pointer) (ok bool, result iface) {
-	if e.rtyp == nil {
-		// nil conversion
-		return true, iface{}
+// itabTableSize is the number of buckets in itabTable.
+const itabTableSize = 1009
+
+var (
+	// itabTable caches the itabs computed by getitab, keyed by
+	// interface and concrete type. Each bucket is a list linked
+	// through itab.link; lists are only ever prepended to, with
+	// itabLock held, so they may be searched without the lock.
+	itabTable [itabTableSize]*itab
+	itabLock  lock
+)
+
+// getitab returns the itab for converting values of type typ to
+// the interface type inter, or nil if typ does not implement inter.
+// Both negative and positive results are cached.
+func getitab(inter *interfaceType, typ *rtype) *itab {
+	// Type descriptors are unique to a type,
+	// so the cache is keyed by their addresses.
+	h := (uintptr(unsafe.Pointer(inter))>>3 + 17*(uintptr(unsafe.Pointer(typ))>>3)) % itabTableSize
+	for locked := false; ; locked = true {
+		head := atomicloadp(unsafe.Pointer(&itabTable[h]))
+		for tab := (*itab)(head); tab != nil; tab = tab.link {
+			if tab.inter == inter && tab.typ == typ {
+				if locked {
+					itabLock.unlock()
+				}
+				if tab.bad != 0 {
+					return nil
+				}
+				return tab
+			}
+		}
+		if locked {
+			break
+		}
+		// Search again with the lock held, in case
+		// another goroutine has added the itab since.
+		itabLock.lock()
+	}
+	tab := makeitab(inter, typ)
+	tab.link = itabTable[h]
+	atomicstorep(unsafe.Pointer(&itabTable[h]), unsafe.Pointer(tab))
+	itabLock.unlock()
+	if tab.bad != 0 {
+		return nil
+	}
+	return tab
+}
+
+// makeitab allocates and fills in an itab for converting values
+// of type typ to the interface type inter. If typ does not
+// implement inter, the itab is marked bad.
+func makeitab(inter *interfaceType, typ *rtype) *itab {
+	var tab *itab
+	size := unsafe.Sizeof(*tab) + (uintptr(len(inter.methods))-1)*ptrsize
+	tab = (*itab)(malloc(size))
+	tab.inter = inter
+	tab.typ = typ
+	if !fillitab(tab) {
+		tab.bad = 1
 	}
-	if e.rtyp.uncommonType == nil {
+	return tab
+}
+
+// fillitab fills in the method table of an itab, returning
+// false if the concrete type does not implement the interface.
+//
+// The compiler sorts the methods of both interface and concrete
+// types by their package-qualified names, so the methods of the
+// concrete type are matched in a single pass over both lists.
+func fillitab(tab *itab) bool {
+	if tab.typ.uncommonType == nil {
 		// unnamed type, cannot succeed
-		return false, iface{}
+		return false
 	}
-	targetType := (*interfaceType)(unsafe.Pointer(typ))
-	if len(targetType.methods) > len(e.rtyp.methods) {
+	imethods := tab.inter.methods
+	methods := tab.typ.methods
+	if len(imethods) > len(methods) {
 		// too few methods
-		return false, iface{}
+		return false
 	}
-	size := unsafe.Sizeof(*result.tab)
-	size += (uintptr(len(targetType.methods)) - 1) * ptrsize
-	tab := (*itab)(malloc(size))
-	for i, tm := range targetType.methods {
-		found := false
-		for _, sm := range e.rtyp.methods {
-			if *sm.name == *tm.name {
-				if eqtyp(sm.typ, tm.typ) {
-					tabptr := unsafe.Pointer(tab)
-					fnptraddr := uintptr(tabptr) + unsafe.Sizeof(*tab) - ptrsize + uintptr(i)*ptrsize
-					*(*unsafe.Pointer)(unsafe.Pointer(fnptraddr)) = sm.ifn
-					found = true
-				}
-				break
-			}
+	j := 0
+	for i := range imethods {
+		tm := &imethods[i]
+		for j < len(methods) && !(*methods[j].name == *tm.name && eqpkgpath(methods[j].pkgPath, tm.pkgPath)) {
+			j++
 		}
-		if !found {
-			free(unsafe.Pointer(tab))
-			return false, iface{}
+		// Method types are only ever compiled,
+		// so are compared by address.
+		if j == len(methods) || methods[j].mtyp != tm.typ {
+			return false
 		}
+		fnptraddr := uintptr(unsafe.Pointer(&tab.fun)) + uintptr(i)*ptrsize
+		*(*unsafe.Pointer)(unsafe.Pointer(fnptraddr)) = methods[j].ifn
+		j++
+	}
+	return true
+}
+
+// eqpkgpath reports whether two method package paths are
+// equal. Exported methods have no package path.
+func eqpkgpath(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func convertE2I(e eface, typ unsafe.Pointer) (ok bool, result iface) {
+	if e.rtyp == nil {
+		// nil conversion
+		return true, iface{}
+	}
+	tab := getitab((*interfaceType)(typ), e.rtyp)
+	if tab == nil {
+		return false, iface{}
 	}
-	result.tab = tab
-	result.data = e.data
-	result.tab.inter = targetType
-	result.tab.typ = e.rtyp
-	return true, result
+	return true, iface{tab, e.data}
 }
 
 func mustConvertE2I(e eface, typ unsafe.Pointer) (result iface) {
@@ -103,9 +181,8 @@ func mustConvertE2I(e eface, typ unsafe.Pointer) (result iface) {
 
 // #llgo name: reflect.ifaceE2I
 func reflect_ifaceE2I(t *rtype, src interface{}, dst unsafe.Pointer) {
-	// TODO
-	println("TODO: reflect.ifaceE2I")
-	llvm_trap()
+	e := *(*eface)(unsafe.Pointer(&src))
+	*(*iface)(dst) = mustConvertE2I(e, unsafe.Pointer(t))
 }
 
 func convertE2V(e eface, typ_, ptr unsafe.Pointer) bool {
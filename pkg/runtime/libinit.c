@@ -0,0 +1,16 @@
+// Copyright 2014 The llgo Authors.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+#include "asm.h"
+
+extern char **environ;
+
+char **getenviron(void) LLGO_ASM_EXPORT("runtime.getenviron");
+
+// getenviron returns the process's environment, for
+// initialising the runtime of a program built as a
+// library, which is not passed envp by "main".
+char **getenviron(void) {
+	return environ;
+}
@@ -12,6 +12,27 @@ func main_init()
 // Defined in main.ll
 func ccall(f *int8)
 
+// Defined in libinit.c
+func getenviron() **byte
+
+// #llgo name: exit
+func c_exit(status int32)
+
+// printpanics prints the values of the active panics.
+func printpanics() {
+	// XXX I guess this all needs to move somewhere
+	// else, for reuse in handling panics escaping
+	// goroutines.
+	println()
+	println("Panic:\t")
+	for p := current_panic(); p != nil; p = p.next {
+		print("\t")
+		// TODO stack trace
+		printany(p.value)
+		println()
+	}
+}
+
 // A Go program will enter this function before doing anything else.
 func main(argc int32, argv **byte, envp **byte, mainmain *int8) int32 {
 	// Initialise the runtime before calling any constructors.
@@ -20,20 +41,46 @@ func main(argc int32, argv **byte, envp **byte, mainmain *int8) int32 {
 	// All done, call "main.main".
 	var rc int32
 	onpanic := func() {
-		// XXX I guess this all needs to move somewhere
-		// else, for reuse in handling panics escaping
-		// goroutines.
-		println()
-		println("Panic:\t")
-		for p := current_panic(); p != nil; p = p.next {
-			print("\t")
-			// TODO stack trace
-			printany(p.value)
-			println()
-		}
+		printpanics()
 		rc = -1
 	}
 	guardedcall1(main_init, onpanic)
 	guardedcall1(func() { ccall(mainmain) }, onpanic)
 	return rc
 }
+
+var (
+	libinitLock lock
+	libinitDone uint32
+)
+
+// libinitThread is set on the thread running the package
+// initializers in libinit, so that any calls they make to
+// exported functions do not try to initialise again.
+// #llgo thread_local
+var libinitThread bool
+
+// libinit is called by the cgo callback of each function exported
+// to C from a program built as a C archive or shared library, which
+// has no "main" to initialise it. The first call initialises
+// the runtime and runs the package initializers; concurrent
+// calls wait for that to complete.
+func libinit() {
+	if atomicload(&libinitDone) != 0 || libinitThread {
+		return
+	}
+	libinitLock.lock()
+	if libinitDone == 0 {
+		libinitThread = true
+		// There are no command line arguments
+		// available to a library.
+		setosargs(0, nil, getenviron())
+		guardedcall1(main_init, func() {
+			printpanics()
+			c_exit(2)
+		})
+		libinitThread = false
+		xchg(&libinitDone, 1)
+	}
+	libinitLock.unlock()
+}
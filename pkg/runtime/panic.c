@@ -26,6 +26,12 @@ void callniladic(struct Func f)
 	LLGO_ASM_EXPORT("runtime.callniladic") __attribute__((noinline));
 void raise() __attribute__((noreturn));
 
+// Defined in race.c, or as no-ops in race0.go. The guarded
+// calls use them to pop the frames that a panic unwinds from
+// the race detector's shadow stack.
+uintptr_t racefuncdepth(void) LLGO_ASM_EXPORT("runtime.racefuncdepth");
+void raceunwind(uintptr_t depth) LLGO_ASM_EXPORT("runtime.raceunwind");
+
 // raise jumps to the next "defers" jmp_buf,
 // aborting if there are none remaining.
 void raise() {
@@ -111,10 +117,12 @@ void rundefers(void) {
 
 void guardedcall0(struct Func f) {
 	struct Defers defers;
+	uintptr_t racedepth = racefuncdepth();
 	initdefers(&defers);
 	if (setjmp(defers.j) == 0) {
 		callniladic(f);
 	} else {
+		raceunwind(racedepth);
 		pop_panic();
 	}
 	rundefers();
@@ -122,10 +130,12 @@ void guardedcall0(struct Func f) {
 
 void guardedcall1(struct Func f, struct Func errback) {
 	struct Defers defers;
+	uintptr_t racedepth = racefuncdepth();
 	initdefers(&defers);
 	if (setjmp(defers.j) == 0) {
 		callniladic(f);
 	} else {
+		raceunwind(racedepth);
 		callniladic(errback);
 		pop_panic();
 	}
@@ -4,24 +4,139 @@
 
 package runtime
 
-// runtime.printfloat, below, is based on code from
+import "unsafe"
+
+// The functions below implement the print and println
+// builtins, which write to standard error, formatting
+// values as gc does. They are based on code from
 // go/pkg/runtime/print.c
 //
 // Copyright 2009 The Go Authors. All rights reserved.
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-// printfloat formats a float64 into the given buffer,
-// returning the number of bytes required to represent
-// the value.
-func printfloat(v float64) string {
+// #llgo name: write
+func c_write(fd int32, buf unsafe.Pointer, n uintptr) int
+
+// gwrite writes n bytes, starting at p, to standard error.
+func gwrite(p unsafe.Pointer, n int) {
+	for n > 0 {
+		m := c_write(2, p, uintptr(n))
+		if m <= 0 {
+			return
+		}
+		p = unsafe.Pointer(uintptr(p) + uintptr(m))
+		n -= m
+	}
+}
+
+func printstring(s string) {
+	str := (*_string)(unsafe.Pointer(&s))
+	gwrite(unsafe.Pointer(str.str), str.len)
+}
+
+func printsp() {
+	printstring(" ")
+}
+
+func printnl() {
+	printstring("\n")
+}
+
+func printbool(v bool) {
+	if v {
+		printstring("true")
+	} else {
+		printstring("false")
+	}
+}
+
+func printuint(v uint64) {
+	var buf [100]byte
+	i := len(buf) - 1
+	for ; i > 0; i-- {
+		buf[i] = byte(v%10 + '0')
+		if v < 10 {
+			break
+		}
+		v /= 10
+	}
+	gwrite(unsafe.Pointer(&buf[i]), len(buf)-i)
+}
+
+func printint(v int64) {
+	if v < 0 {
+		printstring("-")
+		v = -v
+	}
+	printuint(uint64(v))
+}
+
+func printhex(v uint64) {
+	const dig = "0123456789abcdef"
+	var buf [100]byte
+	i := len(buf) - 1
+	for ; i > 0; i-- {
+		buf[i] = dig[v%16]
+		if v < 16 {
+			break
+		}
+		v /= 16
+	}
+	i--
+	buf[i] = 'x'
+	i--
+	buf[i] = '0'
+	gwrite(unsafe.Pointer(&buf[i]), len(buf)-i)
+}
+
+func printpointer(p unsafe.Pointer) {
+	printhex(uint64(uintptr(p)))
+}
+
+func printslice(s slice) {
+	printstring("[")
+	printint(int64(s.len))
+	printstring("/")
+	printint(int64(s.cap))
+	printstring("]")
+	printpointer(unsafe.Pointer(s.array))
+}
+
+func printeface(e eface) {
+	printstring("(")
+	printpointer(unsafe.Pointer(e.rtyp))
+	printstring(",")
+	printpointer(unsafe.Pointer(e.data))
+	printstring(")")
+}
+
+func printiface(i iface) {
+	printstring("(")
+	printpointer(unsafe.Pointer(i.tab))
+	printstring(",")
+	printpointer(unsafe.Pointer(i.data))
+	printstring(")")
+}
+
+func printcomplex(v complex128) {
+	printstring("(")
+	printfloat(real(v))
+	printfloat(imag(v))
+	printstring("i)")
+}
+
+func printfloat(v float64) {
 	switch {
 	case isNaN(v):
-		return "NaN"
+		printstring("NaN")
+		return
 	case v == posinf:
-		return "+Inf"
+		printstring("+Inf")
+		return
 	case v == neginf:
-		return "-Inf"
+		printstring("-Inf")
+		return
 	}
 
 	var buf [20]byte
@@ -85,5 +200,5 @@ func printfloat(v float64) string {
 	buf[n+4] = byte((e / 100) + '0')
 	buf[n+5] = byte((e/10)%10 + '0')
 	buf[n+6] = byte((e % 10) + '0')
-	return string(buf[:n+7])
+	gwrite(unsafe.Pointer(&buf[0]), int(n+7))
 }
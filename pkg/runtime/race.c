@@ -0,0 +1,89 @@
+// Copyright 2014 The llgo Authors.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+// +build race
+
+#include "asm.h"
+#include <stdint.h>
+
+// Dynamic annotations, implemented by the ThreadSanitizer runtime.
+void AnnotateHappensBefore(const char *file, int line, const volatile void *addr);
+void AnnotateHappensAfter(const char *file, int line, const volatile void *addr);
+
+// Instrumentation hooks, implemented by the ThreadSanitizer runtime.
+void __tsan_func_entry(void *pc);
+void __tsan_func_exit(void);
+void __tsan_read1(void *addr);
+void __tsan_write1(void *addr);
+void __tsan_read_range(void *addr, uintptr_t size);
+void __tsan_write_range(void *addr, uintptr_t size);
+
+void raceacquire(uintptr_t addr) LLGO_ASM_EXPORT("runtime.raceacquire");
+void racerelease(uintptr_t addr) LLGO_ASM_EXPORT("runtime.racerelease");
+void racefuncenter(uintptr_t pc) LLGO_ASM_EXPORT("runtime.racefuncenter");
+void racefuncexit(void) LLGO_ASM_EXPORT("runtime.racefuncexit");
+uintptr_t racefuncdepth(void) LLGO_ASM_EXPORT("runtime.racefuncdepth");
+void raceunwind(uintptr_t depth) LLGO_ASM_EXPORT("runtime.raceunwind");
+void raceread(uintptr_t addr) LLGO_ASM_EXPORT("runtime.raceread");
+void racewrite(uintptr_t addr) LLGO_ASM_EXPORT("runtime.racewrite");
+void racereadrange(uintptr_t addr, uintptr_t size) LLGO_ASM_EXPORT("runtime.racereadrange");
+void racewriterange(uintptr_t addr, uintptr_t size) LLGO_ASM_EXPORT("runtime.racewriterange");
+
+// racedepth is the number of frames on the calling
+// thread's ThreadSanitizer shadow stack.
+static __thread uintptr_t racedepth;
+
+void raceacquire(uintptr_t addr) {
+	AnnotateHappensAfter(__FILE__, __LINE__, (const volatile void*)addr);
+}
+
+void racerelease(uintptr_t addr) {
+	AnnotateHappensBefore(__FILE__, __LINE__, (const volatile void*)addr);
+}
+
+void racefuncenter(uintptr_t pc) {
+	racedepth++;
+	__tsan_func_entry((void*)pc);
+}
+
+void racefuncexit(void) {
+	racedepth--;
+	__tsan_func_exit();
+}
+
+uintptr_t racefuncdepth(void) {
+	return racedepth;
+}
+
+// raceunwind pops the frames skipped by a panic
+// from the shadow stack, leaving depth frames.
+void raceunwind(uintptr_t depth) {
+	while (racedepth > depth) {
+		racefuncexit();
+	}
+}
+
+void raceread(uintptr_t addr) {
+	if (addr) {
+		__tsan_read1((void*)addr);
+	}
+}
+
+void racewrite(uintptr_t addr) {
+	if (addr) {
+		__tsan_write1((void*)addr);
+	}
+}
+
+void racereadrange(uintptr_t addr, uintptr_t size) {
+	if (size) {
+		__tsan_read_range((void*)addr, size);
+	}
+}
+
+void racewriterange(uintptr_t addr, uintptr_t size) {
+	if (size) {
+		__tsan_write_range((void*)addr, size);
+	}
+}
@@ -0,0 +1,32 @@
+// Copyright 2014 The llgo Authors.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+// +build race
+
+package runtime
+
+const raceenabled = true
+
+// raceacquire and racerelease establish happens-before
+// edges in the ThreadSanitizer runtime; they are
+// implemented in race.c.
+func raceacquire(addr uintptr)
+func racerelease(addr uintptr)
+
+// racefuncenter and racefuncexit push and pop frames on the
+// ThreadSanitizer shadow stack. racefuncdepth returns the number
+// of frames pushed, and raceunwind pops frames skipped by a panic.
+func racefuncenter(pc uintptr)
+func racefuncexit()
+func racefuncdepth() uintptr
+func raceunwind(depth uintptr)
+
+// raceread and racewrite record an access to the object at
+// addr, which may be nil; instrumented code uses them for
+// map operations. racereadrange and racewriterange record
+// accesses to a range of memory, for copy and append.
+func raceread(addr uintptr)
+func racewrite(addr uintptr)
+func racereadrange(addr, size uintptr)
+func racewriterange(addr, size uintptr)
@@ -0,0 +1,22 @@
+// Copyright 2014 The llgo Authors.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+// +build !race
+
+package runtime
+
+const raceenabled = false
+
+func raceacquire(addr uintptr) {}
+func racerelease(addr uintptr) {}
+
+func racefuncenter(pc uintptr) {}
+func racefuncexit()            {}
+func racefuncdepth() uintptr   { return 0 }
+func raceunwind(depth uintptr) {}
+
+func raceread(addr uintptr)             {}
+func racewrite(addr uintptr)            {}
+func racereadrange(addr, size uintptr)  {}
+func racewriterange(addr, size uintptr) {}
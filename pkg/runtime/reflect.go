@@ -14,37 +14,233 @@ type runtimeSelect struct {
 	val runtimeIword // interface word for value (for SendDir)
 }
 
+// reflectCall is passed to reflect.call as its fn argument by
+// llgo's reflect.Value.call: the type of the function, and a
+// pointer to the function value, a {code, context} pair.
+type reflectCall struct {
+	typ *funcType
+	fn  *[2]unsafe.Pointer
+}
+
+// reflect_call calls a function with the arguments in the frame at
+// arg, storing the results after them, as gc's reflect·call does.
+// The function is called by its type's call shim, which unpacks the
+// frame and passes the arguments as they are passed in any call.
+//
 // #llgo name: reflect.call
 func reflect_call(fn, arg unsafe.Pointer, n uint32) {
-	// TODO This is going to get messy. This code will
-	// obviously need to know the calling convention,
-	// which we currently don't specify, so as to have
-	// LLVM generate the most efficient code it can.
-	//
-	// Apart from the obvious option of specifying a
-	// calling convention (C, I suppose), there is
-	// another somewhat horrible option: generate a
-	// separate shim function with a specific calling
-	// convention specifically for reflection.
-	panic("unimplemented")
+	c := (*reflectCall)(fn)
+	shim := (*int8)(c.typ.call)
+	reflectcall(shim, (*int8)(c.fn[0]), (*int8)(c.fn[1]), (*int8)(arg))
 }
 
+// reflectcall calls the shim with the code and
+// context of a function value, and a frame.
+//
+// Defined in reflect.ll.
+func reflectcall(shim, fn, context, frame *int8)
+
 // #llgo name: reflect.cacheflush
 func reflect_cacheflush(start, end *byte) {
 	panic("unimplemented")
 }
 
+// reflect_makeFuncStub is called by the stub of each function made
+// by reflect.MakeFunc, with the function's context and a frame that
+// holds its arguments. The context is the function's
+// reflect.makeFuncImpl, whose first field is the function that
+// calls its implementation with the arguments in the frame, and
+// stores the results in the frame for the stub to return.
+//
 // #llgo name: reflect.makeFuncStub
-func reflect_makeFuncStub() {
-	panic("unimplemented")
+func reflect_makeFuncStub(impl, frame unsafe.Pointer) {
+	call := *(*func(impl, frame unsafe.Pointer))(impl)
+	call(impl, frame)
+}
+
+// reflect_funcStub returns the MakeFunc stub of a function type.
+//
+// #llgo name: reflect.funcStub
+func reflect_funcStub(t unsafe.Pointer) unsafe.Pointer {
+	return (*funcType)(t).makefunc
 }
 
+// reflect_itabMethod returns the code of the i'th method of an
+// itab, which takes the interface value's word as its receiver.
+//
+// #llgo name: reflect.itabMethod
+func reflect_itabMethod(tab unsafe.Pointer, i int) unsafe.Pointer {
+	fnptraddr := uintptr(unsafe.Pointer(&(*itab)(tab).fun)) + uintptr(i)*ptrsize
+	return *(*unsafe.Pointer)(unsafe.Pointer(fnptraddr))
+}
+
+// reflect_typeMethod returns the code of the i'th method of a
+// type, which takes the word of an interface value holding a
+// value of the type as its receiver.
+//
+// #llgo name: reflect.typeMethod
+func reflect_typeMethod(t unsafe.Pointer, i int) unsafe.Pointer {
+	return (*rtype)(t).methods[i].ifn
+}
+
+// Values of runtimeSelect.dir, as reflect.SelectDir.
+const (
+	selectSend = iota + 1
+	selectRecv
+	selectDefault
+)
+
+// reflect_rselect implements reflect.Select, using the same
+// functions as the code generated for select statements. The
+// chosen case's index is returned; if it is a receive, so is
+// the interface word for the value received.
+//
 // #llgo name: reflect.rselect
-func reflect_rselect([]runtimeSelect) (chosen int, recv runtimeIword, recvOK bool) {
-	panic("unimplemented")
+func reflect_rselect(cases []runtimeSelect) (chosen int, recv runtimeIword, recvOK bool) {
+	// Values that fit in a word are received into recv;
+	// larger values are received into memory allocated
+	// for the largest of them, which becomes the word.
+	const wordsize = unsafe.Sizeof(recv)
+	var maxsize uintptr
+	for _, rc := range cases {
+		if rc.dir == selectRecv && rc.ch != nil {
+			if size := (*chanType)(unsafe.Pointer(rc.typ)).elem.size; size > maxsize {
+				maxsize = size
+			}
+		}
+	}
+	var recvbuf unsafe.Pointer
+	if maxsize > wordsize {
+		recvbuf = malloc(maxsize)
+	}
+
+	n := int32(len(cases))
+	sel := malloc(selectsize(n))
+	selectinit(n, sel)
+	for i := range cases {
+		rc := &cases[i]
+		switch rc.dir {
+		case selectDefault:
+			selectdefault(sel)
+		case selectSend:
+			// A nil channel's case is never chosen, but
+			// must still be added to keep the indices.
+			var elem unsafe.Pointer
+			if rc.ch != nil {
+				elem = unsafe.Pointer(rc.val)
+				if (*chanType)(unsafe.Pointer(rc.typ)).elem.size <= wordsize {
+					elem = unsafe.Pointer(&rc.val)
+				}
+			}
+			selectsend(sel, unsafe.Pointer(rc.ch), elem)
+		case selectRecv:
+			var elem unsafe.Pointer
+			if rc.ch != nil {
+				elem = unsafe.Pointer(&recv)
+				if (*chanType)(unsafe.Pointer(rc.typ)).elem.size > wordsize {
+					elem = recvbuf
+				}
+			}
+			selectrecv(sel, unsafe.Pointer(rc.ch), elem, &recvOK)
+		}
+	}
+	chosen = selectgo(sel)
+	free(sel)
+
+	if recvbuf != nil {
+		rc := &cases[chosen]
+		if rc.dir == selectRecv && (*chanType)(unsafe.Pointer(rc.typ)).elem.size > wordsize {
+			recv = runtimeIword(recvbuf)
+		} else {
+			free(recvbuf)
+		}
+	}
+	return chosen, recv, recvOK
+}
+
+// typelinkModule is the list of runtime types defined by a module,
+// which the compiler registers with a static constructor. The list
+// includes the pointer, slice, array, map and channel types, which
+// reflect looks up by string before constructing new types.
+type typelinkModule struct {
+	next  *typelinkModule
+	types []*rtype
 }
 
+var (
+	// typelinkModules is the list of registered modules.
+	typelinkModules *typelinkModule
+
+	// typelinks holds the types of the modules in
+	// typelinkModules, up to typelinksMerged, without
+	// duplicates and sorted by string.
+	typelinks       []*rtype
+	typelinksMerged *typelinkModule
+	typelinksLock   lock
+)
+
 // #llgo name: reflect.typelinks
 func reflect_typelinks() []*rtype {
-	panic("unimplemented")
+	typelinksLock.lock()
+	if typelinksMerged != typelinkModules {
+		// Modules are only ever added to the head of the list,
+		// e.g. when a module is loaded by a JIT, so the types
+		// merged previously are those of the tail.
+		var all []*rtype
+		for m := typelinkModules; m != nil; m = m.next {
+			all = append(all, m.types...)
+		}
+		sortTypes(all)
+		typelinks = all[:0]
+		for i, t := range all {
+			// Descriptors are unique to each type, but are
+			// listed by each module that refers to them.
+			if i == 0 || t != all[i-1] {
+				typelinks = append(typelinks, t)
+			}
+		}
+		typelinksMerged = typelinkModules
+	}
+	result := typelinks
+	typelinksLock.unlock()
+	return result
+}
+
+// typeLess orders types by their string, and then by address,
+// so that identical types are adjacent.
+func typeLess(a, b *rtype) bool {
+	if *a.string != *b.string {
+		return *a.string < *b.string
+	}
+	return uintptr(unsafe.Pointer(a)) < uintptr(unsafe.Pointer(b))
+}
+
+// sortTypes sorts the types with typeLess,
+// using heapsort.
+func sortTypes(t []*rtype) {
+	n := len(t)
+	for i := n/2 - 1; i >= 0; i-- {
+		siftTypes(t, i, n)
+	}
+	for i := n - 1; i > 0; i-- {
+		t[0], t[i] = t[i], t[0]
+		siftTypes(t, 0, i)
+	}
+}
+
+func siftTypes(t []*rtype, root, n int) {
+	for {
+		child := 2*root + 1
+		if child >= n {
+			return
+		}
+		if child+1 < n && typeLess(t[child], t[child+1]) {
+			child++
+		}
+		if !typeLess(t[root], t[child]) {
+			return
+		}
+		t[root], t[child] = t[child], t[root]
+		root = child
+	}
 }
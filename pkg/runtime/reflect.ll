@@ -0,0 +1,9 @@
+; Copyright 2014 The llgo Authors.
+; Use of this source code is governed by an MIT-style
+; license that can be found in the LICENSE file.
+
+define void @runtime.reflectcall(i8* %shim, i8* %fn, i8* %context, i8* %frame) {
+	%1 = bitcast i8* %shim to void (i8*, i8*, i8*)*
+	call void %1(i8* %fn, i8* %context, i8* %frame)
+	ret void
+}
@@ -0,0 +1,243 @@
+# Copyright 2014 The llgo Authors.
+# Use of this source code is governed by an MIT-style
+# license that can be found in the LICENSE file.
+
+"""GDB pretty printers and commands for programs compiled by llgo.
+
+llgo-build installs this script alongside the runtime. To use it,
+add the following to your .gdbinit, or enter it at the gdb prompt:
+
+    source $GOPATH/pkg/llgo/<triple>/runtime-gdb.py
+
+The printers rely on the debug information emitted by llgo, which
+describes maps, channels and interfaces with types mirroring the
+runtime's data structures (runtime.hmap<K,V>, runtime.hchan<T>,
+runtime.eface and runtime.iface).
+"""
+
+from __future__ import print_function
+
+import sys
+
+import gdb
+
+if sys.version > '3':
+	xrange = range
+
+goobjfile = gdb.current_objfile() or gdb.objfiles()[0]
+
+
+def structname(val):
+	"""Returns the name of the struct type of val, after
+	stripping typedefs, or None if val is not a struct."""
+	t = val.type.strip_typedefs()
+	if t.code != gdb.TYPE_CODE_STRUCT:
+		return None
+	return t.tag or t.name
+
+
+def pointee(val):
+	"""Returns the struct name of the type pointed to
+	by val, or None if val is not a struct pointer."""
+	t = val.type.strip_typedefs()
+	if t.code != gdb.TYPE_CODE_PTR:
+		return None
+	t = t.target().strip_typedefs()
+	if t.code != gdb.TYPE_CODE_STRUCT:
+		return None
+	return t.tag or t.name
+
+
+def gostring(val):
+	"""Returns the Python string of a Go string value."""
+	n = int(val['len'])
+	if n == 0:
+		return ""
+	ptr = val['str']
+	return ptr.string(encoding='utf-8', errors='replace', length=n)
+
+
+def typestring(rtype):
+	"""Returns the string representation of the
+	type described by a *runtime.rtype value."""
+	if rtype == 0:
+		return "nil"
+	s = rtype['string']
+	if s == 0:
+		return "?"
+	return gostring(s.dereference())
+
+
+class StringPrinter(object):
+	"Pretty print Go strings."
+
+	def __init__(self, val):
+		self.val = val
+
+	def display_hint(self):
+		return 'string'
+
+	def to_string(self):
+		return gostring(self.val)
+
+
+class SlicePrinter(object):
+	"Pretty print Go slices."
+
+	def __init__(self, val):
+		self.val = val
+
+	def display_hint(self):
+		return 'array'
+
+	def to_string(self):
+		t = str(self.val.type)
+		return '%s len %d cap %d' % (t, int(self.val['len']), int(self.val['cap']))
+
+	def children(self):
+		ptr = self.val['array']
+		for i in xrange(int(self.val['len'])):
+			yield ('[%d]' % i, (ptr + i).dereference())
+
+
+class MapPrinter(object):
+	"Pretty print Go maps."
+
+	def __init__(self, val):
+		self.val = val
+
+	def display_hint(self):
+		return 'map'
+
+	def to_string(self):
+		if self.val == 0:
+			return '%s nil' % self.val.type
+		return str(self.val.type)
+
+	def children(self):
+		if self.val == 0:
+			return
+		hmap = self.val.dereference()
+		entries = hmap['entries']
+		for i in xrange(int(hmap['count'])):
+			entry = (entries + i).dereference().dereference()
+			yield ('[%d]' % i, entry['key'])
+			yield ('[%d]' % i, entry['value'])
+
+
+class ChanPrinter(object):
+	"Pretty print Go channels."
+
+	def __init__(self, val):
+		self.val = val
+
+	def display_hint(self):
+		return 'array'
+
+	def to_string(self):
+		if self.val == 0:
+			return '%s nil' % self.val.type
+		hchan = self.val.dereference()
+		s = '%s len %d cap %d' % (self.val.type, int(hchan['qcount']), int(hchan['dataqsiz']))
+		if hchan['closed']:
+			s += ' closed'
+		return s
+
+	def children(self):
+		if self.val == 0:
+			return
+		hchan = self.val.dereference()
+		buf = hchan['buf'].address.cast(hchan['buf'].type.target().pointer())
+		size = int(hchan['dataqsiz'])
+		start = int(hchan['recvx'])
+		for i in xrange(int(hchan['qcount'])):
+			yield ('[%d]' % i, (buf + (start + i) % size).dereference())
+
+
+class InterfacePrinter(object):
+	"Pretty print Go interfaces, showing their dynamic types."
+
+	def __init__(self, val, empty):
+		self.val = val
+		self.empty = empty
+
+	def dynamic_type(self):
+		if self.empty:
+			return self.val['_type']
+		tab = self.val['tab']
+		if tab == 0:
+			return tab
+		return tab['_type']
+
+	def to_string(self):
+		rtype = self.dynamic_type()
+		if rtype == 0:
+			return '%s nil' % self.val.type
+		return '%s (%s) %s' % (self.val.type, typestring(rtype), self.val['data'])
+
+
+def lookup_printer(val):
+	name = structname(val)
+	if name is not None:
+		if name == 'string':
+			return StringPrinter(val)
+		if name.startswith('[]'):
+			return SlicePrinter(val)
+		if name == 'runtime.eface':
+			return InterfacePrinter(val, True)
+		if name == 'runtime.iface':
+			return InterfacePrinter(val, False)
+		return None
+	name = pointee(val)
+	if name is not None:
+		if name.startswith('runtime.hmap<'):
+			return MapPrinter(val)
+		if name.startswith('runtime.hchan<'):
+			return ChanPrinter(val)
+	return None
+
+goobjfile.pretty_printers.append(lookup_printer)
+
+
+class GoroutinesCmd(gdb.Command):
+	"""List the goroutines of the program.
+
+Each goroutine runs in its own thread; for each, this lists the
+thread number and the innermost Go function it is executing."""
+
+	def __init__(self):
+		gdb.Command.__init__(self, "info goroutines", gdb.COMMAND_STACK, gdb.COMPLETE_NONE)
+
+	def invoke(self, arg, from_tty):
+		selected = gdb.selected_thread()
+		try:
+			for thread in sorted(gdb.selected_inferior().threads(), key=lambda t: t.num):
+				thread.switch()
+				frame = gdb.newest_frame()
+				f = frame
+				while f is not None and not isgoframe(f):
+					f = f.older()
+				if f is None:
+					f = frame
+				mark = '*' if thread == selected else ' '
+				print('%s %d %s' % (mark, thread.num, describe(f)))
+		finally:
+			if selected is not None:
+				selected.switch()
+
+
+def isgoframe(frame):
+	"""Reports whether the frame is executing a function
+	compiled from Go, according to its symbol table."""
+	sal = frame.find_sal()
+	return sal.symtab is not None and sal.symtab.filename.endswith('.go')
+
+
+def describe(frame):
+	name = frame.name() or '??'
+	sal = frame.find_sal()
+	if sal.symtab is None:
+		return '%s ()' % name
+	return '%s () at %s:%d' % (name, sal.symtab.filename, sal.line)
+
+GoroutinesCmd()
@@ -3,8 +3,7 @@
 // license that can be found in the LICENSE file.
 
 // Software IEEE754 64-bit floating point.
-// Only referred to (and thus linked in) by arm port
-// and by tests in this directory.
+// Referred to by code compiled by llgo with -softfloat.
 
 package runtime
 
@@ -415,6 +414,25 @@ func fintto64(val int64) (f uint64) {
 	return fpack64(fs, mant, int(mantbits64), 0)
 }
 
+func fintto32(val int64) (f uint32) {
+	fs := uint64(val) & (1 << 63)
+	mant := uint64(val)
+	if fs != 0 {
+		mant = -mant
+	}
+	// Reduce the mantissa to 32 bits, keeping track of
+	// the bits shifted out, as fpack32 does, so that
+	// the result is correctly rounded.
+	exp := int(mantbits32)
+	var trunc uint32
+	for mant >= 1<<32 {
+		trunc |= uint32(mant) & 1
+		mant >>= 1
+		exp++
+	}
+	return fpack32(uint32(fs>>32), uint32(mant), exp, trunc)
+}
+
 // 64x64 -> 128 multiply.
 // adapted from hacker's delight.
 func mullu(u, v uint64) (lo, hi uint64) {
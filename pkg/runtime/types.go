@@ -81,6 +81,12 @@ type funcType struct {
 	dotdotdot bool
 	in        []*rtype
 	out       []*rtype
+
+	// call and makefunc are the function type's call shim and
+	// MakeFunc stub, which the compiler generates for each type.
+	// See reflect_call and reflect_makeFuncStub.
+	call     unsafe.Pointer
+	makefunc unsafe.Pointer
 }
 
 type structField struct {
@@ -128,6 +134,12 @@ const (
 
 // eqtyp takes two runtime types and returns true
 // iff they are equal.
+//
+// The compiler emits a single descriptor for each type
+// in a program, so types are equal iff their descriptors
+// are at the same address. Types constructed at runtime
+// by the reflect package may duplicate a compiled type's
+// descriptor, and so are compared structurally.
 func eqtyp(t1, t2 *rtype) bool {
 	if t1 == t2 {
 		return true
@@ -135,8 +147,6 @@ func eqtyp(t1, t2 *rtype) bool {
 		return false
 	}
 	if t1.kind == t2.kind {
-		// TODO check rules for type equality.
-		//
 		// Named type equality is covered in the trivial
 		// case, since there is only one definition for
 		// each named type.
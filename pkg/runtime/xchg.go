@@ -1,11 +1,19 @@
 package runtime
 
+import "unsafe"
+
 // #llgo name: sync/atomic.CompareAndSwapUint32
 func cas(addr *uint32, old, new uint32) (swapped bool)
 
 // #llgo name: sync/atomic.LoadUint32
 func atomicload(addr *uint32) uint32
 
+// #llgo name: sync/atomic.StoreUint32
+func atomicstore(addr *uint32, v uint32)
+
+// #llgo name: sync/atomic.StoreInt64
+func atomicstore64(addr *int64, v int64)
+
 func xchg(addr *uint32, new uint32) uint32 {
 	// TODO provide arch-specific implementations where possible.
 	for {
@@ -15,3 +23,20 @@ func xchg(addr *uint32, new uint32) uint32 {
 		}
 	}
 }
+
+// atomicloadp atomically loads the pointer at addr.
+func atomicloadp(addr unsafe.Pointer) unsafe.Pointer {
+	if ptrsize == unsafe.Sizeof(int64(0)) {
+		return unsafe.Pointer(uintptr(atomicload64((*int64)(addr))))
+	}
+	return unsafe.Pointer(uintptr(atomicload((*uint32)(addr))))
+}
+
+// atomicstorep atomically stores the pointer v at addr.
+func atomicstorep(addr unsafe.Pointer, v unsafe.Pointer) {
+	if ptrsize == unsafe.Sizeof(int64(0)) {
+		atomicstore64((*int64)(addr), int64(uintptr(v)))
+	} else {
+		atomicstore((*uint32)(addr), uint32(uintptr(v)))
+	}
+}
@@ -1,8 +0,0 @@
-; Copyright 2013 Andrew Wilkins.
-; Use of this source code is governed by an MIT-style
-; license that can be found in the LICENSE file.
-
-; +build ignore
-
-This file exists only to appease llgo-dist.
-
@@ -12,156 +12,118 @@ import (
 	"github.com/axw/gollvm/llvm"
 )
 
-func getPrintf(module llvm.Module) llvm.Value {
-	printf := module.NamedFunction("printf")
-	if printf.IsNil() {
-		charPtr := llvm.PointerType(llvm.Int8Type(), 0)
-		ftyp := llvm.FunctionType(llvm.Int32Type(), []llvm.Type{charPtr}, true)
-		printf = llvm.AddFunction(module, "printf", ftyp)
-		printf.SetFunctionCallConv(llvm.CCallConv)
+// printValues lowers print/println to calls to the runtime's
+// print functions, which write to standard error. println
+// separates the values with spaces, and ends with a newline.
+func (c *compiler) printValues(println_ bool, values ...Value) {
+	for i, value := range values {
+		if println_ && i > 0 {
+			c.callPrint(c.runtime.printsp)
+		}
+		c.printValue(value)
 	}
-	return printf
-}
-
-func getFflush(module llvm.Module) llvm.Value {
-	fflush := module.NamedFunction("fflush")
-	if fflush.IsNil() {
-		voidPtr := llvm.PointerType(llvm.Int8Type(), 0)
-		ftyp := llvm.FunctionType(llvm.Int32Type(), []llvm.Type{voidPtr}, false)
-		fflush = llvm.AddFunction(module, "fflush", ftyp)
-		fflush.SetFunctionCallConv(llvm.CCallConv)
+	if println_ {
+		c.callPrint(c.runtime.printnl)
 	}
-	return fflush
 }
 
-func (c *compiler) getBoolString(v llvm.Value) llvm.Value {
-	startBlock := c.builder.GetInsertBlock()
-	resultBlock := llvm.InsertBasicBlock(startBlock, "")
-	resultBlock.MoveAfter(startBlock)
-	falseBlock := llvm.InsertBasicBlock(resultBlock, "")
-
-	CharPtr := llvm.PointerType(llvm.Int8Type(), 0)
-	falseString := c.builder.CreateGlobalStringPtr("false", "")
-	falseString = c.builder.CreateBitCast(falseString, CharPtr, "")
-	trueString := c.builder.CreateGlobalStringPtr("true", "")
-	trueString = c.builder.CreateBitCast(trueString, CharPtr, "")
-
-	c.builder.CreateCondBr(v, resultBlock, falseBlock)
-	c.builder.SetInsertPointAtEnd(falseBlock)
-	c.builder.CreateBr(resultBlock)
-	c.builder.SetInsertPointAtEnd(resultBlock)
-	result := c.builder.CreatePHI(CharPtr, "")
-	result.AddIncoming([]llvm.Value{trueString, falseString},
-		[]llvm.BasicBlock{startBlock, falseBlock})
-	return result
-}
-
-func (c *compiler) printValues(println_ bool, values ...Value) {
-	var args []llvm.Value = nil
-	if len(values) > 0 {
-		format := ""
-		args = make([]llvm.Value, 0, len(values)+1)
-		for i, value := range values {
-			llvm_value := value.LLVMValue()
-
-			typ := value.Type().Underlying()
-			if name, isname := typ.(*types.Named); isname {
-				typ = name.Underlying()
+// printValue prints a single value with the
+// runtime print function for its type.
+func (c *compiler) printValue(value Value) {
+	llv := value.LLVMValue()
+	switch typ := value.Type().Underlying().(type) {
+	case *types.Basic:
+		switch typ.Kind() {
+		case types.Bool, types.UntypedBool:
+			c.callPrint(c.runtime.printbool, llv)
+		case types.Int, types.Int8, types.Int16, types.Int32, types.Int64:
+			if llv.Type().IntTypeWidth() < 64 {
+				llv = c.builder.CreateSExt(llv, llvm.Int64Type(), "")
 			}
-
-			if println_ && i > 0 {
-				format += " "
+			c.callPrint(c.runtime.printint, llv)
+		case types.Uint, types.Uint8, types.Uint16, types.Uint32, types.Uint64, types.Uintptr:
+			if llv.Type().IntTypeWidth() < 64 {
+				llv = c.builder.CreateZExt(llv, llvm.Int64Type(), "")
 			}
-			switch typ := typ.(type) {
-			case *types.Basic:
-				switch typ.Kind() {
-				case types.Uint8:
-					format += "%hhu"
-				case types.Uint16:
-					format += "%hu"
-				case types.Uint32:
-					format += "%u"
-				case types.Uintptr, types.Uint:
-					format += "%lu"
-				case types.Uint64:
-					format += "%llu" // FIXME windows
-				case types.Int:
-					format += "%ld"
-				case types.Int8:
-					format += "%hhd"
-				case types.Int16:
-					format += "%hd"
-				case types.Int32:
-					format += "%d"
-				case types.Int64:
-					format += "%lld" // FIXME windows
-				case types.Float32:
-					llvm_value = c.builder.CreateFPExt(llvm_value, llvm.DoubleType(), "")
-					fallthrough
-				case types.Float64:
-					printfloat := c.runtime.printfloat.LLVMValue()
-					args := []llvm.Value{llvm_value}
-					llvm_value = c.builder.CreateCall(printfloat, args, "")
-					fallthrough
-				case types.String, types.UntypedString:
-					ptrval := c.builder.CreateExtractValue(llvm_value, 0, "")
-					lenval := c.builder.CreateExtractValue(llvm_value, 1, "")
-					llvm_value = ptrval
-					args = append(args, lenval)
-					format += "%.*s"
-				case types.Bool:
-					format += "%s"
-					llvm_value = c.getBoolString(llvm_value)
-				case types.UnsafePointer:
-					format += "%p"
-				default:
-					panic(fmt.Sprint("Unhandled Basic Kind: ", typ.Kind))
-				}
+			c.callPrint(c.runtime.printuint, llv)
+		case types.Float32, types.Float64:
+			c.callPrint(c.runtime.printfloat, llv)
+		case types.Complex64, types.Complex128:
+			c.callPrint(c.runtime.printcomplex, llv)
+		case types.String, types.UntypedString:
+			c.callPrint(c.runtime.printstring, llv)
+		case types.UnsafePointer:
+			c.callPrint(c.runtime.printpointer, llv)
+		default:
+			panic(fmt.Sprint("Unhandled Basic Kind: ", typ.Kind()))
+		}
 
-			case *types.Interface:
-				format += "(0x%lx,0x%lx)"
-				ival := c.builder.CreateExtractValue(llvm_value, 0, "")
-				itype := c.builder.CreateExtractValue(llvm_value, 1, "")
-				args = append(args, ival)
-				llvm_value = itype
+	case *types.Interface:
+		if typ.NumMethods() == 0 {
+			c.callPrint(c.runtime.printeface, llv)
+		} else {
+			c.callPrint(c.runtime.printiface, llv)
+		}
 
-			case *types.Slice, *types.Array:
-				// If we see a constant array, we either:
-				//     Create an internal constant if it's a constant array, or
-				//     Create space on the stack and store it there.
-				init_ := value.(*LLVMValue)
-				init_value := init_.LLVMValue()
-				llvm_value = c.builder.CreateAlloca(init_value.Type(), "")
-				c.builder.CreateStore(init_value, llvm_value)
-				// FIXME don't assume string...
-				format += "%s"
+	case *types.Slice:
+		c.callPrint(c.runtime.printslice, llv)
 
-			case *types.Pointer:
-				format += "0x%lx"
+	case *types.Signature:
+		// Print the function pointer, ignoring the context.
+		c.callPrint(c.runtime.printpointer, c.builder.CreateExtractValue(llv, 0, ""))
 
-			default:
-				panic(fmt.Sprintf("Unhandled type kind: %s (%T)", typ, typ))
-			}
+	case *types.Pointer, *types.Map, *types.Chan:
+		c.callPrint(c.runtime.printpointer, llv)
+
+	default:
+		panic(fmt.Sprintf("Unhandled type kind: %s (%T)", typ, typ))
+	}
+}
 
-			args = append(args, llvm_value)
+// callPrint calls a runtime print function, converting the
+// argument, if any, to the type of the function's parameter.
+func (c *compiler) callPrint(fn *LLVMValue, args ...llvm.Value) {
+	llfn := fn.LLVMValue()
+	paramTypes := llfn.Type().ElementType().ParamTypes()
+	for i, arg := range args {
+		args[i] = c.convertPrintArg(arg, paramTypes[i])
+	}
+	c.builder.CreateCall(llfn, args, "")
+}
+
+func (c *compiler) convertPrintArg(v llvm.Value, typ llvm.Type) llvm.Value {
+	vtyp := v.Type()
+	if vtyp == typ {
+		return v
+	}
+	switch typ.TypeKind() {
+	case llvm.IntegerTypeKind:
+		// unsafe.Pointer is represented as an integer.
+		if vtyp.TypeKind() == llvm.PointerTypeKind {
+			return c.builder.CreatePtrToInt(v, typ, "")
+		}
+	case llvm.DoubleTypeKind:
+		if c.SoftFloat {
+			return c.softFloatConvert(v, false, typ, false)
 		}
-		if println_ {
-			format += "\n"
+		return c.builder.CreateFPExt(v, typ, "")
+	case llvm.PointerTypeKind:
+		if vtyp.TypeKind() == llvm.IntegerTypeKind {
+			return c.builder.CreateIntToPtr(v, typ, "")
 		}
-		formatval := c.builder.CreateGlobalStringPtr(format, "")
-		args = append([]llvm.Value{formatval}, args...)
-	} else {
-		var format string
-		if println_ {
-			format = "\n"
+		return c.builder.CreateBitCast(v, typ, "")
+	case llvm.StructTypeKind:
+		// Strings, slices, interfaces and complex numbers
+		// are converted element by element.
+		result := llvm.Undef(typ)
+		for i, elemtyp := range typ.StructElementTypes() {
+			elem := c.builder.CreateExtractValue(v, i, "")
+			elem = c.convertPrintArg(elem, elemtyp)
+			result = c.builder.CreateInsertValue(result, elem, i, "")
 		}
-		args = []llvm.Value{c.builder.CreateGlobalStringPtr(format, "")}
+		return result
 	}
-	printf := getPrintf(c.module.Module)
-	c.NewValue(c.builder.CreateCall(printf, args, ""), types.Typ[types.Int32])
-	fflush := getFflush(c.module.Module)
-	fileptr := llvm.ConstNull(fflush.Type().ElementType().ParamTypes()[0])
-	c.builder.CreateCall(fflush, []llvm.Value{fileptr}, "")
+	return coerce(c.builder, v, typ)
 }
 
 func (c *compiler) printf(format string, args ...interface{}) {
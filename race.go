@@ -0,0 +1,184 @@
+// Copyright 2014 The llgo Authors.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package llgo
+
+import (
+	"fmt"
+
+	"code.google.com/p/go.tools/go/ssa"
+	"github.com/axw/gollvm/llvm"
+)
+
+// raceSync identifies the happens-before edges introduced
+// by a call to a function that synchronises on the memory
+// addressed by its first argument.
+type raceSync int
+
+const (
+	// raceRelease indicates that a release is performed
+	// on the address before the call.
+	raceRelease raceSync = 1 << iota
+
+	// raceAcquire indicates that an acquire is performed
+	// on the address after the call.
+	raceAcquire
+)
+
+// raceEnabled reports whether the code being generated should
+// be instrumented for the race detector. The runtime package
+// is never instrumented; it is the runtime that establishes
+// the happens-before relations between goroutines.
+func (c *compiler) raceEnabled() bool {
+	return c.RaceDetector && c.module.Name != "runtime"
+}
+
+// raceFunction returns the ThreadSanitizer runtime function
+// with the specified name, declaring it if necessary.
+func (c *compiler) raceFunction(name string, result llvm.Type, params ...llvm.Type) llvm.Value {
+	f := c.module.NamedFunction(name)
+	if f.IsNil() {
+		ftyp := llvm.FunctionType(result, params, false)
+		f = llvm.AddFunction(c.module.Module, name, ftyp)
+		f.SetFunctionCallConv(llvm.CCallConv)
+	}
+	return f
+}
+
+// raceFuncEnter records entry to the current function in the
+// race detector's shadow stack, so reports include a stack trace.
+func (c *compiler) raceFuncEnter() {
+	i8ptr := llvm.PointerType(llvm.Int8Type(), 0)
+	retaddr := c.raceFunction("llvm.returnaddress", i8ptr, llvm.Int32Type())
+	pc := c.builder.CreateCall(retaddr, []llvm.Value{llvm.ConstNull(llvm.Int32Type())}, "")
+	pc = c.builder.CreatePtrToInt(pc, c.target.IntPtrType(), "")
+	c.builder.CreateCall(c.runtime.racefuncenter.LLVMValue(), []llvm.Value{pc}, "")
+}
+
+// raceFuncExit records exit from the current function.
+func (c *compiler) raceFuncExit() {
+	c.builder.CreateCall(c.runtime.racefuncexit.LLVMValue(), nil, "")
+}
+
+// raceFuncDepth returns the depth of the shadow stack,
+// which is restored by raceUnwind when a panic unwinds
+// to the current function.
+func (c *compiler) raceFuncDepth() llvm.Value {
+	return c.builder.CreateCall(c.runtime.racefuncdepth.LLVMValue(), nil, "")
+}
+
+// raceUnwind pops the frames that a panic longjmped over
+// from the shadow stack, as they never call raceFuncExit.
+func (c *compiler) raceUnwind(depth llvm.Value) {
+	c.builder.CreateCall(c.runtime.raceunwind.LLVMValue(), []llvm.Value{depth}, "")
+}
+
+// raceAccess records a read or write of a value of the specified
+// LLVM type at the specified address.
+func (c *compiler) raceAccess(addr llvm.Value, typ llvm.Type, write bool) {
+	size := c.target.TypeStoreSize(typ)
+	if size == 0 {
+		return
+	}
+	kind := "read"
+	if write {
+		kind = "write"
+	}
+	i8ptr := llvm.PointerType(llvm.Int8Type(), 0)
+	addr = c.builder.CreateBitCast(addr, i8ptr, "")
+	switch size {
+	case 1, 2, 4, 8, 16:
+		name := fmt.Sprintf("__tsan_%s%d", kind, size)
+		f := c.raceFunction(name, llvm.VoidType(), i8ptr)
+		c.builder.CreateCall(f, []llvm.Value{addr}, "")
+	default:
+		uintptr := c.target.IntPtrType()
+		f := c.raceFunction("__tsan_"+kind+"_range", llvm.VoidType(), i8ptr, uintptr)
+		c.builder.CreateCall(f, []llvm.Value{addr, llvm.ConstInt(uintptr, size, false)}, "")
+	}
+}
+
+// raceMapAccess records a read or write of the map m.
+// Map operations are implemented by the runtime, which is
+// not instrumented, so the map as a whole is treated as
+// a single object.
+func (c *compiler) raceMapAccess(m llvm.Value, write bool) {
+	f := c.runtime.raceread
+	if write {
+		f = c.runtime.racewrite
+	}
+	c.builder.CreateCall(f.LLVMValue(), []llvm.Value{m}, "")
+}
+
+// raceRangeAccess records a read or write of the memory
+// holding n elements of type elemtyp in the slice (or
+// string) s, from index i. It is used for the elements
+// copied by copy and append.
+func (c *compiler) raceRangeAccess(s, i, n llvm.Value, elemtyp llvm.Type, write bool) {
+	f := c.runtime.racereadrange
+	if write {
+		f = c.runtime.racewriterange
+	}
+	uintptr := c.target.IntPtrType()
+	elemsize := llvm.ConstInt(uintptr, c.target.TypeAllocSize(elemtyp), false)
+	addr := c.builder.CreatePtrToInt(c.builder.CreateExtractValue(s, 0, ""), uintptr, "")
+	i = c.builder.CreateZExtOrBitCast(i, uintptr, "")
+	n = c.builder.CreateZExtOrBitCast(n, uintptr, "")
+	addr = c.builder.CreateAdd(addr, c.builder.CreateMul(i, elemsize, ""), "")
+	size := c.builder.CreateMul(n, elemsize, "")
+	c.builder.CreateCall(f.LLVMValue(), []llvm.Value{addr, size}, "")
+}
+
+// raceAcquire emits a call to runtime.raceacquire, which
+// establishes a happens-before edge from the last release
+// on the same address.
+func (c *compiler) raceAcquire(addr llvm.Value) {
+	c.raceSyncCall(c.runtime.raceacquire, addr)
+}
+
+// raceRelease emits a call to runtime.racerelease.
+func (c *compiler) raceRelease(addr llvm.Value) {
+	c.raceSyncCall(c.runtime.racerelease, addr)
+}
+
+func (c *compiler) raceSyncCall(f *LLVMValue, addr llvm.Value) {
+	if addr.Type().TypeKind() == llvm.PointerTypeKind {
+		addr = c.builder.CreatePtrToInt(addr, c.target.IntPtrType(), "")
+	}
+	c.builder.CreateCall(f.LLVMValue(), []llvm.Value{addr}, "")
+}
+
+// raceInstrumentAddr reports whether memory accesses through
+// the specified address should be instrumented. Stack slots
+// that do not escape cannot be shared between goroutines.
+func raceInstrumentAddr(addr ssa.Value) bool {
+	if alloc, ok := addr.(*ssa.Alloc); ok && !alloc.Heap {
+		return false
+	}
+	return true
+}
+
+// raceSyncOf returns the happens-before edges introduced by
+// a static call. The sync/atomic operations are treated as
+// both a release and an acquire on the address operated on,
+// and the semaphore functions that package sync is built on
+// are treated as an acquire or a release on the semaphore.
+func raceSyncOf(call *ssa.CallCommon) raceSync {
+	f, ok := call.Value.(*ssa.Function)
+	if !ok || f.Pkg == nil || len(call.Args) == 0 {
+		return 0
+	}
+	switch f.Pkg.Object.Path() {
+	case "sync/atomic":
+		return raceRelease | raceAcquire
+	case "sync":
+		switch f.Name() {
+		case "runtime_Semacquire", "runtime_Syncsemacquire":
+			return raceAcquire
+		case "runtime_Semrelease", "runtime_Syncsemrelease":
+			return raceRelease
+		}
+	}
+	return 0
+}
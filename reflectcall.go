@@ -0,0 +1,217 @@
+// Copyright 2014 The llgo Authors.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package llgo
+
+import (
+	"code.google.com/p/go.tools/go/types"
+	"github.com/axw/gollvm/llvm"
+)
+
+// reflect.Value.Call and reflect.MakeFunc pass arguments and
+// results in a frame laid out as gc's reflect·call expects: each
+// argument is aligned to its type's alignment, and the results
+// follow at the next word boundary, each aligned in turn. For each
+// function type, the compiler emits a call shim that unpacks such
+// a frame, and a stub for the functions made by MakeFunc that
+// packs one; their addresses are stored in the type's descriptor.
+
+// frameMaxAlign is the alignment of the frames allocated by
+// reflect and by the MakeFunc stubs. Values whose types have
+// a greater alignment (vectors) are loaded and stored with
+// this alignment instead.
+const frameMaxAlign = 8
+
+// frameOffsets returns the offsets of a function's
+// arguments and results in a frame, and the frame's size.
+func (tm *TypeMap) frameOffsets(f *types.Signature) (params, results []int64, size int64) {
+	var off int64
+	field := func(t types.Type) int64 {
+		a := tm.Alignof(t)
+		off = (off + a - 1) &^ (a - 1)
+		fieldoff := off
+		off += tm.Sizeof(t)
+		return fieldoff
+	}
+	params = make([]int64, f.Params().Len())
+	for i := range params {
+		params[i] = field(f.Params().At(i).Type())
+	}
+	ptrsize := int64(tm.target.PointerSize())
+	off = (off + ptrsize - 1) &^ (ptrsize - 1)
+	results = make([]int64, f.Results().Len())
+	for i := range results {
+		results[i] = field(f.Results().At(i).Type())
+	}
+	return params, results, off
+}
+
+// framePtr returns a pointer to the value of type t
+// at the specified offset in the frame.
+func (tm *TypeMap) framePtr(b llvm.Builder, frame llvm.Value, off int64, t types.Type) llvm.Value {
+	ptr := b.CreateGEP(frame, []llvm.Value{llvm.ConstInt(tm.target.IntPtrType(), uint64(off), false)}, "")
+	return b.CreateBitCast(ptr, llvm.PointerType(tm.ToLLVM(t), 0), "")
+}
+
+// frameAlign returns the alignment with which a
+// value of type t is loaded from or stored to a frame.
+func (tm *TypeMap) frameAlign(t types.Type) int {
+	if a := tm.Alignof(t); a < frameMaxAlign {
+		return int(a)
+	}
+	return frameMaxAlign
+}
+
+// contextFuncType returns the type of the code pointer of a
+// function value, as it is called dynamically: with the function
+// value's context as its first argument.
+func (tm *TypeMap) contextFuncType(f *types.Signature) llvm.Type {
+	fntyp := tm.ToLLVM(f).StructElementTypes()[0].ElementType()
+	i8ptr := llvm.PointerType(llvm.Int8Type(), 0)
+	return llvm.FunctionType(
+		fntyp.ReturnType(),
+		append([]llvm.Type{i8ptr}, fntyp.ParamTypes()...),
+		fntyp.IsFunctionVarArg(),
+	)
+}
+
+// reflectCallShim returns the call shim of the function type,
+// which reflect.call calls with the code and context of a
+// function value and a frame holding the arguments:
+//
+//	void shim(i8* fn, i8* context, i8* frame)
+//
+// The shim calls fn(context, args...), and stores the results
+// in the frame.
+func (tm *TypeMap) reflectCallShim(f *types.Signature) llvm.Value {
+	name := "__llgo.reflectcall." + typeString(f)
+	if shim := tm.module.NamedFunction(name); !shim.IsNil() {
+		return shim
+	}
+	i8ptr := llvm.PointerType(llvm.Int8Type(), 0)
+	shimtyp := llvm.FunctionType(llvm.VoidType(), []llvm.Type{i8ptr, i8ptr, i8ptr}, false)
+	shim := llvm.AddFunction(tm.module, name, shimtyp)
+	shim.SetLinkage(llvm.LinkOnceODRLinkage)
+
+	b := llvm.GlobalContext().NewBuilder()
+	defer b.Dispose()
+	b.SetInsertPointAtEnd(llvm.AddBasicBlock(shim, "entry"))
+	fn, context, frame := shim.Param(0), shim.Param(1), shim.Param(2)
+
+	paramOffsets, resultOffsets, _ := tm.frameOffsets(f)
+	args := []llvm.Value{context}
+	for i, off := range paramOffsets {
+		t := f.Params().At(i).Type()
+		arg := b.CreateLoad(tm.framePtr(b, frame, off, t), "")
+		arg.SetAlignment(tm.frameAlign(t))
+		args = append(args, arg)
+	}
+	fn = b.CreateBitCast(fn, llvm.PointerType(tm.contextFuncType(f), 0), "")
+	result := b.CreateCall(fn, args, "")
+	for i, off := range resultOffsets {
+		t := f.Results().At(i).Type()
+		value := result
+		if len(resultOffsets) > 1 {
+			value = b.CreateExtractValue(result, i, "")
+		}
+		store := b.CreateStore(value, tm.framePtr(b, frame, off, t))
+		store.SetAlignment(tm.frameAlign(t))
+	}
+	b.CreateRetVoid()
+	return shim
+}
+
+// makeFuncStub returns the code of the functions of the type that
+// are made by reflect.MakeFunc. The stub is called as any function
+// value is, with the function's reflect.makeFuncImpl as its context.
+// It stores its arguments in a frame, passes the context and frame
+// to the runtime's reflect.makeFuncStub, which calls the function's
+// implementation, and returns the results stored in the frame.
+func (tm *TypeMap) makeFuncStub(f *types.Signature) llvm.Value {
+	name := "__llgo.makefunc." + typeString(f)
+	if stub := tm.module.NamedFunction(name); !stub.IsNil() {
+		return stub
+	}
+	i8ptr := llvm.PointerType(llvm.Int8Type(), 0)
+	intptr := tm.target.IntPtrType()
+	entry := tm.module.NamedFunction("reflect.makeFuncStub")
+	if entry.IsNil() {
+		// func(impl, frame unsafe.Pointer)
+		entrytyp := llvm.FunctionType(llvm.VoidType(), []llvm.Type{intptr, intptr}, false)
+		entry = llvm.AddFunction(tm.module, "reflect.makeFuncStub", entrytyp)
+	}
+	stubtyp := tm.contextFuncType(f)
+	stub := llvm.AddFunction(tm.module, name, stubtyp)
+	stub.SetLinkage(llvm.LinkOnceODRLinkage)
+
+	b := llvm.GlobalContext().NewBuilder()
+	defer b.Dispose()
+	b.SetInsertPointAtEnd(llvm.AddBasicBlock(stub, "entry"))
+
+	// The frame is allocated as an array of
+	// i64, so that it is aligned to 8 bytes.
+	paramOffsets, resultOffsets, size := tm.frameOffsets(f)
+	nwords := (size + frameMaxAlign - 1) / frameMaxAlign
+	if nwords == 0 {
+		nwords = 1
+	}
+	frame := b.CreateAlloca(llvm.ArrayType(llvm.Int64Type(), int(nwords)), "")
+	frame = b.CreateBitCast(frame, i8ptr, "")
+	for i, off := range paramOffsets {
+		t := f.Params().At(i).Type()
+		store := b.CreateStore(stub.Param(i+1), tm.framePtr(b, frame, off, t))
+		store.SetAlignment(tm.frameAlign(t))
+	}
+	impl := b.CreatePtrToInt(stub.Param(0), intptr, "")
+	b.CreateCall(entry, []llvm.Value{impl, b.CreatePtrToInt(frame, intptr, "")}, "")
+	results := make([]llvm.Value, len(resultOffsets))
+	for i, off := range resultOffsets {
+		t := f.Results().At(i).Type()
+		results[i] = b.CreateLoad(tm.framePtr(b, frame, off, t), "")
+		results[i].SetAlignment(tm.frameAlign(t))
+	}
+	switch len(results) {
+	case 0:
+		b.CreateRetVoid()
+	case 1:
+		b.CreateRet(results[0])
+	default:
+		b.CreateAggregateRet(results)
+	}
+	return stub
+}
+
+// methodContextFunc returns a wrapper of a method function,
+// which takes the receiver as its first parameter, that has an
+// additional first parameter for a function value's context, and
+// ignores it. The wrapper is the tfn of the method in its type's
+// method table, which reflect calls as the code of a function value.
+func (tm *TypeMap) methodContextFunc(f llvm.Value) llvm.Value {
+	name := f.Name() + ".tfn"
+	if newf := f.GlobalParent().NamedFunction(name); !newf.IsNil() {
+		return newf
+	}
+	ftyp := f.Type().ElementType()
+	i8ptr := llvm.PointerType(llvm.Int8Type(), 0)
+	newf := llvm.AddFunction(f.GlobalParent(), name, llvm.FunctionType(
+		ftyp.ReturnType(),
+		append([]llvm.Type{i8ptr}, ftyp.ParamTypes()...),
+		ftyp.IsFunctionVarArg(),
+	))
+
+	b := llvm.GlobalContext().NewBuilder()
+	defer b.Dispose()
+	b.SetInsertPointAtEnd(llvm.AddBasicBlock(newf, "entry"))
+	args := make([]llvm.Value, len(ftyp.ParamTypes()))
+	for i := range args {
+		args[i] = newf.Param(i + 1)
+	}
+	result := b.CreateCall(f, args, "")
+	if result.Type().TypeKind() == llvm.VoidTypeKind {
+		b.CreateRetVoid()
+	} else {
+		b.CreateRet(result)
+	}
+	return newf
+}
@@ -65,7 +65,20 @@ type runtimeInterface struct {
 	stacksave,
 	setjmp,
 	main,
+	cgocallbackenter,
+	cgocallbackpanic,
+	printbool,
+	printcomplex,
+	printeface,
 	printfloat,
+	printiface,
+	printint,
+	printnl,
+	printpointer,
+	printslice,
+	printsp,
+	printstring,
+	printuint,
 	makemap,
 	makechan,
 	malloc,
@@ -79,6 +92,17 @@ type runtimeInterface struct {
 	memset,
 	panic_,
 	pushdefer,
+	libinit,
+	raceacquire,
+	racerelease,
+	racefuncenter,
+	racefuncexit,
+	racefuncdepth,
+	raceunwind,
+	raceread,
+	racewrite,
+	racereadrange,
+	racewriterange,
 	recover_,
 	rundefers,
 	chancap,
@@ -105,7 +129,18 @@ type runtimeInterface struct {
 	f32eqalg,
 	f64eqalg,
 	c64eqalg,
-	c128eqalg *LLVMValue
+	c128eqalg,
+	fadd64,
+	fsub64,
+	fmul64,
+	fdiv64,
+	fneg64,
+	f32to64,
+	f64to32,
+	fcmp64,
+	f64toint,
+	fintto32,
+	fintto64 *LLVMValue
 }
 
 func newRuntimeInterface(pkg *types.Package, module llvm.Module, tm *llvmTypeMap, fr FuncResolver) (*runtimeInterface, error) {
@@ -156,7 +191,20 @@ func newRuntimeInterface(pkg *types.Package, module llvm.Module, tm *llvmTypeMap
 		"llvm_stacksave":    &ri.stacksave,
 		"llvm_setjmp":       &ri.setjmp,
 		"main":              &ri.main,
+		"cgocallbackenter":  &ri.cgocallbackenter,
+		"cgocallbackpanic":  &ri.cgocallbackpanic,
+		"printbool":         &ri.printbool,
+		"printcomplex":      &ri.printcomplex,
+		"printeface":        &ri.printeface,
 		"printfloat":        &ri.printfloat,
+		"printiface":        &ri.printiface,
+		"printint":          &ri.printint,
+		"printnl":           &ri.printnl,
+		"printpointer":      &ri.printpointer,
+		"printslice":        &ri.printslice,
+		"printsp":           &ri.printsp,
+		"printstring":       &ri.printstring,
+		"printuint":         &ri.printuint,
 		"makechan":          &ri.makechan,
 		"makemap":           &ri.makemap,
 		"malloc":            &ri.malloc,
@@ -170,6 +218,17 @@ func newRuntimeInterface(pkg *types.Package, module llvm.Module, tm *llvmTypeMap
 		"memset":            &ri.memset,
 		"panic_":            &ri.panic_,
 		"pushdefer":         &ri.pushdefer,
+		"libinit":           &ri.libinit,
+		"raceacquire":       &ri.raceacquire,
+		"racerelease":       &ri.racerelease,
+		"racefuncenter":     &ri.racefuncenter,
+		"racefuncexit":      &ri.racefuncexit,
+		"racefuncdepth":     &ri.racefuncdepth,
+		"raceunwind":        &ri.raceunwind,
+		"raceread":          &ri.raceread,
+		"racewrite":         &ri.racewrite,
+		"racereadrange":     &ri.racereadrange,
+		"racewriterange":    &ri.racewriterange,
 		"recover_":          &ri.recover_,
 		"rundefers":         &ri.rundefers,
 		"chancap":           &ri.chancap,
@@ -197,6 +256,17 @@ func newRuntimeInterface(pkg *types.Package, module llvm.Module, tm *llvmTypeMap
 		"f64eqalg":          &ri.f64eqalg,
 		"c64eqalg":          &ri.c64eqalg,
 		"c128eqalg":         &ri.c128eqalg,
+		"fadd64":            &ri.fadd64,
+		"fsub64":            &ri.fsub64,
+		"fmul64":            &ri.fmul64,
+		"fdiv64":            &ri.fdiv64,
+		"fneg64":            &ri.fneg64,
+		"f32to64":           &ri.f32to64,
+		"f64to32":           &ri.f64to32,
+		"fcmp64":            &ri.fcmp64,
+		"f64toint":          &ri.f64toint,
+		"fintto32":          &ri.fintto32,
+		"fintto64":          &ri.fintto64,
 	}
 	for name, field := range intrinsics {
 		obj := pkg.Scope().Lookup(name)
@@ -0,0 +1,307 @@
+// Copyright 2014 The llgo Authors.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package llgo
+
+import (
+	"go/token"
+
+	"code.google.com/p/go.tools/go/ssa"
+	"code.google.com/p/go.tools/go/types"
+	"github.com/axw/gollvm/llvm"
+)
+
+// simdPackagePath is the import path of the package whose named
+// array types are represented by LLVM vector types.
+const simdPackagePath = "github.com/axw/llgo/pkg/llgo/simd"
+
+// isSIMDType reports whether t is one of the vector
+// types declared in the simd package.
+func isSIMDType(t types.Type) bool {
+	n, ok := t.(*types.Named)
+	if !ok {
+		return false
+	}
+	if pkg := n.Obj().Pkg(); pkg == nil || pkg.Path() != simdPackagePath {
+		return false
+	}
+	a, ok := n.Underlying().(*types.Array)
+	if !ok {
+		return false
+	}
+	b, ok := a.Elem().Underlying().(*types.Basic)
+	return ok && b.Info()&(types.IsInteger|types.IsFloat) != 0
+}
+
+func (tm *llvmTypeMap) vectorLLVMType(a *types.Array) llvm.Type {
+	return llvm.VectorType(tm.ToLLVM(a.Elem()), int(a.Len()))
+}
+
+// convertVector converts between a vector value and an array
+// value with the same elements, element by element. Values of
+// other types are returned unchanged.
+func (c *compiler) convertVector(v llvm.Value, t llvm.Type) llvm.Value {
+	vt := v.Type()
+	switch {
+	case vt == t:
+		return v
+	case vt.TypeKind() == llvm.VectorTypeKind && t.TypeKind() == llvm.ArrayTypeKind:
+		result := llvm.Undef(t)
+		for i := 0; i < vt.VectorSize(); i++ {
+			index := llvm.ConstInt(llvm.Int32Type(), uint64(i), false)
+			elem := c.builder.CreateExtractElement(v, index, "")
+			result = c.builder.CreateInsertValue(result, elem, i, "")
+		}
+		return result
+	case vt.TypeKind() == llvm.ArrayTypeKind && t.TypeKind() == llvm.VectorTypeKind:
+		result := llvm.Undef(t)
+		for i := 0; i < t.VectorSize(); i++ {
+			index := llvm.ConstInt(llvm.Int32Type(), uint64(i), false)
+			elem := c.builder.CreateExtractValue(v, i, "")
+			result = c.builder.CreateInsertElement(result, elem, index, "")
+		}
+		return result
+	}
+	return v
+}
+
+// compareVectors compares two vectors for equality.
+func (c *compiler) compareVectors(lhs, rhs *LLVMValue) *LLVMValue {
+	b := c.builder
+	x, y := lhs.LLVMValue(), rhs.LLVMValue()
+	var eq llvm.Value
+	if x.Type().ElementType().TypeKind() == llvm.IntegerTypeKind {
+		eq = b.CreateICmp(llvm.IntEQ, x, y, "")
+	} else {
+		eq = b.CreateFCmp(llvm.FloatOEQ, x, y, "")
+	}
+	result := boolLLVMValue(true)
+	for i := 0; i < x.Type().VectorSize(); i++ {
+		index := llvm.ConstInt(llvm.Int32Type(), uint64(i), false)
+		result = b.CreateAnd(result, b.CreateExtractElement(eq, index, ""), "")
+	}
+	return c.NewValue(result, types.Typ[types.Bool])
+}
+
+// simdCall lowers a direct call to a method of one of the simd
+// package's vector types to vector instructions. It returns nil
+// if the call is not to such a method, or the method cannot be
+// lowered at the call site; in that case the method is called.
+func (fr *frame) simdCall(call *ssa.CallCommon) *LLVMValue {
+	fn := call.StaticCallee()
+	if fn == nil || fn.Synthetic != "" || fn.Signature.Recv() == nil {
+		return nil
+	}
+	recv := fn.Signature.Recv().Type()
+	if !isSIMDType(recv) {
+		return nil
+	}
+	elem := recv.Underlying().(*types.Array).Elem().Underlying().(*types.Basic)
+	isfloat := elem.Info()&types.IsFloat != 0
+	unsigned := elem.Info()&types.IsUnsigned != 0
+
+	b := fr.builder
+	x := fr.value(call.Args[0]).LLVMValue()
+	var y llvm.Value
+	if len(call.Args) > 1 {
+		y = fr.value(call.Args[1]).LLVMValue()
+	}
+
+	var result llvm.Value
+	switch fn.Name() {
+	case "Add":
+		if isfloat {
+			result = b.CreateFAdd(x, y, "")
+		} else {
+			result = b.CreateAdd(x, y, "")
+		}
+	case "Sub":
+		if isfloat {
+			result = b.CreateFSub(x, y, "")
+		} else {
+			result = b.CreateSub(x, y, "")
+		}
+	case "Mul":
+		if isfloat {
+			result = b.CreateFMul(x, y, "")
+		} else {
+			result = b.CreateMul(x, y, "")
+		}
+	case "Div":
+		result = b.CreateFDiv(x, y, "")
+	case "And":
+		result = b.CreateAnd(x, y, "")
+	case "Or":
+		result = b.CreateOr(x, y, "")
+	case "Xor":
+		result = b.CreateXor(x, y, "")
+	case "Min", "Max":
+		// Min selects y where y < x, and Max selects y
+		// where x < y, as the scalar implementations do.
+		lhs, rhs := y, x
+		if fn.Name() == "Max" {
+			lhs, rhs = x, y
+		}
+		var less llvm.Value
+		switch {
+		case isfloat:
+			less = b.CreateFCmp(llvm.FloatOLT, lhs, rhs, "")
+		case unsigned:
+			less = b.CreateICmp(llvm.IntULT, lhs, rhs, "")
+		default:
+			less = b.CreateICmp(llvm.IntSLT, lhs, rhs, "")
+		}
+		result = b.CreateSelect(less, y, x, "")
+	case "HAdd":
+		result = fr.simdHAdd(x, isfloat)
+	case "Shuffle":
+		n := x.Type().VectorSize()
+		mask, ok := constShuffleMask(call.Args[2], n)
+		if !ok {
+			return nil
+		}
+		llmask := make([]llvm.Value, n)
+		for i, m := range mask {
+			if m < 0 || m >= 2*n {
+				// Let the scalar implementation panic.
+				return nil
+			}
+			llmask[i] = llvm.ConstInt(llvm.Int32Type(), uint64(m), false)
+		}
+		result = b.CreateShuffleVector(x, y, llvm.ConstVector(llmask, false), "")
+	default:
+		return nil
+	}
+	return fr.NewValue(result, fn.Signature.Results().At(0).Type())
+}
+
+// simdHAdd returns the sum of the elements of the vector v. The
+// elements are added pairwise, halving the vector at each step,
+// in the same order as the scalar implementation of HAdd.
+func (fr *frame) simdHAdd(v llvm.Value, isfloat bool) llvm.Value {
+	b := fr.builder
+	int32type := llvm.Int32Type()
+	size := v.Type().VectorSize()
+	for n := size / 2; n > 0; n /= 2 {
+		mask := make([]llvm.Value, size)
+		for i := range mask {
+			if i < n {
+				mask[i] = llvm.ConstInt(int32type, uint64(i+n), false)
+			} else {
+				mask[i] = llvm.Undef(int32type)
+			}
+		}
+		upper := b.CreateShuffleVector(v, llvm.Undef(v.Type()), llvm.ConstVector(mask, false), "")
+		if isfloat {
+			v = b.CreateFAdd(v, upper, "")
+		} else {
+			v = b.CreateAdd(v, upper, "")
+		}
+	}
+	return b.CreateExtractElement(v, llvm.ConstNull(int32type), "")
+}
+
+// constShuffleMask returns the elements of the shuffle mask v,
+// if it is an array composite literal whose elements are all
+// constants. LLVM requires shuffle masks to be constant.
+func constShuffleMask(v ssa.Value, n int) ([]int, bool) {
+	load, ok := v.(*ssa.UnOp)
+	if !ok || load.Op != token.MUL {
+		return nil, false
+	}
+	alloc, ok := load.X.(*ssa.Alloc)
+	if !ok || alloc.Heap {
+		return nil, false
+	}
+	// Each element must be stored exactly once,
+	// in the same block as, and before, the load.
+	before := make(map[ssa.Instruction]bool)
+	for _, instr := range load.Block().Instrs {
+		if instr == load {
+			break
+		}
+		before[instr] = true
+	}
+	mask := make([]int, n)
+	stored := make([]bool, n)
+	for _, ref := range *alloc.Referrers() {
+		switch ref := ref.(type) {
+		case *ssa.UnOp, *ssa.DebugRef:
+			// loads of the mask
+		case *ssa.IndexAddr:
+			index, ok := ref.Index.(*ssa.Const)
+			if !ok {
+				return nil, false
+			}
+			i := index.Int64()
+			for _, ref := range *ref.Referrers() {
+				store, ok := ref.(*ssa.Store)
+				if !ok || !before[store] || stored[i] {
+					return nil, false
+				}
+				value, ok := store.Val.(*ssa.Const)
+				if !ok {
+					return nil, false
+				}
+				mask[i] = int(value.Int64())
+				stored[i] = true
+			}
+		default:
+			return nil, false
+		}
+	}
+	return mask, true
+}
+
+// LLVM aligns vectors to their size, but the runtime allocates
+// memory with C's malloc, which aligns it only to 8 or 16 bytes,
+// so vectors on the heap (for example, in slices or maps, or in
+// structs allocated with new) may be misaligned. Loads and stores
+// of vectors, and of values containing them, are therefore given
+// the alignment of the vectors' elements instead.
+
+// alignVectorAccesses sets the alignment of the loads and stores
+// in the module of values of types that contain vectors.
+func (c *compiler) alignVectorAccesses() {
+	for fn := c.module.FirstFunction(); !fn.IsNil(); fn = llvm.NextFunction(fn) {
+		for b := fn.FirstBasicBlock(); !b.IsNil(); b = llvm.NextBasicBlock(b) {
+			for instr := b.FirstInstruction(); !instr.IsNil(); instr = llvm.NextInstruction(instr) {
+				var t llvm.Type
+				switch instr.InstructionOpcode() {
+				case llvm.Load:
+					t = instr.Type()
+				case llvm.Store:
+					t = instr.Operand(0).Type()
+				default:
+					continue
+				}
+				if align, ok := c.elementAlignment(t); ok {
+					instr.SetAlignment(align)
+				}
+			}
+		}
+	}
+}
+
+// elementAlignment returns the alignment of t if vectors were
+// aligned as their elements are, and whether t contains a vector.
+func (c *compiler) elementAlignment(t llvm.Type) (align int, vector bool) {
+	switch t.TypeKind() {
+	case llvm.VectorTypeKind:
+		return c.target.ABITypeAlignment(t.ElementType()), true
+	case llvm.ArrayTypeKind:
+		return c.elementAlignment(t.ElementType())
+	case llvm.StructTypeKind:
+		align = 1
+		for _, field := range t.StructElementTypes() {
+			fieldalign, fieldvector := c.elementAlignment(field)
+			if fieldalign > align {
+				align = fieldalign
+			}
+			vector = vector || fieldvector
+		}
+		return align, vector
+	}
+	return c.target.ABITypeAlignment(t), false
+}
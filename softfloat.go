@@ -0,0 +1,203 @@
+// Copyright 2014 The llgo Authors.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package llgo
+
+import (
+	"fmt"
+	"go/token"
+	"math"
+
+	"github.com/axw/gollvm/llvm"
+)
+
+// Floating point values are represented by LLVM float and double
+// values whether or not SoftFloat is set, so that memory layouts and
+// calling conventions are unchanged. In SoftFloat mode, operations on
+// them are implemented by the runtime's software floating point
+// functions (pkg/runtime/softfloat64.go), which operate on the bits
+// of float64 values. float32 operands are converted to float64 and
+// the results converted back; as float64 has more than twice the
+// precision of float32, the results are correctly rounded.
+
+// floatArith emits the arithmetic operation op on two floats.
+func (c *compiler) floatArith(op token.Token, x, y llvm.Value) llvm.Value {
+	b := c.builder
+	if c.SoftFloat {
+		var fn *LLVMValue
+		switch op {
+		case token.ADD:
+			fn = c.runtime.fadd64
+		case token.SUB:
+			fn = c.runtime.fsub64
+		case token.MUL:
+			fn = c.runtime.fmul64
+		case token.QUO:
+			fn = c.runtime.fdiv64
+		default:
+			panic(fmt.Sprint("Unimplemented operator: ", op))
+		}
+		args := []llvm.Value{c.softFloatBits(x), c.softFloatBits(y)}
+		return c.softFloatValue(b.CreateCall(fn.LLVMValue(), args, ""), x.Type())
+	}
+	switch op {
+	case token.ADD:
+		return b.CreateFAdd(x, y, "")
+	case token.SUB:
+		return b.CreateFSub(x, y, "")
+	case token.MUL:
+		return b.CreateFMul(x, y, "")
+	case token.QUO:
+		return b.CreateFDiv(x, y, "")
+	}
+	panic(fmt.Sprint("Unimplemented operator: ", op))
+}
+
+// floatCompare emits the comparison op of two floats.
+func (c *compiler) floatCompare(op token.Token, x, y llvm.Value) llvm.Value {
+	b := c.builder
+	if c.SoftFloat {
+		// fcmp64 returns the sign of x-y, and whether either
+		// operand is NaN, in which case all comparisons other
+		// than != (which is lowered as !(==)) are false.
+		var pred llvm.IntPredicate
+		switch op {
+		case token.EQL:
+			pred = llvm.IntEQ
+		case token.LSS:
+			pred = llvm.IntSLT
+		case token.LEQ:
+			pred = llvm.IntSLE
+		case token.GTR:
+			pred = llvm.IntSGT
+		case token.GEQ:
+			pred = llvm.IntSGE
+		default:
+			panic(fmt.Sprint("Unimplemented operator: ", op))
+		}
+		args := []llvm.Value{c.softFloatBits(x), c.softFloatBits(y)}
+		result := b.CreateCall(c.runtime.fcmp64.LLVMValue(), args, "")
+		cmp := b.CreateExtractValue(result, 0, "")
+		isnan := b.CreateExtractValue(result, 1, "")
+		ok := b.CreateICmp(pred, cmp, llvm.ConstNull(cmp.Type()), "")
+		return b.CreateAnd(ok, b.CreateNot(isnan, ""), "")
+	}
+	var pred llvm.FloatPredicate
+	switch op {
+	case token.EQL:
+		pred = llvm.FloatOEQ
+	case token.LSS:
+		pred = llvm.FloatOLT
+	case token.LEQ:
+		pred = llvm.FloatOLE
+	case token.GTR:
+		pred = llvm.FloatOGT
+	case token.GEQ:
+		pred = llvm.FloatOGE
+	default:
+		panic(fmt.Sprint("Unimplemented operator: ", op))
+	}
+	return b.CreateFCmp(pred, x, y, "")
+}
+
+// softFloatNeg negates a float.
+func (c *compiler) softFloatNeg(x llvm.Value) llvm.Value {
+	args := []llvm.Value{c.softFloatBits(x)}
+	return c.softFloatValue(c.builder.CreateCall(c.runtime.fneg64.LLVMValue(), args, ""), x.Type())
+}
+
+// softFloatConvert converts x between float types, or between
+// integer and float types. Integers are signed unless srcUnsigned
+// or dstUnsigned (for the result) is set.
+func (c *compiler) softFloatConvert(x llvm.Value, srcUnsigned bool, t llvm.Type, dstUnsigned bool) llvm.Value {
+	b := c.builder
+	int64type := llvm.Int64Type()
+	switch {
+	case x.Type().TypeKind() == llvm.IntegerTypeKind:
+		unsigned64 := srcUnsigned && x.Type().IntTypeWidth() == 64
+		switch {
+		case x.Type().IntTypeWidth() == 64:
+		case srcUnsigned:
+			x = b.CreateZExt(x, int64type, "")
+		default:
+			x = b.CreateSExt(x, int64type, "")
+		}
+		return c.softFloatFromInt(x, unsigned64, t)
+
+	case t.TypeKind() == llvm.IntegerTypeKind:
+		unsigned64 := dstUnsigned && t.IntTypeWidth() == 64
+		v := c.softFloatToInt(c.softFloatBits(x), unsigned64)
+		if t.IntTypeWidth() < 64 {
+			v = b.CreateTrunc(v, t, "")
+		}
+		return v
+	}
+	return c.softFloatValue(c.softFloatBits(x), t)
+}
+
+// softFloatBits returns the bits of the float64 value of x.
+func (c *compiler) softFloatBits(x llvm.Value) llvm.Value {
+	b := c.builder
+	if x.Type().TypeKind() == llvm.FloatTypeKind {
+		bits := b.CreateBitCast(x, llvm.Int32Type(), "")
+		return b.CreateCall(c.runtime.f32to64.LLVMValue(), []llvm.Value{bits}, "")
+	}
+	return b.CreateBitCast(x, llvm.Int64Type(), "")
+}
+
+// softFloatValue returns the value of type t (float or
+// double) nearest to the float64 with the specified bits.
+func (c *compiler) softFloatValue(bits llvm.Value, t llvm.Type) llvm.Value {
+	b := c.builder
+	if t.TypeKind() == llvm.FloatTypeKind {
+		bits = b.CreateCall(c.runtime.f64to32.LLVMValue(), []llvm.Value{bits}, "")
+	}
+	return b.CreateBitCast(bits, t, "")
+}
+
+// softFloatFromInt returns the value of type t (float or double)
+// nearest to the 64-bit integer x. Integers are converted directly
+// to float32, rather than via float64, which would round twice.
+// The runtime's conversions take signed integers, so if unsigned
+// is set, values of 2^63 or more are halved, keeping the low bit
+// so that the result is rounded correctly, converted, and then
+// doubled.
+func (c *compiler) softFloatFromInt(x llvm.Value, unsigned bool, t llvm.Type) llvm.Value {
+	b := c.builder
+	fintto := c.runtime.fintto64.LLVMValue()
+	if t.TypeKind() == llvm.FloatTypeKind {
+		fintto = c.runtime.fintto32.LLVMValue()
+	}
+	f := b.CreateBitCast(b.CreateCall(fintto, []llvm.Value{x}, ""), t, "")
+	if !unsigned {
+		return f
+	}
+	one := llvm.ConstInt(x.Type(), 1, false)
+	half := b.CreateOr(b.CreateLShr(x, one, ""), b.CreateAnd(x, one, ""), "")
+	fhalf := b.CreateBitCast(b.CreateCall(fintto, []llvm.Value{half}, ""), t, "")
+	large := c.floatArith(token.ADD, fhalf, fhalf)
+	isLarge := b.CreateICmp(llvm.IntSLT, x, llvm.ConstNull(x.Type()), "")
+	return b.CreateSelect(isLarge, large, f, "")
+}
+
+// softFloatToInt returns the 64-bit integer value of the float64
+// with the bits f, truncated toward zero. f64toint produces signed
+// integers, so if unsigned is set, values of 2^63 or more are reduced
+// by 2^63 before conversion, and the top bit is set afterwards.
+func (c *compiler) softFloatToInt(f llvm.Value, unsigned bool) llvm.Value {
+	b := c.builder
+	f64toint := c.runtime.f64toint.LLVMValue()
+	v := b.CreateExtractValue(b.CreateCall(f64toint, []llvm.Value{f}, ""), 0, "")
+	if !unsigned {
+		return v
+	}
+	two63 := llvm.ConstInt(f.Type(), math.Float64bits(1<<63), false)
+	cmp := b.CreateCall(c.runtime.fcmp64.LLVMValue(), []llvm.Value{f, two63}, "")
+	cmp = b.CreateExtractValue(cmp, 0, "")
+	isLarge := b.CreateICmp(llvm.IntSGE, cmp, llvm.ConstNull(cmp.Type()), "")
+	reduced := b.CreateCall(c.runtime.fsub64.LLVMValue(), []llvm.Value{f, two63}, "")
+	large := b.CreateExtractValue(b.CreateCall(f64toint, []llvm.Value{reduced}, ""), 0, "")
+	large = b.CreateXor(large, llvm.ConstInt(v.Type(), 1<<63, false), "")
+	return b.CreateSelect(isLarge, large, v, "")
+}
@@ -46,9 +46,16 @@ func newUnit(c *compiler, pkg *ssa.Package) *unit {
 // translatePackage translates an *ssa.Package into an LLVM module, and returns
 // the translation unit information.
 func (u *unit) translatePackage(pkg *ssa.Package) {
-	// Initialize global storage.
-	for _, m := range pkg.Members {
-		switch v := m.(type) {
+	// Initialize global storage. Members are visited
+	// in order of name, so that the output is the same
+	// for each compilation of the package.
+	names := make([]string, 0, len(pkg.Members))
+	for name := range pkg.Members {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		switch v := pkg.Members[name].(type) {
 		case *ssa.Global:
 			llelemtyp := u.llvmtypes.ToLLVM(deref(v.Type()))
 			global := llvm.AddGlobal(u.module.Module, llelemtyp, v.String())
@@ -57,29 +64,30 @@ func (u *unit) translatePackage(pkg *ssa.Package) {
 		}
 	}
 
-	// Define functions.
-	// Sort if flag is set for deterministic behaviour (for debugging)
-	functions := ssautil.AllFunctions(pkg.Prog)
-	if !u.compiler.OrderedCompilation {
-		for f, _ := range functions {
-			u.defineFunction(f)
-		}
-	} else {
-		fns := []*ssa.Function{}
-		for f, _ := range functions {
+	// Define functions, in a deterministic order.
+	var fns []*ssa.Function
+	for f := range ssautil.AllFunctions(pkg.Prog) {
+		fns = append(fns, f)
+	}
+	sort.Sort(byName(fns))
+	for _, f := range fns {
+		u.defineFunction(f)
+	}
+
+	// Define remaining functions that were resolved during
+	// runtime type mapping, but not defined. Defining them
+	// may resolve further functions.
+	for len(u.undefinedFuncs) > 0 {
+		fns = fns[:0]
+		for f := range u.undefinedFuncs {
 			fns = append(fns, f)
 		}
 		sort.Sort(byName(fns))
 		for _, f := range fns {
 			u.defineFunction(f)
+			delete(u.undefinedFuncs, f)
 		}
 	}
-
-	// Define remaining functions that were resolved during
-	// runtime type mapping, but not defined.
-	for f, _ := range u.undefinedFuncs {
-		u.defineFunction(f)
-	}
 }
 
 // ResolveMethod implements MethodResolver.ResolveMethod.
@@ -159,6 +167,7 @@ func (u *unit) defineFunction(f *ssa.Function) {
 	fr := frame{
 		unit:   u,
 		blocks: make([]llvm.BasicBlock, len(f.Blocks)),
+		exits:  make([]llvm.BasicBlock, len(f.Blocks)),
 		env:    make(map[ssa.Value]*LLVMValue),
 	}
 
@@ -167,9 +176,8 @@ func (u *unit) defineFunction(f *ssa.Function) {
 	delete(u.undefinedFuncs, f)
 
 	// Push the function onto the debug context.
-	// TODO(axw) create a fake CU for synthetic functions
-	if u.GenerateDebug && f.Synthetic == "" {
-		u.debug.pushFunctionContext(llvmFunction, f.Signature, f.Pos())
+	if u.GenerateDebug {
+		u.debug.pushFunctionContext(llvmFunction, f)
 		defer u.debug.popFunctionContext()
 		u.debug.setLocation(u.builder, f.Pos())
 	}
@@ -221,9 +229,28 @@ func (u *unit) defineFunction(f *ssa.Function) {
 				paramIndex = -1
 			}
 			fr.debug.declare(fr.builder, local, alloca, paramIndex)
+			delete(paramPos, local.Pos())
+		}
+	}
+
+	// Describe the parameters that are not spilled to
+	// locals, and so live only in registers.
+	if fr.GenerateDebug {
+		for _, param := range f.Params {
+			obj := param.Object()
+			if obj == nil || !param.Pos().IsValid() {
+				continue
+			}
+			if paramIndex, ok := paramPos[param.Pos()]; ok {
+				fr.debug.value(fr.builder, obj, fr.env[param].LLVMValue(), paramIndex)
+			}
 		}
 	}
 
+	if fr.raceEnabled() {
+		fr.raceFuncEnter()
+	}
+
 	// Move any allocs relating to named results from the entry block
 	// to the prologue block, so they dominate the rundefers and recover
 	// blocks.
@@ -257,6 +284,10 @@ func (u *unit) defineFunction(f *ssa.Function) {
 	// f.Recover != nil.
 	if f.Recover != nil || hasDefer(f) {
 		rdblock := llvm.AddBasicBlock(llvmFunction, "rundefers")
+		var racedepth llvm.Value
+		if fr.raceEnabled() {
+			racedepth = fr.raceFuncDepth()
+		}
 		defers := fr.builder.CreateAlloca(fr.runtime.defers.llvm, "")
 		fr.builder.CreateCall(fr.runtime.initdefers.LLVMValue(), []llvm.Value{defers}, "")
 		jb := fr.builder.CreateStructGEP(defers, 0, "")
@@ -275,6 +306,9 @@ func (u *unit) defineFunction(f *ssa.Function) {
 		} else {
 			recoverBlock = llvm.AddBasicBlock(llvmFunction, "recover")
 			fr.builder.SetInsertPointAtEnd(recoverBlock)
+			if fr.raceEnabled() {
+				fr.raceFuncExit()
+			}
 			var nresults int
 			results := f.Signature.Results()
 			if results != nil {
@@ -294,14 +328,21 @@ func (u *unit) defineFunction(f *ssa.Function) {
 			}
 		}
 		fr.builder.SetInsertPointAtEnd(rdblock)
+		if fr.raceEnabled() {
+			fr.raceUnwind(racedepth)
+		}
 		fr.builder.CreateCall(fr.runtime.rundefers.LLVMValue(), nil, "")
 		fr.builder.CreateBr(recoverBlock)
 	} else {
 		fr.builder.CreateBr(fr.blocks[0])
 	}
 
-	for i, block := range f.Blocks {
-		fr.translateBlock(block, fr.blocks[i])
+	fr.typeSwitches = fr.findTypeSwitches(f)
+	for _, block := range blockOrder(f) {
+		fr.translateBlock(block, fr.block(block))
+	}
+	for _, phi := range fr.phis {
+		fr.addPhiIncoming(phi)
 	}
 }
 
@@ -310,23 +351,76 @@ type frame struct {
 	blocks    []llvm.BasicBlock
 	backpatch map[ssa.Value]*LLVMValue
 	env       map[ssa.Value]*LLVMValue
+
+	// exits holds the LLVM basic block in which the
+	// translation of each SSA basic block ends, which
+	// differs from the entry block if the translation
+	// of an instruction introduced new blocks.
+	exits []llvm.BasicBlock
+
+	// phis holds the Phi instructions translated so far,
+	// whose incoming values are added once all blocks
+	// have been translated, and their exit blocks known.
+	phis []*ssa.Phi
+
+	// typeSwitches maps the type assertions of type
+	// switches that are dispatched on type hashes to
+	// their cases.
+	typeSwitches map[*ssa.TypeAssert]typeSwitchCase
 }
 
-func (fr *frame) translateBlock(b *ssa.BasicBlock, llb llvm.BasicBlock) {
-	if fr.GenerateDebug {
-		fr.debug.pushBlockContext(b.Instrs[0].Pos())
-		defer fr.debug.popBlockContext()
+// blockOrder returns the blocks of a function in the order in
+// which they are translated: a preorder of the dominator tree,
+// so that each value other than a phi edge is translated before
+// its uses (including the llvm.dbg.value calls of DebugRefs),
+// followed by any blocks not in the tree, such as the recover
+// block.
+func blockOrder(f *ssa.Function) []*ssa.BasicBlock {
+	order := make([]*ssa.BasicBlock, 0, len(f.Blocks))
+	visited := make([]bool, len(f.Blocks))
+	var visit func(b *ssa.BasicBlock)
+	visit = func(b *ssa.BasicBlock) {
+		visited[b.Index] = true
+		order = append(order, b)
+		for _, d := range b.Dominees() {
+			visit(d)
+		}
 	}
+	visit(f.Blocks[0])
+	for _, b := range f.Blocks {
+		if !visited[b.Index] {
+			visit(b)
+		}
+	}
+	return order
+}
+
+func (fr *frame) translateBlock(b *ssa.BasicBlock, llb llvm.BasicBlock) {
 	fr.builder.SetInsertPointAtEnd(llb)
 	for _, instr := range b.Instrs {
 		fr.instruction(instr)
 	}
+	fr.exits[b.Index] = fr.builder.GetInsertBlock()
 }
 
 func (fr *frame) block(b *ssa.BasicBlock) llvm.BasicBlock {
 	return fr.blocks[b.Index]
 }
 
+// addPhiIncoming adds the incoming values of a Phi
+// instruction, from the exits of its predecessors.
+func (fr *frame) addPhiIncoming(instr *ssa.Phi) {
+	phi := fr.env[instr].LLVMValue()
+	values := make([]llvm.Value, len(instr.Edges))
+	blocks := make([]llvm.BasicBlock, len(instr.Edges))
+	block := instr.Block()
+	for i, edge := range instr.Edges {
+		values[i] = fr.value(edge).LLVMValue()
+		blocks[i] = fr.exits[block.Preds[i].Index]
+	}
+	phi.AddIncoming(values, blocks)
+}
+
 func (fr *frame) value(v ssa.Value) (result *LLVMValue) {
 	switch v := v.(type) {
 	case nil:
@@ -440,6 +534,10 @@ func (fr *frame) instruction(instr ssa.Instruction) {
 		fr.env[instr] = lhs.BinaryOp(instr.Op, rhs).(*LLVMValue)
 
 	case *ssa.Call:
+		if result := fr.simdCall(instr.Common()); result != nil {
+			fr.env[instr] = result
+			break
+		}
 		fn, args, result := fr.prepareCall(instr)
 		// Some builtins may only be used immediately, and not
 		// deferred; in this case, "fn" will be nil, and result
@@ -450,7 +548,19 @@ func (fr *frame) instruction(instr ssa.Instruction) {
 				fr.env[instr] = result
 			}
 		} else {
-			result = fr.createCall(fn, args)
+			var sync raceSync
+			if fr.raceEnabled() {
+				sync = raceSyncOf(instr.Common())
+			}
+			if sync&raceRelease != 0 {
+				fr.raceRelease(args[0].LLVMValue())
+			}
+			if result = fr.inlineCall(instr.Common(), args); result == nil {
+				result = fr.createCall(fn, args)
+			}
+			if sync&raceAcquire != 0 {
+				fr.raceAcquire(args[0].LLVMValue())
+			}
 			fr.env[instr] = result
 		}
 
@@ -473,6 +583,8 @@ func (fr *frame) instruction(instr ssa.Instruction) {
 		value := fr.value(instr.X).LLVMValue()
 		if _, ok := instr.Type().Underlying().(*types.Pointer); ok {
 			value = fr.builder.CreateBitCast(value, fr.llvmtypes.ToLLVM(instr.Type()), "")
+		} else if isSIMDType(instr.Type()) || isSIMDType(instr.X.Type()) {
+			value = fr.convertVector(value, fr.llvmtypes.ToLLVM(instr.Type()))
 		}
 		v := fr.NewValue(value, instr.Type())
 		if _, ok := instr.X.(*ssa.Phi); ok {
@@ -487,7 +599,15 @@ func (fr *frame) instruction(instr ssa.Instruction) {
 		}
 		fr.env[instr] = v.Convert(instr.Type()).(*LLVMValue)
 
-	//case *ssa.DebugRef:
+	case *ssa.DebugRef:
+		// Variables held in memory are described by the
+		// llvm.dbg.declare calls for their allocations;
+		// the others are described at each reference.
+		if obj, ok := instr.Object().(*types.Var); ok && !instr.IsAddr && fr.GenerateDebug {
+			if obj.Parent() != nil && obj.Parent() != fr.pkg.Object.Scope() {
+				fr.debug.value(fr.builder, obj, fr.value(instr.X).LLVMValue(), -1)
+			}
+		}
 
 	case *ssa.Defer:
 		fn, args, result := fr.prepareCall(instr)
@@ -523,6 +643,12 @@ func (fr *frame) instruction(instr ssa.Instruction) {
 			panic("illegal use of builtin in go statement")
 		}
 		fn = fr.indirectFunction(fn, args)
+		if fr.raceEnabled() {
+			// The new goroutine acquires its argument
+			// block on entry; see indirectFunction.
+			ctx := fr.builder.CreateExtractValue(fn.LLVMValue(), 1, "")
+			fr.raceRelease(ctx)
+		}
 		fr.createCall(fr.runtime.Go, []*LLVMValue{fn})
 
 	case *ssa.If:
@@ -533,9 +659,14 @@ func (fr *frame) instruction(instr ssa.Instruction) {
 		fr.builder.CreateCondBr(cond, trueBlock, falseBlock)
 
 	case *ssa.Index:
+		array := fr.value(instr.X).LLVMValue()
+		if array.Type().TypeKind() == llvm.VectorTypeKind {
+			index := fr.value(instr.Index).LLVMValue()
+			fr.env[instr] = fr.NewValue(fr.builder.CreateExtractElement(array, index, ""), instr.Type())
+			break
+		}
 		// FIXME Surely we should be dealing with an
 		// *array, so we can do a GEP?
-		array := fr.value(instr.X).LLVMValue()
 		arrayptr := fr.builder.CreateAlloca(array.Type(), "")
 		fr.builder.CreateStore(array, arrayptr)
 		index := fr.value(instr.Index).LLVMValue()
@@ -648,14 +779,7 @@ func (fr *frame) instruction(instr ssa.Instruction) {
 		typ := instr.Type()
 		phi := fr.builder.CreatePHI(fr.llvmtypes.ToLLVM(typ), instr.Comment)
 		fr.env[instr] = fr.NewValue(phi, typ)
-		values := make([]llvm.Value, len(instr.Edges))
-		blocks := make([]llvm.BasicBlock, len(instr.Edges))
-		block := instr.Block()
-		for i, edge := range instr.Edges {
-			values[i] = fr.value(edge).LLVMValue()
-			blocks[i] = fr.block(block.Preds[i])
-		}
-		phi.AddIncoming(values, blocks)
+		fr.phis = append(fr.phis, instr)
 
 	case *ssa.Range:
 		x := fr.value(instr.X)
@@ -669,6 +793,9 @@ func (fr *frame) instruction(instr ssa.Instruction) {
 		}
 
 	case *ssa.Return:
+		if fr.raceEnabled() {
+			fr.raceFuncExit()
+		}
 		switch n := len(instr.Results); n {
 		case 0:
 			// https://code.google.com/p/go/issues/detail?id=7022
@@ -715,9 +842,21 @@ func (fr *frame) instruction(instr ssa.Instruction) {
 		value := fr.value(instr.Val).LLVMValue()
 		// The bitcast is necessary to handle recursive pointer stores.
 		addr = fr.builder.CreateBitCast(addr, llvm.PointerType(value.Type(), 0), "")
+		if fr.raceEnabled() && raceInstrumentAddr(instr.Addr) {
+			fr.raceAccess(addr, value.Type(), true)
+		}
 		fr.builder.CreateStore(value, addr)
 
 	case *ssa.TypeAssert:
+		if c, ok := fr.typeSwitches[instr]; ok {
+			if instr.Block() == c.sw.start && c.sw.selector.IsNil() {
+				fr.dispatchTypeSwitch(c.sw, fr.value(instr.X))
+			}
+			if c.index >= 0 && !c.sw.selector.IsNil() {
+				fr.env[instr] = fr.typeSwitchAssert(instr, c)
+				break
+			}
+		}
 		x := fr.value(instr.X)
 		if iface, ok := x.Type().Underlying().(*types.Interface); ok && iface.NumMethods() > 0 {
 			x = x.convertI2E()
@@ -751,7 +890,11 @@ func (fr *frame) instruction(instr ssa.Instruction) {
 			fr.env[instr] = fr.chanRecv(operand, instr.CommaOk)
 		case token.MUL:
 			// The bitcast is necessary to handle recursive pointer loads.
-			llptr := fr.builder.CreateBitCast(operand.LLVMValue(), llvm.PointerType(fr.llvmtypes.ToLLVM(instr.Type()), 0), "")
+			lltyp := fr.llvmtypes.ToLLVM(instr.Type())
+			llptr := fr.builder.CreateBitCast(operand.LLVMValue(), llvm.PointerType(lltyp, 0), "")
+			if fr.raceEnabled() && raceInstrumentAddr(instr.X) {
+				fr.raceAccess(llptr, lltyp, false)
+			}
 			fr.env[instr] = fr.NewValue(fr.builder.CreateLoad(llptr, ""), instr.Type())
 		default:
 			fr.env[instr] = operand.UnaryOp(instr.Op).(*LLVMValue)
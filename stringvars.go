@@ -0,0 +1,151 @@
+// Copyright 2014 The llgo Authors.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package llgo
+
+import (
+	"fmt"
+	"sort"
+
+	"code.google.com/p/go.tools/go/exact"
+	"code.google.com/p/go.tools/go/ssa"
+	"code.google.com/p/go.tools/go/types"
+	"github.com/axw/gollvm/llvm"
+)
+
+// stringInit is a package-level string variable
+// that is initialised with a constant value.
+type stringInit struct {
+	global *ssa.Global
+	value  exact.Value
+}
+
+// stringVarsGlobal is the name of the array holding the addresses
+// of the string variables that SetStringVars may set. It has
+// appending linkage, so the arrays of linked modules are joined.
+const stringVarsGlobal = "__llgo.stringvars"
+
+// prepareStringInits finds the package-level string variables
+// that are initialised with a constant, and removes the store
+// of the constant from the package initializer, so that they
+// are statically initialised by applyStringInits instead. This
+// allows the value of any such variable, or of an uninitialised
+// string variable, to be replaced in the linked program by
+// SetStringVars; vars holds all such variables, in order of name.
+func prepareStringInits(pkg *ssa.Package) (inits []stringInit, vars []*ssa.Global) {
+	isString := func(g *ssa.Global) bool {
+		basic, ok := deref(g.Type()).Underlying().(*types.Basic)
+		return ok && basic.Kind() == types.String
+	}
+	computed := make(map[*ssa.Global]bool)
+	if init := pkg.Func("init"); init != nil {
+		for _, b := range init.Blocks {
+			for i := 0; i < len(b.Instrs); i++ {
+				store, ok := b.Instrs[i].(*ssa.Store)
+				if !ok {
+					continue
+				}
+				g, ok := store.Addr.(*ssa.Global)
+				if !ok || g.Pkg != pkg || !isString(g) {
+					continue
+				}
+				c, ok := store.Val.(*ssa.Const)
+				if !ok {
+					computed[g] = true
+					continue
+				}
+				inits = append(inits, stringInit{g, c.Value})
+				b.Instrs = append(b.Instrs[:i], b.Instrs[i+1:]...)
+				i--
+			}
+		}
+	}
+
+	var names []string
+	for name := range pkg.Members {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if g, ok := pkg.Members[name].(*ssa.Global); ok && isString(g) && !computed[g] {
+			vars = append(vars, g)
+		}
+	}
+	return inits, vars
+}
+
+// applyStringInits sets the initializers of the
+// variables returned by prepareStringInits.
+func (u *unit) applyStringInits(inits []stringInit) {
+	for _, init := range inits {
+		typ := deref(init.global.Type())
+		value := u.NewConstValue(init.value, typ).LLVMValue()
+		global := u.globals[init.global].LLVMValue()
+		global.SetInitializer(value)
+	}
+}
+
+// exportStringVars records the variables returned by
+// prepareStringInits in the module, for SetStringVars.
+func (u *unit) exportStringVars(vars []*ssa.Global) {
+	if len(vars) == 0 {
+		return
+	}
+	i8ptr := llvm.PointerType(llvm.Int8Type(), 0)
+	ptrs := make([]llvm.Value, len(vars))
+	for i, v := range vars {
+		ptrs[i] = llvm.ConstBitCast(u.globals[v].LLVMValue(), i8ptr)
+	}
+	global := llvm.AddGlobal(u.module.Module, llvm.ArrayType(i8ptr, len(ptrs)), stringVarsGlobal)
+	global.SetInitializer(llvm.ConstArray(i8ptr, ptrs))
+	global.SetLinkage(llvm.AppendingLinkage)
+}
+
+// SetStringVars sets the initializers of the package-level
+// string variables in the module, as the -X flag does. The
+// vars map the names of the variables, in the form
+// importpath.name ("main.name" for a command), to their
+// values. Each variable must be a string variable that is
+// uninitialised or initialised with a constant; any other
+// would have its value replaced when its package is
+// initialised.
+func SetStringVars(m llvm.Module, vars map[string]string) error {
+	settable := make(map[string]bool)
+	if global := m.NamedGlobal(stringVarsGlobal); !global.IsNil() {
+		if init := global.Initializer(); !init.IsNil() {
+			for i := 0; i < init.OperandsCount(); i++ {
+				settable[init.Operand(i).Operand(0).Name()] = true
+			}
+		}
+	}
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		global := m.NamedGlobal(name)
+		if global.IsNil() {
+			return fmt.Errorf("-X %s: variable is not defined", name)
+		}
+		if !settable[name] {
+			return fmt.Errorf("-X %s: not a string variable that is uninitialised or initialised with a constant", name)
+		}
+		typ := global.Type().ElementType()
+		value := vars[name]
+		i8ptr := llvm.PointerType(llvm.Int8Type(), 0)
+		ptr := llvm.ConstNull(i8ptr)
+		if len(value) > 0 {
+			ptr = addPrivateGlobal(m, "__llgo.str", llvm.ConstString(value, false))
+			ptr = llvm.ConstBitCast(ptr, i8ptr)
+		}
+		lentyp := typ.StructElementTypes()[1]
+		len_ := llvm.ConstInt(lentyp, uint64(len(value)), false)
+		init := llvm.Undef(typ)
+		init = llvm.ConstInsertValue(init, ptr, []uint32{0})
+		init = llvm.ConstInsertValue(init, len_, []uint32{1})
+		global.SetInitializer(init)
+	}
+	return nil
+}
@@ -5,8 +5,10 @@
 package llgo
 
 import (
+	"bytes"
 	"fmt"
 	"go/ast"
+	"hash/fnv"
 	"reflect"
 
 	"code.google.com/p/go.tools/go/types"
@@ -49,6 +51,15 @@ type TypeMap struct {
 	alg            *algorithmMap
 	types.MethodSetCache
 
+	// typelinks holds the runtime types defined in the module
+	// that are registered with the runtime for reflect, in the
+	// order they were created.
+	typelinks []llvm.Value
+
+	// itabs maps interface and concrete type
+	// string pairs to statically emitted itabs.
+	itabs map[string]llvm.Value
+
 	hashAlgFunctionType,
 	equalAlgFunctionType,
 	printAlgFunctionType,
@@ -82,6 +93,7 @@ func NewTypeMap(pkgpath string, llvmtm *llvmTypeMap, module llvm.Module, r *runt
 		runtime:        r,
 		methodResolver: mr,
 		alg:            newAlgorithmMap(module, r, llvmtm.target),
+		itabs:          make(map[string]llvm.Value),
 	}
 }
 
@@ -309,10 +321,16 @@ func (tm *llvmTypeMap) chanLLVMType(c *types.Chan) llvm.Type {
 }
 
 func (tm *llvmTypeMap) nameLLVMType(n *types.Named) llvm.Type {
+	if isSIMDType(n) {
+		return tm.vectorLLVMType(n.Underlying().(*types.Array))
+	}
 	return tm.toLLVM(n.Underlying(), n.String())
 }
 
 func (tm *llvmTypeMap) Alignof(typ types.Type) int64 {
+	if isSIMDType(typ) {
+		return int64(tm.target.ABITypeAlignment(tm.ToLLVM(typ)))
+	}
 	switch typ := typ.Underlying().(type) {
 	case *types.Array:
 		return tm.Alignof(typ.Elem())
@@ -371,7 +389,8 @@ func (tm *llvmTypeMap) Sizeof(typ types.Type) int64 {
 		offsets := tm.Offsetsof(fields)
 		return offsets[n-1] + tm.Sizeof(fields[n-1].Type())
 	case *types.Interface:
-		return int64((2 + typ.NumMethods()) * tm.target.PointerSize())
+		// {type or itab, value}
+		return int64(2 * tm.target.PointerSize())
 	}
 	return int64(tm.target.PointerSize())
 }
@@ -400,7 +419,7 @@ func (tm *TypeMap) makeRuntimeType(t types.Type) (global, ptr llvm.Value) {
 	case *types.Array:
 		global, ptr = tm.arrayRuntimeType(t)
 	case *types.Slice:
-		return tm.sliceRuntimeType(t)
+		global, ptr = tm.sliceRuntimeType(t)
 	case *types.Struct:
 		return tm.structRuntimeType(t)
 	case *types.Pointer:
@@ -419,6 +438,14 @@ func (tm *TypeMap) makeRuntimeType(t types.Type) (global, ptr llvm.Value) {
 		panic(fmt.Sprintf("unhandled type: %#v", t))
 	}
 	tm.types.Set(t, runtimeTypeInfo{global, ptr})
+
+	// Record the types that reflect may look up by string.
+	switch t.(type) {
+	case *types.Array, *types.Slice, *types.Pointer, *types.Map, *types.Chan:
+		if !global.IsDeclaration() {
+			tm.typelinks = append(tm.typelinks, ptr)
+		}
+	}
 	return global, ptr
 }
 
@@ -437,14 +464,27 @@ func (tm *TypeMap) makeAlgorithmTable(t types.Type) llvm.Value {
 	return llvm.ConstStruct(elems, false)
 }
 
+// makeRuntimeTypeGlobal creates a global for a runtime type descriptor.
+//
+// Descriptors are emitted into every module that refers to them, with
+// linkonce_odr linkage; as their symbols are derived from the canonical
+// type string, the linker merges the copies into one. Descriptors are
+// then unique for each type, and may be compared by address.
 func (tm *TypeMap) makeRuntimeTypeGlobal(v llvm.Value, name string) (global, ptr llvm.Value) {
 	global = llvm.AddGlobal(tm.module, v.Type(), typeSymbol(name))
 	global.SetInitializer(v)
-	global.SetLinkage(llvm.LinkOnceAnyLinkage)
+	global.SetLinkage(llvm.LinkOnceODRLinkage)
 	ptr = llvm.ConstBitCast(global, llvm.PointerType(tm.runtime.rtype.llvm, 0))
 	return global, ptr
 }
 
+// runtimeTypeDecl declares the runtime type descriptor of a type
+// defined by another package: a named type, a basic type (defined
+// by the runtime), or a pointer to either.
+func (tm *TypeMap) runtimeTypeDecl(name string) llvm.Value {
+	return llvm.AddGlobal(tm.module, tm.runtime.rtype.llvm, typeSymbol(name))
+}
+
 func (tm *TypeMap) makeRtype(t types.Type, k reflect.Kind) llvm.Value {
 	// Not sure if there's an easier way to do this, but if you just
 	// use ConstStruct, you end up getting a different llvm.Type.
@@ -455,7 +495,10 @@ func (tm *TypeMap) makeRtype(t types.Type, k reflect.Kind) llvm.Value {
 	size := llvm.ConstInt(elementTypes[0], uint64(tm.Sizeof(t)), false)
 	typ = llvm.ConstInsertValue(typ, size, []uint32{0})
 
-	// TODO hash
+	// Hash.
+	hash := llvm.ConstInt(llvm.Int32Type(), uint64(typeHash(t)), false)
+	typ = llvm.ConstInsertValue(typ, hash, []uint32{1})
+
 	// TODO padding
 
 	// Alignment.
@@ -469,13 +512,12 @@ func (tm *TypeMap) makeRtype(t types.Type, k reflect.Kind) llvm.Value {
 
 	// Algorithm table.
 	alg := tm.makeAlgorithmTable(t)
-	algptr := llvm.AddGlobal(tm.module, alg.Type(), "")
-	algptr.SetInitializer(alg)
+	algptr := addPrivateGlobal(tm.module, typeSymbol(typeString(t))+".alg", alg)
 	algptr = llvm.ConstBitCast(algptr, elementTypes[6])
 	typ = llvm.ConstInsertValue(typ, algptr, []uint32{6})
 
 	// String representation.
-	stringrep := tm.globalStringPtr(t.String())
+	stringrep := tm.globalStringPtr(reflectTypeString(t))
 	typ = llvm.ConstInsertValue(typ, stringrep, []uint32{8})
 
 	// TODO gc
@@ -504,8 +546,193 @@ var basicReflectKinds = [...]reflect.Kind{
 	types.UnsafePointer: reflect.UnsafePointer,
 }
 
+// typeString returns the canonical string for a type, from which
+// the symbol of its runtime type descriptor is derived. Identical
+// types have the same string in every package: aliases are resolved,
+// named types are qualified with the full package path, as are the
+// unexported names of struct fields and interface methods, and the
+// names of parameters are omitted.
 func typeString(t types.Type) string {
-	return types.TypeString(nil, t)
+	var buf bytes.Buffer
+	writeTypeString(&buf, t, true)
+	return buf.String()
+}
+
+// typeHash returns the hash of a type, as stored in its runtime
+// type descriptor. The hash is that of the type's canonical string,
+// so it is the same in every package.
+func typeHash(t types.Type) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(typeString(t)))
+	return h.Sum32()
+}
+
+// reflectTypeString returns the string stored in the runtime type
+// descriptor of a type, which is formatted as by gc: named types are
+// qualified with the package name, and struct and interface types
+// are spaced as by reflect. The reflect package builds the strings
+// of derived types from these (e.g. "[]" + the element type's), to
+// look up the types in typelinks.
+func reflectTypeString(t types.Type) string {
+	var buf bytes.Buffer
+	writeTypeString(&buf, t, false)
+	return buf.String()
+}
+
+// writeTypeString writes the canonical string of a type if
+// canonical is true, and otherwise its reflect string.
+func writeTypeString(buf *bytes.Buffer, t types.Type, canonical bool) {
+	switch t := t.(type) {
+	case *types.Basic:
+		if t.Kind() == types.UnsafePointer {
+			buf.WriteString("unsafe.Pointer")
+		} else {
+			buf.WriteString(types.Typ[t.Kind()].Name())
+		}
+	case *types.Named:
+		obj := t.Obj()
+		if pkg := obj.Pkg(); pkg != nil {
+			if canonical {
+				buf.WriteString(pkg.Path())
+			} else {
+				buf.WriteString(pkg.Name())
+			}
+			buf.WriteByte('.')
+		}
+		buf.WriteString(obj.Name())
+	case *types.Pointer:
+		buf.WriteByte('*')
+		writeTypeString(buf, t.Elem(), canonical)
+	case *types.Array:
+		fmt.Fprintf(buf, "[%d]", t.Len())
+		writeTypeString(buf, t.Elem(), canonical)
+	case *types.Slice:
+		buf.WriteString("[]")
+		writeTypeString(buf, t.Elem(), canonical)
+	case *types.Map:
+		buf.WriteString("map[")
+		writeTypeString(buf, t.Key(), canonical)
+		buf.WriteByte(']')
+		writeTypeString(buf, t.Elem(), canonical)
+	case *types.Chan:
+		parens := false
+		switch t.Dir() {
+		case types.SendOnly:
+			buf.WriteString("chan<- ")
+		case types.RecvOnly:
+			buf.WriteString("<-chan ")
+		default:
+			buf.WriteString("chan ")
+			if elem, ok := t.Elem().(*types.Chan); ok && elem.Dir() == types.RecvOnly {
+				parens = true
+			}
+		}
+		if parens {
+			buf.WriteByte('(')
+		}
+		writeTypeString(buf, t.Elem(), canonical)
+		if parens {
+			buf.WriteByte(')')
+		}
+	case *types.Signature:
+		buf.WriteString("func")
+		writeSignatureString(buf, t, canonical)
+	case *types.Struct:
+		// The canonical string is unspaced; the
+		// reflect string is spaced as gc's is.
+		if !canonical && t.NumFields() == 0 {
+			buf.WriteString("struct {}")
+			break
+		}
+		prefix, suffix := "struct{", "}"
+		if !canonical {
+			prefix, suffix = "struct { ", " }"
+		}
+		buf.WriteString(prefix)
+		for i := 0; i < t.NumFields(); i++ {
+			if i > 0 {
+				buf.WriteString("; ")
+			}
+			field := t.Field(i)
+			if !field.Anonymous() {
+				writeMemberName(buf, field.Pkg(), field.Name(), canonical)
+				buf.WriteByte(' ')
+			}
+			writeTypeString(buf, field.Type(), canonical)
+			if tag := t.Tag(i); tag != "" {
+				fmt.Fprintf(buf, " %q", tag)
+			}
+		}
+		buf.WriteString(suffix)
+	case *types.Interface:
+		methodset := types.NewMethodSet(t)
+		if !canonical && methodset.Len() == 0 {
+			buf.WriteString("interface {}")
+			break
+		}
+		prefix, suffix := "interface{", "}"
+		if !canonical {
+			prefix, suffix = "interface { ", " }"
+		}
+		buf.WriteString(prefix)
+		for i := 0; i < methodset.Len(); i++ {
+			if i > 0 {
+				buf.WriteString("; ")
+			}
+			method := methodset.At(i).Obj()
+			writeMemberName(buf, method.Pkg(), method.Name(), canonical)
+			writeSignatureString(buf, method.Type().(*types.Signature), canonical)
+		}
+		buf.WriteString(suffix)
+	default:
+		panic(fmt.Sprintf("unhandled type: %#v", t))
+	}
+}
+
+// writeSignatureString writes the parameter and result
+// types of a function signature, without the receiver.
+func writeSignatureString(buf *bytes.Buffer, sig *types.Signature, canonical bool) {
+	params := sig.Params()
+	buf.WriteByte('(')
+	for i := 0; i < params.Len(); i++ {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		typ := params.At(i).Type()
+		if sig.Variadic() && i == params.Len()-1 {
+			buf.WriteString("...")
+			typ = typ.(*types.Slice).Elem()
+		}
+		writeTypeString(buf, typ, canonical)
+	}
+	buf.WriteByte(')')
+	results := sig.Results()
+	switch results.Len() {
+	case 0:
+	case 1:
+		buf.WriteByte(' ')
+		writeTypeString(buf, results.At(0).Type(), canonical)
+	default:
+		buf.WriteString(" (")
+		for i := 0; i < results.Len(); i++ {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			writeTypeString(buf, results.At(i).Type(), canonical)
+		}
+		buf.WriteByte(')')
+	}
+}
+
+// writeMemberName writes the name of a struct field or interface
+// method. In a canonical string, unexported names are qualified
+// with the package path, as they are distinct in each package.
+func writeMemberName(buf *bytes.Buffer, pkg *types.Package, name string, canonical bool) {
+	if canonical && pkg != nil && !ast.IsExported(name) {
+		buf.WriteString(pkg.Path())
+		buf.WriteByte('.')
+	}
+	buf.WriteString(name)
 }
 
 func typeSymbol(name string) string {
@@ -524,23 +751,13 @@ func (tm *TypeMap) basicRuntimeType(b *types.Basic, underlying bool) (global, pt
 	if !underlying {
 		name = typeString(b)
 		if tm.pkgpath != "runtime" {
-			global := llvm.AddGlobal(tm.module, tm.runtime.rtype.llvm, typeSymbol(name))
-			global.SetInitializer(llvm.ConstNull(tm.runtime.rtype.llvm))
-			global.SetLinkage(llvm.CommonLinkage)
+			global := tm.runtimeTypeDecl(name)
 			return global, global
 		}
 	}
 	rtype := tm.makeRtype(b, basicReflectKinds[b.Kind()])
 	global, ptr = tm.makeRuntimeTypeGlobal(rtype, name)
 	global.SetLinkage(llvm.ExternalLinkage)
-	if !underlying {
-		switch b.Kind() {
-		case types.Int32:
-			llvm.AddAlias(tm.module, global.Type(), global, typeSymbol("rune"))
-		case types.Uint8:
-			llvm.AddAlias(tm.module, global.Type(), global, typeSymbol("byte"))
-		}
-	}
 	return global, ptr
 }
 
@@ -613,15 +830,14 @@ func (tm *TypeMap) structRuntimeType(s *types.Struct) (global, ptr llvm.Value) {
 }
 
 func (tm *TypeMap) pointerRuntimeType(p *types.Pointer) (global, ptr llvm.Value) {
-	// Is the base type a named type from another package? If so, we'll
-	// create a reference to the externally defined symbol.
-	linkage := llvm.LinkOnceAnyLinkage
+	// Is the base type a named type from another package, or a basic
+	// type? If so, we'll declare the descriptor, which is defined along
+	// with that of the base type.
+	linkage := llvm.LinkOnceODRLinkage
 	switch elem := p.Elem().(type) {
 	case *types.Basic:
 		if tm.pkgpath != "runtime" {
-			global := llvm.AddGlobal(tm.module, tm.runtime.rtype.llvm, typeSymbol(typeString(p)))
-			global.SetInitializer(llvm.ConstNull(tm.runtime.rtype.llvm))
-			global.SetLinkage(llvm.CommonLinkage)
+			global := tm.runtimeTypeDecl(typeString(p))
 			return global, global
 		}
 		linkage = llvm.ExternalLinkage
@@ -631,9 +847,7 @@ func (tm *TypeMap) pointerRuntimeType(p *types.Pointer) (global, ptr llvm.Value)
 			path = pkg.Path()
 		}
 		if path != tm.pkgpath {
-			global := llvm.AddGlobal(tm.module, tm.runtime.rtype.llvm, typeSymbol(typeString(p)))
-			global.SetInitializer(llvm.ConstNull(tm.runtime.rtype.llvm))
-			global.SetLinkage(llvm.CommonLinkage)
+			global := tm.runtimeTypeDecl(typeString(p))
 			return global, global
 		}
 		linkage = llvm.ExternalLinkage
@@ -642,8 +856,7 @@ func (tm *TypeMap) pointerRuntimeType(p *types.Pointer) (global, ptr llvm.Value)
 	rtype := tm.makeRtype(p, reflect.Ptr)
 	if n, ok := p.Elem().(*types.Named); ok {
 		uncommonTypeInit := tm.uncommonType(n, p)
-		uncommonType := llvm.AddGlobal(tm.module, uncommonTypeInit.Type(), "")
-		uncommonType.SetInitializer(uncommonTypeInit)
+		uncommonType := addPrivateGlobal(tm.module, typeSymbol(typeString(p))+".uncommon", uncommonTypeInit)
 		rtype = llvm.ConstInsertValue(rtype, uncommonType, []uint32{9})
 	}
 
@@ -667,7 +880,11 @@ func (tm *TypeMap) pointerRuntimeType(p *types.Pointer) (global, ptr llvm.Value)
 	}
 	global.SetLinkage(linkage)
 
-	// Set ptrToThis in the base type's rtype.
+	// Set ptrToThis in the base type's rtype, if it is
+	// defined in this module rather than declared.
+	if baseTypeGlobal.IsDeclaration() {
+		return global, ptr
+	}
 	baseType := baseTypeGlobal.Initializer()
 	if !baseType.IsNull() {
 		if baseType.Type() == tm.runtime.rtype.llvm {
@@ -708,6 +925,12 @@ func (tm *TypeMap) funcRuntimeType(f *types.Signature) (global, ptr llvm.Value)
 	// out
 	outtypes := tm.rtypeSlice(f.Results())
 	funcType = llvm.ConstInsertValue(funcType, outtypes, []uint32{3})
+	// call
+	call := llvm.ConstPtrToInt(tm.reflectCallShim(f), tm.target.IntPtrType())
+	funcType = llvm.ConstInsertValue(funcType, call, []uint32{4})
+	// makefunc
+	makefunc := llvm.ConstPtrToInt(tm.makeFuncStub(f), tm.target.IntPtrType())
+	funcType = llvm.ConstInsertValue(funcType, makefunc, []uint32{5})
 	global.SetInitializer(funcType)
 	return global, ptr
 }
@@ -791,7 +1014,7 @@ func (tm *TypeMap) uncommonType(n *types.Named, p *types.Pointer) llvm.Value {
 		return uncommonTypeInit
 	}
 
-	var methodset, pmethodset *types.MethodSet
+	var methodset *types.MethodSet
 	if p != nil {
 		methodset = tm.MethodSet(p)
 	} else {
@@ -800,6 +1023,8 @@ func (tm *TypeMap) uncommonType(n *types.Named, p *types.Pointer) llvm.Value {
 
 	// Store methods. All methods must be stored, not only exported ones;
 	// this is to allow satisfying of interfaces with non-exported methods.
+	// The method set is sorted by package-qualified name, as are those of
+	// interfaces, which the runtime relies upon when computing itabs.
 	methods := make([]llvm.Value, methodset.Len())
 	for i := range methods {
 		sel := methodset.At(i)
@@ -812,37 +1037,36 @@ func (tm *TypeMap) uncommonType(n *types.Named, p *types.Pointer) llvm.Value {
 		name = llvm.ConstBitCast(name, tm.runtime.method.llvm.StructElementTypes()[0])
 		// name
 		method = llvm.ConstInsertValue(method, name, []uint32{0})
-		// pkgPath
-		method = llvm.ConstInsertValue(method, pkgpathPtr, []uint32{1})
+		// pkgPath (unexported methods only)
+		if !ast.IsExported(mname) {
+			pkgpath := tm.globalStringPtr(sel.Obj().Pkg().Path())
+			method = llvm.ConstInsertValue(method, pkgpath, []uint32{1})
+		}
 		// mtyp (method type, no receiver)
 		{
 			ftyp := types.NewSignature(nil, nil, ftyp.Params(), ftyp.Results(), ftyp.Variadic())
 			mtyp := tm.ToRuntime(ftyp)
 			method = llvm.ConstInsertValue(method, mtyp, []uint32{2})
 		}
-		// typ (function type, with receiver)
-		typ := tm.ToRuntime(ftyp)
-		method = llvm.ConstInsertValue(method, typ, []uint32{3})
+		// typ (function type, with receiver as the first parameter)
+		{
+			params := []*types.Var{ftyp.Recv()}
+			for i := 0; i < ftyp.Params().Len(); i++ {
+				params = append(params, ftyp.Params().At(i))
+			}
+			ftyp := types.NewSignature(nil, nil, types.NewTuple(params...), ftyp.Results(), ftyp.Variadic())
+			typ := tm.ToRuntime(ftyp)
+			method = llvm.ConstInsertValue(method, typ, []uint32{3})
+		}
 
-		// tfn (standard method/function pointer for plain method calls)
-		tfn := llvm.ConstPtrToInt(mfunc.LLVMValue(), tm.target.IntPtrType())
+		// tfn (standard method/function pointer for plain method calls,
+		// taking a context that is ignored, so that reflect may call
+		// it as the code of a function value)
+		tfn := tm.methodContextFunc(mfunc.LLVMValue())
+		tfn = llvm.ConstPtrToInt(tfn, tm.target.IntPtrType())
 
 		// ifn (single-word receiver function pointer for interface calls)
-		ifn := tfn
-		if p == nil {
-			if tm.Sizeof(n) > int64(tm.target.PointerSize()) {
-				if pmethodset == nil {
-					pmethodset = tm.MethodSet(types.NewPointer(n))
-				}
-				pmfunc := tm.methodResolver.ResolveMethod(pmethodset.Lookup(sel.Obj().Pkg(), mname))
-				ifn = llvm.ConstPtrToInt(pmfunc.LLVMValue(), tm.target.IntPtrType())
-			} else if _, ok := n.Underlying().(*types.Pointer); !ok {
-				// Create a wrapper function that takes an *int8,
-				// and coerces to the receiver type.
-				ifn = tm.interfaceFuncWrapper(mfunc.LLVMValue())
-				ifn = llvm.ConstPtrToInt(ifn, tm.target.IntPtrType())
-			}
-		}
+		ifn := tm.interfaceMethodFunc(n, p, sel)
 
 		method = llvm.ConstInsertValue(method, ifn, []uint32{4})
 		method = llvm.ConstInsertValue(method, tfn, []uint32{5})
@@ -854,6 +1078,78 @@ func (tm *TypeMap) uncommonType(n *types.Named, p *types.Pointer) llvm.Value {
 	return uncommonTypeInit
 }
 
+// interfaceMethodFunc returns the function pointer, as a uintptr,
+// through which the selected method of n (or p, if non-nil) is
+// called via an interface, with the single-word receiver stored
+// in the interface value.
+func (tm *TypeMap) interfaceMethodFunc(n *types.Named, p *types.Pointer, sel *types.Selection) llvm.Value {
+	mfunc := tm.methodResolver.ResolveMethod(sel)
+	if p == nil {
+		if tm.Sizeof(n) > int64(tm.target.PointerSize()) {
+			// The value is stored indirectly, so
+			// call the pointer receiver method.
+			pmethodset := tm.MethodSet(types.NewPointer(n))
+			mfunc = tm.methodResolver.ResolveMethod(pmethodset.Lookup(sel.Obj().Pkg(), sel.Obj().Name()))
+		} else if _, ok := n.Underlying().(*types.Pointer); !ok {
+			// Create a wrapper function that takes an *int8,
+			// and coerces to the receiver type.
+			ifn := tm.interfaceFuncWrapper(mfunc.LLVMValue())
+			return llvm.ConstPtrToInt(ifn, tm.target.IntPtrType())
+		}
+	}
+	return llvm.ConstPtrToInt(mfunc.LLVMValue(), tm.target.IntPtrType())
+}
+
+// ToItab returns a pointer to a statically initialised itab for
+// converting values of type t to the interface type iface. If the
+// methods of t are not defined by the package being compiled, then
+// the itab must be computed at runtime, and ToItab returns false.
+func (tm *TypeMap) ToItab(iface, t types.Type) (llvm.Value, bool) {
+	var n *types.Named
+	p, _ := t.(*types.Pointer)
+	if p != nil {
+		n, _ = p.Elem().(*types.Named)
+	} else {
+		n, _ = t.(*types.Named)
+	}
+	if n == nil || n.Obj().Pkg() == nil || n.Obj().Pkg().Path() != tm.pkgpath {
+		return llvm.Value{}, false
+	}
+	if _, ok := n.Underlying().(*types.Interface); ok {
+		return llvm.Value{}, false
+	}
+
+	key := typeString(iface) + "\x00" + typeString(t)
+	if itab, ok := tm.itabs[key]; ok {
+		return itab, true
+	}
+	itabtyp := tm.runtime.itab.llvm
+	elementTypes := itabtyp.StructElementTypes()
+
+	imethodset := tm.MethodSet(iface)
+	methodset := tm.MethodSet(t)
+	fns := make([]llvm.Value, imethodset.Len())
+	for i := range fns {
+		imethod := imethodset.At(i).Obj()
+		sel := methodset.Lookup(imethod.Pkg(), imethod.Name())
+		fns[i] = tm.interfaceMethodFunc(n, p, sel)
+	}
+	inter := llvm.ConstBitCast(tm.ToRuntime(iface), elementTypes[0])
+	init := llvm.ConstStruct([]llvm.Value{
+		inter,
+		tm.ToRuntime(t),
+		llvm.ConstNull(elementTypes[2]),
+		llvm.ConstNull(elementTypes[3]),
+		llvm.ConstNull(elementTypes[4]),
+		llvm.ConstArray(elementTypes[5], fns),
+	}, false)
+	global := addPrivateGlobal(tm.module, "__llgo.itab", init)
+	global.SetGlobalConstant(true)
+	itab := llvm.ConstBitCast(global, llvm.PointerType(itabtyp, 0))
+	tm.itabs[key] = itab
+	return itab, true
+}
+
 func (tm *TypeMap) nameRuntimeType(n *types.Named) (global, ptr llvm.Value) {
 	name := typeString(n)
 	path := "runtime"
@@ -863,9 +1159,7 @@ func (tm *TypeMap) nameRuntimeType(n *types.Named) (global, ptr llvm.Value) {
 	if path != tm.pkgpath {
 		// We're not compiling the package from whence the type came,
 		// so we'll just create a pointer to it here.
-		global := llvm.AddGlobal(tm.module, tm.runtime.rtype.llvm, typeSymbol(name))
-		global.SetInitializer(llvm.ConstNull(tm.runtime.rtype.llvm))
-		global.SetLinkage(llvm.CommonLinkage)
+		global := tm.runtimeTypeDecl(name)
 		return global, global
 	}
 
@@ -894,14 +1188,17 @@ func (tm *TypeMap) nameRuntimeType(n *types.Named) (global, ptr llvm.Value) {
 
 	// Insert the uncommon type.
 	uncommonTypeInit := tm.uncommonType(n, nil)
-	uncommonType := llvm.AddGlobal(tm.module, uncommonTypeInit.Type(), "")
-	uncommonType.SetInitializer(uncommonTypeInit)
+	uncommonType := addPrivateGlobal(tm.module, typeSymbol(typeString(n))+".uncommon", uncommonTypeInit)
 	rtype = llvm.ConstInsertValue(rtype, uncommonType, []uint32{9})
 
-	// Replace the rtype's string representation with the one from
-	// uncommonType. XXX should we have the package name prepended? Probably.
-	namePtr := llvm.ConstExtractValue(uncommonTypeInit, []uint32{0})
-	rtype = llvm.ConstInsertValue(rtype, namePtr, []uint32{8})
+	// Replace the rtype's string representation with
+	// the named type's, qualified with the package name.
+	stringrep := tm.globalStringPtr(reflectTypeString(n))
+	rtype = llvm.ConstInsertValue(rtype, stringrep, []uint32{8})
+
+	// Replace the rtype's hash with the named type's.
+	hash := llvm.ConstInt(llvm.Int32Type(), uint64(typeHash(n)), false)
+	rtype = llvm.ConstInsertValue(rtype, hash, []uint32{1})
 
 	// Update the global's initialiser. Note that we take a copy
 	// of the underlying type; we're not updating a shared type.
@@ -917,14 +1214,11 @@ func (tm *TypeMap) nameRuntimeType(n *types.Named) (global, ptr llvm.Value) {
 // globalStringPtr returns a *string with the specified value.
 func (tm *TypeMap) globalStringPtr(value string) llvm.Value {
 	strval := llvm.ConstString(value, false)
-	strglobal := llvm.AddGlobal(tm.module, strval.Type(), "")
-	strglobal.SetInitializer(strval)
+	strglobal := addPrivateGlobal(tm.module, "__llgo.str", strval)
 	strglobal = llvm.ConstBitCast(strglobal, llvm.PointerType(llvm.Int8Type(), 0))
 	strlen := llvm.ConstInt(tm.inttype, uint64(len(value)), false)
 	str := llvm.ConstStruct([]llvm.Value{strglobal, strlen}, false)
-	g := llvm.AddGlobal(tm.module, str.Type(), "")
-	g.SetInitializer(str)
-	return g
+	return addPrivateGlobal(tm.module, "__llgo.strptr", str)
 }
 
 func (tm *TypeMap) makeSlice(values []llvm.Value, slicetyp llvm.Type) llvm.Value {
@@ -932,8 +1226,7 @@ func (tm *TypeMap) makeSlice(values []llvm.Value, slicetyp llvm.Type) llvm.Value
 	var globalptr llvm.Value
 	if len(values) > 0 {
 		array := llvm.ConstArray(ptrtyp.ElementType(), values)
-		globalptr = llvm.AddGlobal(tm.module, array.Type(), "")
-		globalptr.SetInitializer(array)
+		globalptr = addPrivateGlobal(tm.module, "__llgo.slice", array)
 		globalptr = llvm.ConstBitCast(globalptr, ptrtyp)
 	} else {
 		globalptr = llvm.ConstNull(ptrtyp)
@@ -952,11 +1245,17 @@ func isGlobalObject(obj types.Object) bool {
 }
 
 func (tm *TypeMap) interfaceFuncWrapper(f llvm.Value) llvm.Value {
+	// The wrapper may have been created already,
+	// for the type's method table or for an itab.
+	name := f.Name() + ".ifn"
+	if newf := f.GlobalParent().NamedFunction(name); !newf.IsNil() {
+		return newf
+	}
 	ftyp := f.Type().ElementType()
 	paramTypes := ftyp.ParamTypes()
 	recvType := paramTypes[0]
 	paramTypes[0] = llvm.PointerType(llvm.Int8Type(), 0)
-	newf := llvm.AddFunction(f.GlobalParent(), f.Name()+".ifn", llvm.FunctionType(
+	newf := llvm.AddFunction(f.GlobalParent(), name, llvm.FunctionType(
 		ftyp.ReturnType(),
 		paramTypes,
 		ftyp.IsFunctionVarArg(),
@@ -7,6 +7,7 @@ package llgo
 import (
 	"code.google.com/p/go.tools/go/types"
 	"fmt"
+	"github.com/axw/gollvm/llvm"
 )
 
 func deref(t types.Type) types.Type {
@@ -46,6 +47,56 @@ func (c *compiler) exportRuntimeTypes(exportedTypes []types.Type, builtin bool)
 	}
 }
 
+// createTypelinks emits the list of runtime types recorded by the
+// type map, for reflect's typelinks, and a static constructor that
+// registers the list with the runtime. The constructor runs before
+// the runtime is initialised, so it only links the module's list
+// into runtime.typelinkModules; the runtime merges the lists of all
+// modules the first time they are needed.
+func (c *compiler) createTypelinks() {
+	typelinks := c.types.typelinks
+	if len(typelinks) == 0 {
+		return
+	}
+	m := c.module.Module
+	i8ptr := llvm.PointerType(llvm.Int8Type(), 0)
+	rtypeptr := llvm.PointerType(c.runtime.rtype.llvm, 0)
+
+	// The list is a runtime.typelinkModule:
+	// {next *typelinkModule, types []*rtype}.
+	array := addPrivateGlobal(m, "__llgo.typelinks", llvm.ConstArray(rtypeptr, typelinks))
+	n := llvm.ConstInt(c.types.inttype, uint64(len(typelinks)), false)
+	slice := llvm.ConstStruct([]llvm.Value{
+		llvm.ConstBitCast(array, llvm.PointerType(rtypeptr, 0)), n, n,
+	}, false)
+	node := addPrivateGlobal(m, "__llgo.typelinks.module", llvm.ConstStruct([]llvm.Value{
+		llvm.ConstNull(i8ptr), slice,
+	}, false))
+
+	head := m.NamedGlobal("runtime.typelinkModules")
+	if head.IsNil() {
+		head = llvm.AddGlobal(m, i8ptr, "runtime.typelinkModules")
+	}
+	head = llvm.ConstBitCast(head, llvm.PointerType(i8ptr, 0))
+
+	ftyp := llvm.FunctionType(llvm.VoidType(), nil, false)
+	fn := llvm.AddFunction(m, "__llgo.typelinks.init", ftyp)
+	fn.SetLinkage(llvm.PrivateLinkage)
+	c.builder.SetCurrentDebugLocation(c.debug.MDNode(nil))
+	c.builder.SetInsertPointAtEnd(llvm.AddBasicBlock(fn, "entry"))
+	next := c.builder.CreateStructGEP(node, 0, "")
+	c.builder.CreateStore(c.builder.CreateLoad(head, ""), next)
+	c.builder.CreateStore(llvm.ConstBitCast(node, i8ptr), head)
+	c.builder.CreateRetVoid()
+
+	ctor := llvm.ConstStruct([]llvm.Value{
+		llvm.ConstInt(llvm.Int32Type(), 65535, false), fn,
+	}, false)
+	ctors := llvm.AddGlobal(m, llvm.ArrayType(ctor.Type(), 1), "llvm.global_ctors")
+	ctors.SetInitializer(llvm.ConstArray(ctor.Type(), []llvm.Value{ctor}))
+	ctors.SetLinkage(llvm.AppendingLinkage)
+}
+
 // tupleType returns a struct type with anonymous
 // fields with the specified types.
 func tupleType(fieldTypes ...types.Type) types.Type {
@@ -0,0 +1,226 @@
+// Copyright 2014 The llgo Authors.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package llgo
+
+import (
+	"code.google.com/p/go.tools/go/ssa"
+	"code.google.com/p/go.tools/go/ssa/ssautil"
+	"code.google.com/p/go.tools/go/types"
+	"github.com/axw/gollvm/llvm"
+)
+
+// typeSwitch is a type switch with two or more cases of concrete
+// types. Rather than calling into the runtime for each case, the
+// switch determines which of the concrete types (if any) is the
+// dynamic type of the operand up front, by switching on the hash
+// in its runtime type descriptor and comparing the descriptor's
+// address with those of the types with that hash. Only if that
+// finds no match is the dynamic type compared with each of the
+// types by the runtime, as a type assertion would.
+type typeSwitch struct {
+	// start is the block in which the switch begins.
+	start *ssa.BasicBlock
+
+	// types holds the concrete types of the cases,
+	// in the order in which they appear.
+	types []types.Type
+
+	// selector is the index in types of the dynamic
+	// type of the operand, or -1 if it is none of them.
+	selector llvm.Value
+}
+
+// typeSwitchCase identifies the case of a type switch
+// that a type assertion tests.
+type typeSwitchCase struct {
+	sw *typeSwitch
+
+	// index is the index of the case's type in sw.types,
+	// or -1 if the case's type is an interface type.
+	index int
+}
+
+// findTypeSwitches returns the type assertions of the function's
+// type switches that are dispatched on type hashes.
+func (fr *frame) findTypeSwitches(f *ssa.Function) map[*ssa.TypeAssert]typeSwitchCase {
+	var cases map[*ssa.TypeAssert]typeSwitchCase
+	for _, sw := range ssautil.Switches(f) {
+		if len(sw.TypeCases) == 0 {
+			continue
+		}
+		start := typeAssertIn(sw.Start, sw.X)
+		if start == nil {
+			continue
+		}
+		ts := &typeSwitch{start: sw.Start}
+		var asserts []*ssa.TypeAssert
+		for _, tc := range sw.TypeCases {
+			if _, ok := tc.Type.Underlying().(*types.Interface); ok {
+				continue
+			}
+			if instr := typeAssertIn(tc.Block, sw.X); instr != nil {
+				ts.types = append(ts.types, tc.Type)
+				asserts = append(asserts, instr)
+			}
+		}
+		if len(ts.types) < 2 {
+			continue
+		}
+		if cases == nil {
+			cases = make(map[*ssa.TypeAssert]typeSwitchCase)
+		}
+		cases[start] = typeSwitchCase{ts, -1}
+		for i, instr := range asserts {
+			cases[instr] = typeSwitchCase{ts, i}
+		}
+	}
+	return cases
+}
+
+// typeAssertIn returns the last comma-ok type assertion
+// on x in the block, or nil if there is none.
+func typeAssertIn(b *ssa.BasicBlock, x ssa.Value) *ssa.TypeAssert {
+	for i := len(b.Instrs) - 1; i >= 0; i-- {
+		if instr, ok := b.Instrs[i].(*ssa.TypeAssert); ok && instr.X == x && instr.CommaOk {
+			return instr
+		}
+	}
+	return nil
+}
+
+// dispatchTypeSwitch emits code to compute the switch's selector
+// from the dynamic type of the interface value x.
+func (fr *frame) dispatchTypeSwitch(sw *typeSwitch, x *LLVMValue) {
+	i8ptr := llvm.PointerType(llvm.Int8Type(), 0)
+	int32type := llvm.Int32Type()
+	nomatch := llvm.ConstAllOnes(int32type)
+	fn := fr.builder.GetInsertBlock().Parent()
+	done := llvm.AddBasicBlock(fn, "typeswitch.done")
+	hashblock := llvm.AddBasicBlock(fn, "typeswitch.hash")
+	var values []llvm.Value
+	var blocks []llvm.BasicBlock
+
+	// The first word of an interface value is nil iff the value
+	// is nil; for non-empty interfaces, it is the itab, which
+	// refers to the dynamic type.
+	word := fr.builder.CreateExtractValue(x.LLVMValue(), 0, "")
+	fr.builder.CreateCondBr(fr.builder.CreateIsNull(word, ""), done, hashblock)
+	values = append(values, nomatch)
+	blocks = append(blocks, fr.builder.GetInsertBlock())
+
+	fr.builder.SetInsertPointAtEnd(hashblock)
+	dyntyp := word
+	if x.Type().Underlying().(*types.Interface).NumMethods() > 0 {
+		itab := fr.builder.CreateBitCast(word, llvm.PointerType(fr.runtime.itab.llvm, 0), "")
+		dyntyp = fr.builder.CreateLoad(fr.builder.CreateStructGEP(itab, 1, ""), "")
+		dyntyp = fr.builder.CreateBitCast(dyntyp, i8ptr, "")
+	}
+	rtype := fr.builder.CreateBitCast(dyntyp, llvm.PointerType(fr.runtime.rtype.llvm, 0), "")
+	hash := fr.builder.CreateLoad(fr.builder.CreateStructGEP(rtype, 1, ""), "")
+
+	// Group the types by hash, in the order in which they appear.
+	var hashes []uint32
+	groups := make(map[uint32][]int)
+	for i, typ := range sw.types {
+		h := typeHash(typ)
+		if _, ok := groups[h]; !ok {
+			hashes = append(hashes, h)
+		}
+		groups[h] = append(groups[h], i)
+	}
+	fallback := llvm.AddBasicBlock(fn, "typeswitch.fallback")
+	llswitch := fr.builder.CreateSwitch(hash, fallback, len(hashes))
+	for _, h := range hashes {
+		block := llvm.AddBasicBlock(fn, "typeswitch.case")
+		llswitch.AddCase(llvm.ConstInt(int32type, uint64(h), false), block)
+		fr.builder.SetInsertPointAtEnd(block)
+		// Compiled types are equal iff their descriptors
+		// are at the same address; the hash may collide.
+		index := nomatch
+		for _, i := range groups[h] {
+			typ := fr.builder.CreateBitCast(fr.types.ToRuntime(sw.types[i]), i8ptr, "")
+			eq := fr.builder.CreateICmp(llvm.IntEQ, dyntyp, typ, "")
+			index = fr.builder.CreateSelect(eq, llvm.ConstInt(int32type, uint64(i), false), index, "")
+		}
+		matched := fr.builder.CreateICmp(llvm.IntNE, index, nomatch, "")
+		fr.builder.CreateCondBr(matched, done, fallback)
+		values = append(values, index)
+		blocks = append(blocks, block)
+	}
+
+	// If no descriptor matches, the dynamic type may still be
+	// one of the types, if it was constructed by reflect, so
+	// compare it with each of them as the runtime's type
+	// assertions do, before taking the default case.
+	fr.builder.SetInsertPointAtEnd(fallback)
+	eqtyp := fr.runtime.eqtyp.LLVMValue()
+	rtypeptr := eqtyp.Type().ElementType().ParamTypes()[0]
+	index := nomatch
+	for i := len(sw.types) - 1; i >= 0; i-- {
+		typ := fr.builder.CreateBitCast(fr.types.ToRuntime(sw.types[i]), rtypeptr, "")
+		args := []llvm.Value{fr.builder.CreateBitCast(dyntyp, rtypeptr, ""), typ}
+		eq := fr.builder.CreateCall(eqtyp, args, "")
+		index = fr.builder.CreateSelect(eq, llvm.ConstInt(int32type, uint64(i), false), index, "")
+	}
+	fr.builder.CreateBr(done)
+	values = append(values, index)
+	blocks = append(blocks, fallback)
+
+	fr.builder.SetInsertPointAtEnd(done)
+	sw.selector = fr.builder.CreatePHI(int32type, "")
+	sw.selector.AddIncoming(values, blocks)
+}
+
+// typeSwitchAssert emits code for a comma-ok type assertion
+// of a concrete type switch case, using the switch's selector.
+func (fr *frame) typeSwitchAssert(instr *ssa.TypeAssert, c typeSwitchCase) *LLVMValue {
+	x := fr.value(instr.X)
+	index := llvm.ConstInt(llvm.Int32Type(), uint64(c.index), false)
+	ok := fr.builder.CreateICmp(llvm.IntEQ, c.sw.selector, index, "")
+
+	// Extract the value as stored by makeInterface.
+	data := fr.builder.CreateExtractValue(x.LLVMValue(), 1, "")
+	lltyp := fr.types.ToLLVM(instr.AssertedType)
+	zero := llvm.ConstNull(lltyp)
+	var value llvm.Value
+	switch {
+	case lltyp.TypeKind() == llvm.PointerTypeKind:
+		value = fr.builder.CreateBitCast(data, lltyp, "")
+		value = fr.builder.CreateSelect(ok, value, zero, "")
+	case fr.target.TypeStoreSize(lltyp) <= uint64(fr.target.PointerSize()):
+		bits := fr.target.TypeSizeInBits(lltyp)
+		if bits == 0 {
+			value = zero
+			break
+		}
+		value = fr.builder.CreatePtrToInt(data, fr.target.IntPtrType(), "")
+		if uint64(value.Type().IntTypeWidth()) > bits {
+			value = fr.builder.CreateTrunc(value, llvm.IntType(int(bits)), "")
+		}
+		value = coerce(fr.builder, value, lltyp)
+		value = fr.builder.CreateSelect(ok, value, zero, "")
+	default:
+		// The value is stored indirectly; it must only
+		// be loaded if the assertion succeeds.
+		fn := fr.builder.GetInsertBlock().Parent()
+		entry := fr.builder.GetInsertBlock()
+		load := llvm.AddBasicBlock(fn, "typeswitch.load")
+		cont := llvm.AddBasicBlock(fn, "typeswitch.cont")
+		fr.builder.CreateCondBr(ok, load, cont)
+		fr.builder.SetInsertPointAtEnd(load)
+		ptr := fr.builder.CreateBitCast(data, llvm.PointerType(lltyp, 0), "")
+		loaded := fr.builder.CreateLoad(ptr, "")
+		fr.builder.CreateBr(cont)
+		fr.builder.SetInsertPointAtEnd(cont)
+		value = fr.builder.CreatePHI(lltyp, "")
+		value.AddIncoming([]llvm.Value{zero, loaded}, []llvm.BasicBlock{entry, load})
+	}
+
+	pairtyp := llvm.StructType([]llvm.Type{lltyp, ok.Type()}, false)
+	pair := llvm.Undef(pairtyp)
+	pair = fr.builder.CreateInsertValue(pair, value, 0, "")
+	pair = fr.builder.CreateInsertValue(pair, ok, 1, "")
+	return fr.NewValue(pair, instr.Type())
+}
@@ -2,8 +2,11 @@ package llgo
 
 import (
 	"code.google.com/p/go.tools/go/ssa"
+	"github.com/axw/gollvm/llvm"
 )
 
+// byName sorts functions by their fully qualified names,
+// and then by position, for a deterministic order.
 type byName []*ssa.Function
 
 func (fns byName) Len() int { return len(fns) }
@@ -11,5 +14,21 @@ func (fns byName) Swap(i, j int) {
 	fns[i], fns[j] = fns[j], fns[i]
 }
 func (fns byName) Less(i, j int) bool {
-	return fns[i].Name() < fns[j].Name()
+	si, sj := fns[i].String(), fns[j].String()
+	if si != sj {
+		return si < sj
+	}
+	return fns[i].Pos() < fns[j].Pos()
+}
+
+// addPrivateGlobal adds a private global variable with the
+// specified initializer. Anonymous constants are given names,
+// rather than leaving LLVM to number them, so that the output
+// is the same for each compilation; LLVM makes the name unique
+// by appending a number if necessary.
+func addPrivateGlobal(m llvm.Module, name string, init llvm.Value) llvm.Value {
+	global := llvm.AddGlobal(m, init.Type(), name)
+	global.SetInitializer(init)
+	global.SetLinkage(llvm.PrivateLinkage)
+	return global
 }
@@ -66,8 +66,7 @@ func (c *compiler) NewConstValue(v exact.Value, typ types.Type) *LLVMValue {
 		var ptr llvm.Value
 		if strlen > 0 {
 			init := llvm.ConstString(strval, false)
-			ptr = llvm.AddGlobal(c.module.Module, init.Type(), "")
-			ptr.SetInitializer(init)
+			ptr = addPrivateGlobal(c.module.Module, "__llgo.str", init)
 			ptr = llvm.ConstBitCast(ptr, i8ptr)
 		} else {
 			ptr = llvm.ConstNull(i8ptr)
@@ -163,6 +162,9 @@ func (lhs *LLVMValue) BinaryOp(op token.Token, rhs_ Value) Value {
 	b := lhs.compiler.builder
 
 	rhs := rhs_.(*LLVMValue)
+	if isSIMDType(lhs.typ) {
+		return c.compareVectors(lhs, rhs)
+	}
 	switch typ := lhs.typ.Underlying().(type) {
 	case *types.Struct:
 		// TODO(axw) use runtime equality algorithm (will be suitably inlined).
@@ -218,42 +220,42 @@ func (lhs *LLVMValue) BinaryOp(op token.Token, rhs_ Value) Value {
 		switch op {
 		case token.QUO:
 			// (a+bi)/(c+di) = (ac+bd)/(c**2+d**2) + (bc-ad)/(c**2+d**2)i
-			ac := b.CreateFMul(a_, c_, "")
-			bd := b.CreateFMul(b_, d_, "")
-			bc := b.CreateFMul(b_, c_, "")
-			ad := b.CreateFMul(a_, d_, "")
-			cpow2 := b.CreateFMul(c_, c_, "")
-			dpow2 := b.CreateFMul(d_, d_, "")
-			denom := b.CreateFAdd(cpow2, dpow2, "")
-			realnumer := b.CreateFAdd(ac, bd, "")
-			imagnumer := b.CreateFSub(bc, ad, "")
-			real_ := b.CreateFDiv(realnumer, denom, "")
-			imag_ := b.CreateFDiv(imagnumer, denom, "")
+			ac := c.floatArith(token.MUL, a_, c_)
+			bd := c.floatArith(token.MUL, b_, d_)
+			bc := c.floatArith(token.MUL, b_, c_)
+			ad := c.floatArith(token.MUL, a_, d_)
+			cpow2 := c.floatArith(token.MUL, c_, c_)
+			dpow2 := c.floatArith(token.MUL, d_, d_)
+			denom := c.floatArith(token.ADD, cpow2, dpow2)
+			realnumer := c.floatArith(token.ADD, ac, bd)
+			imagnumer := c.floatArith(token.SUB, bc, ad)
+			real_ := c.floatArith(token.QUO, realnumer, denom)
+			imag_ := c.floatArith(token.QUO, imagnumer, denom)
 			lhsval = b.CreateInsertValue(lhsval, real_, 0, "")
 			result = b.CreateInsertValue(lhsval, imag_, 1, "")
 		case token.MUL:
 			// (a+bi)(c+di) = (ac-bd)+(bc+ad)i
-			ac := b.CreateFMul(a_, c_, "")
-			bd := b.CreateFMul(b_, d_, "")
-			bc := b.CreateFMul(b_, c_, "")
-			ad := b.CreateFMul(a_, d_, "")
-			real_ := b.CreateFSub(ac, bd, "")
-			imag_ := b.CreateFAdd(bc, ad, "")
+			ac := c.floatArith(token.MUL, a_, c_)
+			bd := c.floatArith(token.MUL, b_, d_)
+			bc := c.floatArith(token.MUL, b_, c_)
+			ad := c.floatArith(token.MUL, a_, d_)
+			real_ := c.floatArith(token.SUB, ac, bd)
+			imag_ := c.floatArith(token.ADD, bc, ad)
 			lhsval = b.CreateInsertValue(lhsval, real_, 0, "")
 			result = b.CreateInsertValue(lhsval, imag_, 1, "")
 		case token.ADD:
-			real_ := b.CreateFAdd(a_, c_, "")
-			imag_ := b.CreateFAdd(b_, d_, "")
+			real_ := c.floatArith(token.ADD, a_, c_)
+			imag_ := c.floatArith(token.ADD, b_, d_)
 			lhsval = b.CreateInsertValue(lhsval, real_, 0, "")
 			result = b.CreateInsertValue(lhsval, imag_, 1, "")
 		case token.SUB:
-			real_ := b.CreateFSub(a_, c_, "")
-			imag_ := b.CreateFSub(b_, d_, "")
+			real_ := c.floatArith(token.SUB, a_, c_)
+			imag_ := c.floatArith(token.SUB, b_, d_)
 			lhsval = b.CreateInsertValue(lhsval, real_, 0, "")
 			result = b.CreateInsertValue(lhsval, imag_, 1, "")
 		case token.EQL:
-			realeq := b.CreateFCmp(llvm.FloatOEQ, a_, c_, "")
-			imageq := b.CreateFCmp(llvm.FloatOEQ, b_, d_, "")
+			realeq := c.floatCompare(token.EQL, a_, c_)
+			imageq := c.floatCompare(token.EQL, b_, d_)
 			result = b.CreateAnd(realeq, imageq, "")
 		default:
 			panic(fmt.Errorf("unhandled operator: %v", op))
@@ -261,97 +263,75 @@ func (lhs *LLVMValue) BinaryOp(op token.Token, rhs_ Value) Value {
 		return lhs.compiler.NewValue(result, lhs.typ)
 	}
 
-	// Floats and integers.
-	// TODO determine the NaN rules.
+	// Floats.
+	if isFloat(lhs.typ) {
+		switch op {
+		case token.MUL, token.QUO, token.ADD, token.SUB:
+			result = c.floatArith(op, lhs.LLVMValue(), rhs.LLVMValue())
+			return c.NewValue(result, lhs.typ)
+		case token.EQL, token.LSS, token.LEQ, token.GTR, token.GEQ:
+			result = c.floatCompare(op, lhs.LLVMValue(), rhs.LLVMValue())
+			return c.NewValue(result, types.Typ[types.Bool])
+		default:
+			panic(fmt.Sprint("Unimplemented operator: ", op))
+		}
+	}
 
+	// Integers.
 	switch op {
 	case token.MUL:
-		if isFloat(lhs.typ) {
-			result = b.CreateFMul(lhs.LLVMValue(), rhs.LLVMValue(), "")
-		} else {
-			result = b.CreateMul(lhs.LLVMValue(), rhs.LLVMValue(), "")
-		}
+		result = b.CreateMul(lhs.LLVMValue(), rhs.LLVMValue(), "")
 		return lhs.compiler.NewValue(result, lhs.typ)
 	case token.QUO:
-		switch {
-		case isFloat(lhs.typ):
-			result = b.CreateFDiv(lhs.LLVMValue(), rhs.LLVMValue(), "")
-		case !isUnsigned(lhs.typ):
+		if !isUnsigned(lhs.typ) {
 			result = b.CreateSDiv(lhs.LLVMValue(), rhs.LLVMValue(), "")
-		default:
+		} else {
 			result = b.CreateUDiv(lhs.LLVMValue(), rhs.LLVMValue(), "")
 		}
 		return lhs.compiler.NewValue(result, lhs.typ)
 	case token.REM:
-		switch {
-		case isFloat(lhs.typ):
-			result = b.CreateFRem(lhs.LLVMValue(), rhs.LLVMValue(), "")
-		case !isUnsigned(lhs.typ):
+		if !isUnsigned(lhs.typ) {
 			result = b.CreateSRem(lhs.LLVMValue(), rhs.LLVMValue(), "")
-		default:
+		} else {
 			result = b.CreateURem(lhs.LLVMValue(), rhs.LLVMValue(), "")
 		}
 		return lhs.compiler.NewValue(result, lhs.typ)
 	case token.ADD:
-		if isFloat(lhs.typ) {
-			result = b.CreateFAdd(lhs.LLVMValue(), rhs.LLVMValue(), "")
-		} else {
-			result = b.CreateAdd(lhs.LLVMValue(), rhs.LLVMValue(), "")
-		}
+		result = b.CreateAdd(lhs.LLVMValue(), rhs.LLVMValue(), "")
 		return lhs.compiler.NewValue(result, lhs.typ)
 	case token.SUB:
-		if isFloat(lhs.typ) {
-			result = b.CreateFSub(lhs.LLVMValue(), rhs.LLVMValue(), "")
-		} else {
-			result = b.CreateSub(lhs.LLVMValue(), rhs.LLVMValue(), "")
-		}
+		result = b.CreateSub(lhs.LLVMValue(), rhs.LLVMValue(), "")
 		return lhs.compiler.NewValue(result, lhs.typ)
 	case token.SHL, token.SHR:
 		return lhs.shift(rhs, op)
 	case token.EQL:
-		if isFloat(lhs.typ) {
-			result = b.CreateFCmp(llvm.FloatOEQ, lhs.LLVMValue(), rhs.LLVMValue(), "")
-		} else {
-			result = b.CreateICmp(llvm.IntEQ, lhs.LLVMValue(), rhs.LLVMValue(), "")
-		}
+		result = b.CreateICmp(llvm.IntEQ, lhs.LLVMValue(), rhs.LLVMValue(), "")
 		return lhs.compiler.NewValue(result, types.Typ[types.Bool])
 	case token.LSS:
-		switch {
-		case isFloat(lhs.typ):
-			result = b.CreateFCmp(llvm.FloatOLT, lhs.LLVMValue(), rhs.LLVMValue(), "")
-		case !isUnsigned(lhs.typ):
+		if !isUnsigned(lhs.typ) {
 			result = b.CreateICmp(llvm.IntSLT, lhs.LLVMValue(), rhs.LLVMValue(), "")
-		default:
+		} else {
 			result = b.CreateICmp(llvm.IntULT, lhs.LLVMValue(), rhs.LLVMValue(), "")
 		}
 		return lhs.compiler.NewValue(result, types.Typ[types.Bool])
 	case token.LEQ:
-		switch {
-		case isFloat(lhs.typ):
-			result = b.CreateFCmp(llvm.FloatOLE, lhs.LLVMValue(), rhs.LLVMValue(), "")
-		case !isUnsigned(lhs.typ):
+		if !isUnsigned(lhs.typ) {
 			result = b.CreateICmp(llvm.IntSLE, lhs.LLVMValue(), rhs.LLVMValue(), "")
-		default:
+		} else {
 			result = b.CreateICmp(llvm.IntULE, lhs.LLVMValue(), rhs.LLVMValue(), "")
 		}
 		return lhs.compiler.NewValue(result, types.Typ[types.Bool])
 	case token.GTR:
-		switch {
-		case isFloat(lhs.typ):
-			result = b.CreateFCmp(llvm.FloatOGT, lhs.LLVMValue(), rhs.LLVMValue(), "")
-		case !isUnsigned(lhs.typ):
+		if !isUnsigned(lhs.typ) {
 			result = b.CreateICmp(llvm.IntSGT, lhs.LLVMValue(), rhs.LLVMValue(), "")
-		default:
+		} else {
 			result = b.CreateICmp(llvm.IntUGT, lhs.LLVMValue(), rhs.LLVMValue(), "")
 		}
 		return lhs.compiler.NewValue(result, types.Typ[types.Bool])
 	case token.GEQ:
-		switch {
-		case isFloat(lhs.typ):
-			result = b.CreateFCmp(llvm.FloatOGE, lhs.LLVMValue(), rhs.LLVMValue(), "")
-		case !isUnsigned(lhs.typ):
+		if !isUnsigned(lhs.typ) {
 			result = b.CreateICmp(llvm.IntSGE, lhs.LLVMValue(), rhs.LLVMValue(), "")
-		default:
+		} else {
 			result = b.CreateICmp(llvm.IntUGE, lhs.LLVMValue(), rhs.LLVMValue(), "")
 		}
 		return lhs.compiler.NewValue(result, types.Typ[types.Bool])
@@ -404,10 +384,13 @@ func (v *LLVMValue) UnaryOp(op token.Token) Value {
 	switch op {
 	case token.SUB:
 		var value llvm.Value
-		if isFloat(v.typ) {
+		switch {
+		case isFloat(v.typ) && v.compiler.SoftFloat:
+			value = v.compiler.softFloatNeg(v.LLVMValue())
+		case isFloat(v.typ):
 			zero := llvm.ConstNull(v.compiler.types.ToLLVM(v.Type()))
 			value = b.CreateFSub(zero, v.LLVMValue(), "")
-		} else {
+		default:
 			value = b.CreateNeg(v.LLVMValue(), "")
 		}
 		return v.compiler.NewValue(value, v.typ)
@@ -552,6 +535,10 @@ func (v *LLVMValue) Convert(dsttyp types.Type) Value {
 	}
 
 	lv := v.LLVMValue()
+	if v.compiler.SoftFloat && (isFloat(srctyp) || isFloat(dsttyp)) {
+		lv = v.compiler.softFloatConvert(lv, isUnsigned(srctyp), llvm_type, isUnsigned(dsttyp))
+		return v.compiler.NewValue(lv, origdsttyp)
+	}
 	srcType := lv.Type()
 	switch srcType.TypeKind() {
 	case llvm.IntegerTypeKind:
@@ -622,6 +609,11 @@ func (v *LLVMValue) Convert(dsttyp types.Type) Value {
 			fpcast = (llvm.Builder).CreateFPTrunc
 			fptype = llvm.FloatType()
 		}
+		if v.compiler.SoftFloat {
+			fpcast = func(_ llvm.Builder, x llvm.Value, t llvm.Type, _ string) llvm.Value {
+				return v.compiler.softFloatConvert(x, false, t, false)
+			}
+		}
 		if fpcast != nil {
 			realv := b.CreateExtractValue(lv, 0, "")
 			imagv := b.CreateExtractValue(lv, 1, "")